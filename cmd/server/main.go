@@ -1,26 +1,47 @@
 package main
 
 import (
-	"log"
-	"net/http"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	_ "time/tzdata"
 
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"github.com/mgwinsor/weekbyweek/internal/app/user"
-	"github.com/mgwinsor/weekbyweek/internal/primary/api"
-	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/mgwinsor/weekbyweek/internal/bootstrap"
+	"github.com/mgwinsor/weekbyweek/internal/config"
+	"github.com/mgwinsor/weekbyweek/internal/logging"
 )
 
 func main() {
-	userRepo := memory.NewUserRepository()
-	userService := user.NewUserService(userRepo)
-	userHandler := api.NewUserHandler(userService)
+	cfg, err := config.Load(os.Args[1:], os.LookupEnv)
+	if errors.Is(err, flag.ErrHelp) {
+		config.Usage(os.Stderr)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Loading configuration: %v\n", err)
+		os.Exit(2)
+	}
 
-	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	if cfg.PrintConfig {
+		if err := config.Print(os.Stdout, cfg); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 
-	userHandler.RegisterRoutes(r)
+	slog.SetDefault(logging.New(os.Stderr, cfg.Log.Level, cfg.Log.Format))
 
-	log.Println("Server starting on port 8080")
-	http.ListenAndServe(":8080", r)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := bootstrap.Run(ctx, cfg); err != nil {
+		slog.Error("server failed", "error", err)
+		os.Exit(1)
+	}
 }
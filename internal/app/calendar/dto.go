@@ -0,0 +1,62 @@
+package calendar
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type FeedResponse struct {
+	UserID      uuid.UUID
+	Username    string
+	GeneratedAt time.Time
+	Events      []Event
+}
+
+// Event is an all-day calendar entry. Start and End are calendar dates
+// represented as midnight UTC; End is exclusive.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+}
+
+type ImportRequest struct {
+	UserID uuid.UUID
+	DryRun bool
+	Events []ImportEvent
+}
+
+// ImportEvent is an event read from an imported calendar. Start keeps the
+// event's own location so that its local date is used.
+type ImportEvent struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	// Occurrences returns the start times of the event, recurrences
+	// expanded, up to and including until and at most limit of them. It
+	// returns ErrImportTooLarge if there would be more.
+	Occurrences func(until time.Time, limit int) ([]time.Time, error)
+}
+
+type ImportResponse struct {
+	ImportID uuid.UUID     `json:"importId"`
+	DryRun   bool          `json:"dryRun"`
+	Items    []ImportItem  `json:"items"`
+	Skipped  []SkippedItem `json:"skipped"`
+}
+
+type ImportItem struct {
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+	Week  int       `json:"week"`
+}
+
+type SkippedItem struct {
+	Title  string    `json:"title"`
+	Date   time.Time `json:"date,omitzero"`
+	Reason string    `json:"reason"`
+}
@@ -0,0 +1,130 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+)
+
+var ErrImportTooLarge = errors.New("import contains too many events")
+
+const MaxImportOccurrences = 10000
+
+const (
+	skipReasonFuture      = "in the future"
+	skipReasonBeforeBirth = "before date of birth"
+	skipReasonDuplicate   = "duplicate occurrence"
+)
+
+// ImportEvents expands each event up to the end of the user's today, maps
+// every occurrence to the life week it falls in and, unless the request is
+// a dry run, stores them as milestones under a new import ID that can later
+// be passed to UndoImport.
+func (s *calendarService) ImportEvents(ctx context.Context, req ImportRequest) (*ImportResponse, error) {
+	u, err := s.userRepo.FindByID(ctx, req.UserID)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ImportResponse{
+		DryRun:  req.DryRun,
+		Items:   []ImportItem{},
+		Skipped: []SkippedItem{},
+	}
+	if !req.DryRun {
+		resp.ImportID = uuid.New()
+	}
+
+	now := s.now().In(u.Location())
+	today := week.Date(now)
+	y, m, d := now.Date()
+	until := time.Date(y, m, d+1, 0, 0, 0, 0, now.Location()).Add(-time.Nanosecond)
+
+	var occurrences []ImportEvent
+	for _, e := range req.Events {
+		starts, err := e.Occurrences(until, MaxImportOccurrences-len(occurrences))
+		if errors.Is(err, ErrImportTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			resp.Skipped = append(resp.Skipped, SkippedItem{Title: e.Summary, Date: week.Date(e.Start), Reason: err.Error()})
+			continue
+		}
+		for _, start := range starts {
+			o := e
+			o.Start = start
+			occurrences = append(occurrences, o)
+		}
+	}
+
+	seen := make(map[string]bool, len(occurrences))
+	milestones := make([]*milestone.Milestone, 0, len(occurrences))
+
+	for _, o := range occurrences {
+		day := week.Date(o.Start)
+		skip := func(reason string) {
+			resp.Skipped = append(resp.Skipped, SkippedItem{Title: o.Summary, Date: day, Reason: reason})
+		}
+
+		if day.After(today) {
+			skip(skipReasonFuture)
+			continue
+		}
+
+		w, err := u.WeekAnchor().At(u.DateOfBirth(), day)
+		if err != nil {
+			skip(skipReasonBeforeBirth)
+			continue
+		}
+
+		key := fmt.Sprintf("%s/%s/%s", o.UID, o.Summary, day.Format("2006-01-02"))
+		if seen[key] {
+			skip(skipReasonDuplicate)
+			continue
+		}
+		seen[key] = true
+
+		m, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+			UserID:      u.ID(),
+			Title:       o.Summary,
+			Description: o.Description,
+			Date:        day,
+			ImportID:    resp.ImportID,
+		})
+		if err != nil {
+			skip(err.Error())
+			continue
+		}
+
+		milestones = append(milestones, m)
+		resp.Items = append(resp.Items, ImportItem{Title: m.Title(), Date: day, Week: w.Number})
+	}
+
+	if req.DryRun || len(milestones) == 0 {
+		resp.ImportID = uuid.Nil
+		return resp, nil
+	}
+
+	if err := s.milestoneRepo.SaveAll(ctx, milestones); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (s *calendarService) UndoImport(ctx context.Context, userID, importID uuid.UUID) error {
+	err := s.milestoneRepo.DeleteByImportID(ctx, userID, importID)
+	if errors.Is(err, milestone.ErrImportNotFound) {
+		return ErrImportNotFound
+	}
+	return err
+}
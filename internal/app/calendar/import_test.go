@@ -0,0 +1,241 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user/usertest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnsupportedRule = errors.New("unsupported recurrence rule")
+
+func TestImportEvents(t *testing.T) {
+	dob := time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2025, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	existingUser, err := user.NewUser(
+		context.Background(),
+		user.NewUserParams{
+			Email:       "john@example.com",
+			Username:    "johndoe",
+			Password:    "12345678",
+			DateOfBirth: dob,
+		},
+		&fakeHasher{},
+	)
+	require.NoError(t, err)
+
+	auckland := time.FixedZone("NZST", 12*3600)
+	events := []ImportEvent{
+		{UID: "grad", Summary: "Graduated", Occurrences: occurs(time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC))},
+		{UID: "late", Summary: "Late night", Occurrences: occurs(time.Date(2014, time.June, 13, 0, 30, 0, 0, auckland))},
+		{UID: "grad", Summary: "Graduated", Occurrences: occurs(time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC))},
+		{UID: "future", Summary: "Holiday", Occurrences: occurs(
+			time.Date(2025, time.August, 2, 6, 0, 0, 0, auckland),
+			time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		)},
+		{UID: "old", Summary: "Before", Occurrences: occurs(time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC))},
+		{UID: "blank", Summary: " ", Occurrences: occurs(time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC))},
+		{
+			UID:     "odd",
+			Summary: "Odd rule",
+			Start:   time.Date(2015, time.January, 5, 9, 0, 0, 0, auckland),
+			Occurrences: func(time.Time, int) ([]time.Time, error) {
+				return nil, errUnsupportedRule
+			},
+		},
+	}
+
+	expectedItems := []ImportItem{
+		{Title: "Graduated", Date: time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC), Week: 1125},
+		{Title: "Late night", Date: time.Date(2014, time.June, 13, 0, 0, 0, 0, time.UTC), Week: 1125},
+	}
+	expectedSkipped := []SkippedItem{
+		{Title: "Odd rule", Date: time.Date(2015, time.January, 5, 0, 0, 0, 0, time.UTC), Reason: errUnsupportedRule.Error()},
+		{Title: "Graduated", Date: time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC), Reason: skipReasonDuplicate},
+		{Title: "Holiday", Date: time.Date(2025, time.August, 2, 0, 0, 0, 0, time.UTC), Reason: skipReasonFuture},
+		{Title: "Before", Date: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Reason: skipReasonBeforeBirth},
+		{Title: " ", Date: time.Date(2015, time.January, 1, 0, 0, 0, 0, time.UTC), Reason: milestone.ErrTitleRequired.Error()},
+	}
+
+	tooMany := occurs(make([]time.Time, MaxImportOccurrences/2+1)...)
+
+	tests := []struct {
+		name        string
+		req         ImportRequest
+		mockSetup   func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository)
+		expectedErr error
+	}{
+		{
+			name: "dry run previews without saving",
+			req:  ImportRequest{UserID: existingUser.ID(), DryRun: true, Events: events},
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+			},
+		},
+		{
+			name: "import saves milestones under one import id",
+			req:  ImportRequest{UserID: existingUser.ID(), Events: events},
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+				milestoneRepo.On("SaveAll", mock.Anything, mock.MatchedBy(func(ms []*milestone.Milestone) bool {
+					return len(ms) == 2 && ms[0].ImportID() != uuid.Nil && ms[0].ImportID() == ms[1].ImportID()
+				})).Return(nil).Once()
+			},
+		},
+		{
+			name: "unknown user",
+			req:  ImportRequest{UserID: uuid.New(), Events: events},
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, mock.Anything).
+					Return(nil, user.ErrUserNotFound).Once()
+			},
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name: "repository error during save",
+			req:  ImportRequest{UserID: existingUser.ID(), Events: events},
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+				milestoneRepo.On("SaveAll", mock.Anything, mock.Anything).
+					Return(errRepositoryFailure).Once()
+			},
+			expectedErr: errRepositoryFailure,
+		},
+		{
+			name: "too many occurrences across events",
+			req: ImportRequest{UserID: existingUser.ID(), Events: []ImportEvent{
+				{UID: "a", Summary: "A", Occurrences: tooMany},
+				{UID: "b", Summary: "B", Occurrences: tooMany},
+			}},
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+			},
+			expectedErr: ErrImportTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(usertest.MockRepository)
+			milestoneRepo := new(MockMilestoneRepository)
+			tt.mockSetup(userRepo, milestoneRepo)
+
+			calendarService := NewCalendarService(userRepo, milestoneRepo)
+			calendarService.now = func() time.Time { return now }
+
+			resp, err := calendarService.ImportEvents(context.Background(), tt.req)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, resp)
+
+				assert.Equal(t, tt.req.DryRun, resp.DryRun)
+				assert.Equal(t, tt.req.DryRun, resp.ImportID == uuid.Nil, "only real imports get an import id")
+				assert.Equal(t, expectedItems, resp.Items)
+				assert.Equal(t, expectedSkipped, resp.Skipped)
+			}
+			userRepo.AssertExpectations(t)
+			milestoneRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestImportEvents_ExpandsUntilEndOfUsersToday(t *testing.T) {
+	existingUser, err := user.NewUser(
+		context.Background(),
+		user.NewUserParams{
+			Email:       "john@example.com",
+			Username:    "johndoe",
+			Password:    "12345678",
+			DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+			TimeZone:    "Pacific/Auckland",
+		},
+		&fakeHasher{},
+	)
+	require.NoError(t, err)
+
+	userRepo := new(usertest.MockRepository)
+	userRepo.On("FindByID", mock.Anything, existingUser.ID()).Return(existingUser, nil).Once()
+
+	calendarService := NewCalendarService(userRepo, new(MockMilestoneRepository))
+	calendarService.now = func() time.Time { return time.Date(2025, time.August, 1, 12, 0, 0, 0, time.UTC) }
+
+	var until time.Time
+	_, err = calendarService.ImportEvents(context.Background(), ImportRequest{
+		UserID: existingUser.ID(),
+		DryRun: true,
+		Events: []ImportEvent{{
+			UID:     "e",
+			Summary: "Event",
+			Occurrences: func(u time.Time, limit int) ([]time.Time, error) {
+				until = u
+				return nil, nil
+			},
+		}},
+	})
+
+	require.NoError(t, err)
+	expected := time.Date(2025, time.August, 3, 0, 0, 0, 0, existingUser.Location()).Add(-time.Nanosecond)
+	assert.True(t, expected.Equal(until), "expanded until %v, want %v", until, expected)
+}
+
+// occurs stands in for an expanded calendar event that starts at each of
+// starts.
+func occurs(starts ...time.Time) func(time.Time, int) ([]time.Time, error) {
+	return func(until time.Time, limit int) ([]time.Time, error) {
+		var out []time.Time
+		for _, start := range starts {
+			if start.After(until) {
+				continue
+			}
+			if len(out) >= limit {
+				return nil, ErrImportTooLarge
+			}
+			out = append(out, start)
+		}
+		return out, nil
+	}
+}
+
+func TestUndoImport(t *testing.T) {
+	userID, importID := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		deleteErr   error
+		expectedErr error
+	}{
+		{name: "undo import"},
+		{name: "unknown import", deleteErr: milestone.ErrImportNotFound, expectedErr: ErrImportNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			milestoneRepo := new(MockMilestoneRepository)
+			milestoneRepo.On("DeleteByImportID", mock.Anything, userID, importID).Return(tt.deleteErr).Once()
+
+			calendarService := NewCalendarService(new(usertest.MockRepository), milestoneRepo)
+
+			err := calendarService.UndoImport(context.Background(), userID, importID)
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			milestoneRepo.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package calendar
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+)
+
+var (
+	// ErrInvalidFeedToken means the user is unknown or the token is not
+	// theirs.
+	ErrInvalidFeedToken = errors.New("invalid calendar feed token")
+	ErrUserNotFound     = errors.New("user not found")
+	ErrImportNotFound   = errors.New("import not found")
+)
+
+const (
+	pastWeeks   = 52
+	futureWeeks = 52
+)
+
+type Service interface {
+	Feed(ctx context.Context, userID uuid.UUID, token string) (*FeedResponse, error)
+	ImportEvents(ctx context.Context, req ImportRequest) (*ImportResponse, error)
+	UndoImport(ctx context.Context, userID, importID uuid.UUID) error
+}
+
+type calendarService struct {
+	userRepo      user.UserRepository
+	milestoneRepo milestone.MilestoneRepository
+	now           func() time.Time
+}
+
+func NewCalendarService(userRepo user.UserRepository, milestoneRepo milestone.MilestoneRepository) *calendarService {
+	return &calendarService{
+		userRepo:      userRepo,
+		milestoneRepo: milestoneRepo,
+		now:           time.Now,
+	}
+}
+
+func (s *calendarService) Feed(ctx context.Context, userID uuid.UUID, token string) (*FeedResponse, error) {
+	u, err := s.authenticate(ctx, userID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	milestones, err := s.milestoneRepo.FindByUserID(ctx, u.ID())
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now().UTC()
+	first, last := feedWindow(u, now)
+
+	var events []Event
+	events = append(events, weekEvents(u, first, last)...)
+	events = append(events, birthdayEvents(u, first.Start, last.End)...)
+	events = append(events, milestoneEvents(u, milestones)...)
+
+	resp := &FeedResponse{
+		UserID:      u.ID(),
+		Username:    u.Username(),
+		GeneratedAt: now,
+		Events:      events,
+	}
+
+	return resp, nil
+}
+
+// authenticate returns the user whose calendar token is token. The token
+// only reads the feed: imports change the calendar and are left to callers
+// to authorise with the user's own credentials.
+func (s *calendarService) authenticate(ctx context.Context, userID uuid.UUID, token string) (*user.User, error) {
+	u, err := s.userRepo.FindByID(ctx, userID)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, ErrInvalidFeedToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(u.CalendarToken()), []byte(token)) != 1 {
+		return nil, ErrInvalidFeedToken
+	}
+	return u, nil
+}
+
+func feedWindow(u *user.User, now time.Time) (week.Week, week.Week) {
+	anchor, dob := u.WeekAnchor(), u.DateOfBirth()
+
+	current, err := u.CurrentWeek(now)
+	if err != nil {
+		current = anchor.Nth(dob, 1)
+	}
+
+	first := max(current.Number-pastWeeks, 1)
+	return anchor.Nth(dob, first), anchor.Nth(dob, current.Number+futureWeeks)
+}
+
+func weekEvents(u *user.User, first, last week.Week) []Event {
+	events := make([]Event, 0, last.Number-first.Number+1)
+	for n := first.Number; n <= last.Number; n++ {
+		w := u.WeekAnchor().Nth(u.DateOfBirth(), n)
+		events = append(events, Event{
+			UID:         fmt.Sprintf("week-%d-%s@weekbyweek", n, u.ID()),
+			Summary:     fmt.Sprintf("Week %d", n),
+			Description: fmt.Sprintf("Week %d of your life begins.", n),
+			Start:       w.Start,
+			End:         w.End,
+		})
+	}
+	return events
+}
+
+func birthdayEvents(u *user.User, from, to time.Time) []Event {
+	var events []Event
+	dob := week.Date(u.DateOfBirth())
+	for age := 1; ; age++ {
+		birthday := dob.AddDate(age, 0, 0)
+		if !birthday.Before(to) {
+			break
+		}
+		if birthday.Before(from) {
+			continue
+		}
+
+		events = append(events, Event{
+			UID:     fmt.Sprintf("birthday-%d-%s@weekbyweek", age, u.ID()),
+			Summary: fmt.Sprintf("%s birthday", ordinal(age)),
+			Start:   birthday,
+			End:     birthday.AddDate(0, 0, 1),
+		})
+	}
+	return events
+}
+
+func milestoneEvents(u *user.User, milestones []*milestone.Milestone) []Event {
+	events := make([]Event, 0, len(milestones))
+	for _, m := range milestones {
+		day := week.Date(m.Date())
+
+		description := m.Description()
+		if w, err := u.WeekAnchor().At(u.DateOfBirth(), day); err == nil {
+			description = strings.TrimSpace(fmt.Sprintf("Week %d. %s", w.Number, description))
+		}
+
+		events = append(events, Event{
+			UID:         fmt.Sprintf("milestone-%s@weekbyweek", m.ID()),
+			Summary:     m.Title(),
+			Description: description,
+			Start:       day,
+			End:         day.AddDate(0, 0, 1),
+		})
+	}
+	return events
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch n % 10 {
+	case 1:
+		suffix = "st"
+	case 2:
+		suffix = "nd"
+	case 3:
+		suffix = "rd"
+	}
+	if n%100 >= 11 && n%100 <= 13 {
+		suffix = "th"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
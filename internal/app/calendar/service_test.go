@@ -0,0 +1,217 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user/usertest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var errRepositoryFailure = errors.New("error in data repository")
+
+type MockMilestoneRepository struct {
+	mock.Mock
+}
+
+func (m *MockMilestoneRepository) Save(ctx context.Context, ms *milestone.Milestone) error {
+	args := m.Called(ctx, ms)
+	return args.Error(0)
+}
+
+func (m *MockMilestoneRepository) SaveAll(ctx context.Context, ms []*milestone.Milestone) error {
+	args := m.Called(ctx, ms)
+	return args.Error(0)
+}
+
+func (m *MockMilestoneRepository) DeleteByImportID(ctx context.Context, userID, importID uuid.UUID) error {
+	args := m.Called(ctx, userID, importID)
+	return args.Error(0)
+}
+
+func (m *MockMilestoneRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*milestone.Milestone, error) {
+	args := m.Called(ctx, userID)
+	var ms []*milestone.Milestone
+	if args.Get(0) != nil {
+		ms = args.Get(0).([]*milestone.Milestone)
+	}
+	return ms, args.Error(1)
+}
+
+type fakeHasher struct{}
+
+func (f *fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+func (f *fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error { return nil }
+
+func TestFeed(t *testing.T) {
+	dob := time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2025, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	existingUser, err := user.NewUser(
+		context.Background(),
+		user.NewUserParams{
+			Email:       "john@example.com",
+			Username:    "johndoe",
+			Password:    "12345678",
+			DateOfBirth: dob,
+		},
+		&fakeHasher{},
+	)
+	require.NoError(t, err)
+
+	graduation, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+		UserID: existingUser.ID(),
+		Title:  "Graduated",
+		Date:   time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		userID      uuid.UUID
+		token       string
+		mockSetup   func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository)
+		expectedErr error
+	}{
+		{
+			name:   "successfully build feed",
+			userID: existingUser.ID(),
+			token:  existingUser.CalendarToken(),
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+				milestoneRepo.On("FindByUserID", mock.Anything, existingUser.ID()).
+					Return([]*milestone.Milestone{graduation}, nil).Once()
+			},
+			expectedErr: nil,
+		},
+		{
+			name:   "wrong token",
+			userID: existingUser.ID(),
+			token:  "not-the-token",
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+			},
+			expectedErr: ErrInvalidFeedToken,
+		},
+		{
+			name:   "unknown user is reported as invalid token",
+			userID: uuid.New(),
+			token:  existingUser.CalendarToken(),
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, mock.Anything).
+					Return(nil, user.ErrUserNotFound).Once()
+			},
+			expectedErr: ErrInvalidFeedToken,
+		},
+		{
+			name:   "milestone repository error",
+			userID: existingUser.ID(),
+			token:  existingUser.CalendarToken(),
+			mockSetup: func(userRepo *usertest.MockRepository, milestoneRepo *MockMilestoneRepository) {
+				userRepo.On("FindByID", mock.Anything, existingUser.ID()).
+					Return(existingUser, nil).Once()
+				milestoneRepo.On("FindByUserID", mock.Anything, existingUser.ID()).
+					Return(nil, errRepositoryFailure).Once()
+			},
+			expectedErr: errRepositoryFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(usertest.MockRepository)
+			milestoneRepo := new(MockMilestoneRepository)
+			tt.mockSetup(userRepo, milestoneRepo)
+
+			calendarService := NewCalendarService(userRepo, milestoneRepo)
+			calendarService.now = func() time.Time { return now }
+
+			resp, err := calendarService.Feed(context.Background(), tt.userID, tt.token)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, resp)
+
+				assert.Equal(t, existingUser.Username(), resp.Username)
+				assert.Equal(t, now, resp.GeneratedAt)
+				assertHasEvent(t, resp.Events, "Week 1654", time.Date(2024, time.July, 27, 0, 0, 0, 0, time.UTC))
+				assertHasEvent(t, resp.Events, "Week 1706", time.Date(2025, time.July, 26, 0, 0, 0, 0, time.UTC))
+				assertHasEvent(t, resp.Events, "Week 1758", time.Date(2026, time.July, 25, 0, 0, 0, 0, time.UTC))
+				assertHasEvent(t, resp.Events, "32nd birthday", time.Date(2024, time.November, 21, 0, 0, 0, 0, time.UTC))
+				assertHasEvent(t, resp.Events, "33rd birthday", time.Date(2025, time.November, 21, 0, 0, 0, 0, time.UTC))
+				assertHasEvent(t, resp.Events, "Graduated", time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC))
+				assert.Len(t, resp.Events, 105+2+1)
+			}
+			userRepo.AssertExpectations(t)
+			milestoneRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFeed_HonoursWeekAnchor(t *testing.T) {
+	now := time.Date(2025, time.August, 1, 12, 0, 0, 0, time.UTC)
+
+	birthdayUser, err := user.NewUser(
+		context.Background(),
+		user.NewUserParams{
+			Email:       "john@example.com",
+			Username:    "johndoe",
+			Password:    "12345678",
+			DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+			WeekAnchor:  week.Anchor{Mode: week.ModeBirthday},
+		},
+		&fakeHasher{},
+	)
+	require.NoError(t, err)
+
+	userRepo := new(usertest.MockRepository)
+	userRepo.On("FindByID", mock.Anything, birthdayUser.ID()).Return(birthdayUser, nil)
+	milestoneRepo := new(MockMilestoneRepository)
+	milestoneRepo.On("FindByUserID", mock.Anything, birthdayUser.ID()).Return(nil, nil)
+
+	calendarService := NewCalendarService(userRepo, milestoneRepo)
+	calendarService.now = func() time.Time { return now }
+
+	resp, err := calendarService.Feed(context.Background(), birthdayUser.ID(), birthdayUser.CalendarToken())
+	require.NoError(t, err)
+
+	assertHasEvent(t, resp.Events, "Week 1701", time.Date(2025, time.July, 31, 0, 0, 0, 0, time.UTC))
+	assertHasEvent(t, resp.Events, "Week 1716", time.Date(2025, time.November, 13, 0, 0, 0, 0, time.UTC))
+	assertHasEvent(t, resp.Events, "Week 1717", time.Date(2025, time.November, 21, 0, 0, 0, 0, time.UTC))
+}
+
+func assertHasEvent(t *testing.T, events []Event, summary string, start time.Time) {
+	t.Helper()
+	for _, e := range events {
+		if e.Summary == summary {
+			assert.Equal(t, start, e.Start, "event %q starts on the wrong date", summary)
+			return
+		}
+	}
+	t.Errorf("event %q not found", summary)
+}
+
+func TestOrdinal(t *testing.T) {
+	tests := map[int]string{
+		1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th",
+		13: "13th", 21: "21st", 22: "22nd", 101: "101st", 111: "111th",
+	}
+	for n, expected := range tests {
+		assert.Equal(t, expected, ordinal(n))
+	}
+}
@@ -0,0 +1,43 @@
+package digest
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+)
+
+// SendDigest mails a user's digest for a week. The delivery is recorded
+// when the job is queued, so that the digest is not queued twice, and is
+// released again if the job is dead-lettered.
+type SendDigest struct {
+	UserID   uuid.UUID `json:"userId"`
+	Week     int       `json:"week"`
+	To       string    `json:"to"`
+	Subject  string    `json:"subject"`
+	TextBody string    `json:"textBody"`
+	HTMLBody string    `json:"htmlBody,omitempty"`
+}
+
+func (SendDigest) JobType() string { return "digest.send" }
+
+// SendDigestHandler delivers queued SendDigest jobs through mailer.
+func SendDigestHandler(mailer mail.Mailer) func(ctx context.Context, payload SendDigest) error {
+	return func(ctx context.Context, payload SendDigest) error {
+		return mailer.Send(ctx, mail.Message{
+			To:       payload.To,
+			Subject:  payload.Subject,
+			TextBody: payload.TextBody,
+			HTMLBody: payload.HTMLBody,
+		})
+	}
+}
+
+// ReleaseDigestHandler forgets the delivery of a SendDigest job that was
+// dead-lettered, so that the next run queues the digest again.
+func ReleaseDigestHandler(deliveryRepo digest.DeliveryRepository) func(ctx context.Context, payload SendDigest) error {
+	return func(ctx context.Context, payload SendDigest) error {
+		return deliveryRepo.Delete(ctx, payload.UserID, payload.Week)
+	}
+}
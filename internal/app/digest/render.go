@@ -0,0 +1,53 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"time"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+var templateFuncs = map[string]any{
+	"date": func(t time.Time) string { return t.Format("Mon 2 Jan 2006") },
+}
+
+var (
+	textTemplate = texttemplate.Must(texttemplate.New("digest.txt.tmpl").
+			Funcs(templateFuncs).
+			ParseFS(templateFS, "templates/digest.txt.tmpl"))
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("digest.html.tmpl").
+			Funcs(templateFuncs).
+			ParseFS(templateFS, "templates/digest.html.tmpl"))
+)
+
+type digestData struct {
+	Username    string
+	Week        int
+	WeekStart   time.Time
+	WeekLastDay time.Time
+	WeeksLived  int
+	NextWeek    int
+	Milestones  []milestoneData
+}
+
+type milestoneData struct {
+	Title string
+	Date  time.Time
+}
+
+func render(data digestData) (text, html string, err error) {
+	var textBuf, htmlBuf bytes.Buffer
+
+	if err := textTemplate.Execute(&textBuf, data); err != nil {
+		return "", "", err
+	}
+	if err := htmlTemplate.Execute(&htmlBuf, data); err != nil {
+		return "", "", err
+	}
+
+	return textBuf.String(), htmlBuf.String(), nil
+}
@@ -0,0 +1,150 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+)
+
+type Service interface {
+	SendDueDigests(ctx context.Context) (int, error)
+}
+
+type digestService struct {
+	userRepo      user.UserRepository
+	milestoneRepo milestone.MilestoneRepository
+	deliveryRepo  digest.DeliveryRepository
+	enqueuer      job.Enqueuer
+	now           func() time.Time
+}
+
+func NewDigestService(
+	userRepo user.UserRepository,
+	milestoneRepo milestone.MilestoneRepository,
+	deliveryRepo digest.DeliveryRepository,
+	enqueuer job.Enqueuer,
+) *digestService {
+	return &digestService{
+		userRepo:      userRepo,
+		milestoneRepo: milestoneRepo,
+		deliveryRepo:  deliveryRepo,
+		enqueuer:      enqueuer,
+		now:           time.Now,
+	}
+}
+
+// SendDueDigests queues a summary of the week that has just ended for every
+// user whose new week has started in their own time zone and who has not
+// had that digest yet. It returns how many digests were queued; failures for
+// individual users are joined into the returned error.
+func (s *digestService) SendDueDigests(ctx context.Context) (int, error) {
+	users, err := s.userRepo.FindAll(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := s.now()
+	sent := 0
+	var errs []error
+	for _, u := range users {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		ok, err := s.sendDigest(ctx, u, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("digest for user %s: %w", u.ID(), err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+func (s *digestService) sendDigest(ctx context.Context, u *user.User, now time.Time) (bool, error) {
+	current, err := u.CurrentWeek(now)
+	if err != nil || current.Number < 2 {
+		return false, nil
+	}
+
+	// Users who signed up during the current week have nothing to review.
+	y, m, d := current.Start.Date()
+	if !u.CreatedAt().Before(time.Date(y, m, d, 0, 0, 0, 0, u.Location())) {
+		return false, nil
+	}
+
+	previous := u.WeekAnchor().Nth(u.DateOfBirth(), current.Number-1)
+
+	delivery := digest.Delivery{UserID: u.ID(), Week: previous.Number, SentAt: now.UTC()}
+	if err := s.deliveryRepo.Record(ctx, delivery); err != nil {
+		if errors.Is(err, digest.ErrAlreadyDelivered) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	msg, err := s.buildMessage(ctx, u, previous, current)
+	if err == nil {
+		err = s.enqueuer.Enqueue(ctx, SendDigest{
+			UserID:   u.ID(),
+			Week:     previous.Number,
+			To:       msg.To,
+			Subject:  msg.Subject,
+			TextBody: msg.TextBody,
+			HTMLBody: msg.HTMLBody,
+		})
+	}
+	if err != nil {
+		if deleteErr := s.deliveryRepo.Delete(ctx, u.ID(), previous.Number); deleteErr != nil {
+			err = errors.Join(err, deleteErr)
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *digestService) buildMessage(ctx context.Context, u *user.User, previous, current week.Week) (mail.Message, error) {
+	milestones, err := s.milestoneRepo.FindByUserID(ctx, u.ID())
+	if err != nil {
+		return mail.Message{}, err
+	}
+
+	data := digestData{
+		Username:    u.Username(),
+		Week:        previous.Number,
+		WeekStart:   previous.Start,
+		WeekLastDay: previous.End.AddDate(0, 0, -1),
+		WeeksLived:  previous.Number,
+		NextWeek:    current.Number,
+	}
+	for _, m := range milestones {
+		day := week.Date(m.Date())
+		if !day.Before(previous.Start) && day.Before(previous.End) {
+			data.Milestones = append(data.Milestones, milestoneData{Title: m.Title(), Date: day})
+		}
+	}
+
+	text, html, err := render(data)
+	if err != nil {
+		return mail.Message{}, err
+	}
+
+	return mail.Message{
+		To:       u.Email(),
+		Subject:  fmt.Sprintf("Week %d in review", previous.Number),
+		TextBody: text,
+		HTMLBody: html,
+	}, nil
+}
@@ -0,0 +1,187 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errQueueFailure = errors.New("job queue unavailable")
+
+type fakeHasher struct{}
+
+func (f *fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+func (f *fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error { return nil }
+
+type fakeEnqueuer struct {
+	queued []SendDigest
+	err    error
+}
+
+func (f *fakeEnqueuer) Enqueue(ctx context.Context, payload job.Payload) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.queued = append(f.queued, payload.(SendDigest))
+	return nil
+}
+
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestSendDueDigests(t *testing.T) {
+	ctx := context.Background()
+	// A week later every user created now has finished at least one full
+	// week on the service.
+	now := time.Now().AddDate(0, 0, 8)
+
+	setup := func(t *testing.T) (*digestService, *fakeEnqueuer, *user.User) {
+		userRepo := memory.NewUserRepository()
+		milestoneRepo := memory.NewMilestoneRepository()
+		enqueuer := &fakeEnqueuer{}
+
+		u, err := user.NewUser(
+			context.Background(),
+			user.NewUserParams{
+				Email:       "john@example.com",
+				Username:    "johndoe",
+				Password:    "12345678",
+				DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+				TimeZone:    "Pacific/Auckland",
+			},
+			&fakeHasher{},
+		)
+		require.NoError(t, err)
+		require.NoError(t, userRepo.Create(ctx, u))
+
+		current, err := u.CurrentWeek(now)
+		require.NoError(t, err)
+		previous := u.WeekAnchor().Nth(u.DateOfBirth(), current.Number-1)
+
+		inPreviousWeek, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+			UserID: u.ID(),
+			Title:  "Ran a marathon",
+			Date:   previous.Start.AddDate(0, 0, 2),
+		})
+		require.NoError(t, err)
+		inCurrentWeek, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+			UserID: u.ID(),
+			Title:  "Started a new job",
+			Date:   current.Start,
+		})
+		require.NoError(t, err)
+		require.NoError(t, milestoneRepo.SaveAll(ctx, []*milestone.Milestone{inPreviousWeek, inCurrentWeek}))
+
+		service := NewDigestService(userRepo, milestoneRepo, memory.NewDeliveryRepository(), enqueuer)
+		service.now = func() time.Time { return now }
+		return service, enqueuer, u
+	}
+
+	t.Run("queues the previous week's digest once", func(t *testing.T) {
+		service, enqueuer, u := setup(t)
+
+		sent, err := service.SendDueDigests(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+
+		current, err := u.CurrentWeek(now)
+		require.NoError(t, err)
+
+		require.Len(t, enqueuer.queued, 1)
+		msg := enqueuer.queued[0]
+		assert.Equal(t, u.ID(), msg.UserID)
+		assert.Equal(t, current.Number-1, msg.Week)
+		assert.Equal(t, "john@example.com", msg.To)
+		assert.Equal(t, fmt.Sprintf("Week %d in review", current.Number-1), msg.Subject)
+		assert.Contains(t, msg.TextBody, "Ran a marathon")
+		assert.NotContains(t, msg.TextBody, "Started a new job")
+		assert.Contains(t, msg.TextBody, fmt.Sprintf("You have now lived %d weeks.", current.Number-1))
+		assert.Contains(t, msg.HTMLBody, "<li>")
+		assert.Contains(t, msg.HTMLBody, "Ran a marathon")
+
+		sent, err = service.SendDueDigests(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent, "digest should not be sent twice")
+		assert.Len(t, enqueuer.queued, 1)
+	})
+
+	t.Run("failed enqueue is retried on the next run", func(t *testing.T) {
+		service, enqueuer, _ := setup(t)
+		enqueuer.err = errQueueFailure
+
+		sent, err := service.SendDueDigests(ctx)
+		assert.ErrorIs(t, err, errQueueFailure)
+		assert.Equal(t, 0, sent)
+
+		enqueuer.err = nil
+		sent, err = service.SendDueDigests(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+	})
+
+	t.Run("dead-lettered digest is queued again on the next run", func(t *testing.T) {
+		service, enqueuer, _ := setup(t)
+
+		_, err := service.SendDueDigests(ctx)
+		require.NoError(t, err)
+		require.Len(t, enqueuer.queued, 1)
+
+		mailer := &recordingMailer{}
+		require.NoError(t, SendDigestHandler(mailer)(ctx, enqueuer.queued[0]))
+		require.Len(t, mailer.sent, 1)
+		assert.Equal(t, enqueuer.queued[0].Subject, mailer.sent[0].Subject)
+
+		require.NoError(t, ReleaseDigestHandler(service.deliveryRepo)(ctx, enqueuer.queued[0]))
+
+		sent, err := service.SendDueDigests(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Len(t, enqueuer.queued, 2)
+	})
+
+	t.Run("users who joined this week are skipped", func(t *testing.T) {
+		service, enqueuer, _ := setup(t)
+		service.now = time.Now
+
+		sent, err := service.SendDueDigests(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+		assert.Empty(t, enqueuer.queued)
+	})
+}
+
+func TestRender_EscapesHTML(t *testing.T) {
+	text, html, err := render(digestData{
+		Username:    "<script>",
+		Week:        10,
+		WeekStart:   time.Date(2025, time.July, 26, 0, 0, 0, 0, time.UTC),
+		WeekLastDay: time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC),
+		WeeksLived:  10,
+		NextWeek:    11,
+	})
+	require.NoError(t, err)
+
+	assert.Contains(t, text, "Hi <script>,")
+	assert.Contains(t, text, "from Sat 26 Jul 2025 to Fri 1 Aug 2025")
+	assert.Contains(t, text, "No milestones were recorded that week.")
+	assert.NotContains(t, html, "<script>")
+	assert.Contains(t, html, "&lt;script&gt;")
+}
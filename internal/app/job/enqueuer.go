@@ -0,0 +1,28 @@
+package job
+
+import (
+	"context"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+)
+
+type enqueuer struct {
+	store       job.Store
+	maxAttempts int
+}
+
+func NewEnqueuer(store job.Store, maxAttempts int) *enqueuer {
+	return &enqueuer{
+		store:       store,
+		maxAttempts: maxAttempts,
+	}
+}
+
+func (e *enqueuer) Enqueue(ctx context.Context, payload job.Payload) error {
+	j, err := job.New(payload, e.maxAttempts, time.Time{})
+	if err != nil {
+		return err
+	}
+	return e.store.Enqueue(ctx, j)
+}
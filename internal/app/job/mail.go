@@ -0,0 +1,49 @@
+package job
+
+import (
+	"context"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+)
+
+type SendEmail struct {
+	To       string `json:"to"`
+	Subject  string `json:"subject"`
+	TextBody string `json:"textBody"`
+	HTMLBody string `json:"htmlBody,omitempty"`
+}
+
+func (SendEmail) JobType() string { return "mail.send" }
+
+type queuedMailer struct {
+	enqueuer job.Enqueuer
+}
+
+// NewQueuedMailer returns a Mailer that enqueues messages instead of
+// sending them, so a slow or unavailable mail server is retried in the
+// background rather than failing the caller.
+func NewQueuedMailer(enqueuer job.Enqueuer) mail.Mailer {
+	return &queuedMailer{enqueuer: enqueuer}
+}
+
+func (m *queuedMailer) Send(ctx context.Context, msg mail.Message) error {
+	return m.enqueuer.Enqueue(ctx, SendEmail{
+		To:       msg.To,
+		Subject:  msg.Subject,
+		TextBody: msg.TextBody,
+		HTMLBody: msg.HTMLBody,
+	})
+}
+
+// SendEmailHandler delivers queued SendEmail jobs through mailer.
+func SendEmailHandler(mailer mail.Mailer) func(ctx context.Context, payload SendEmail) error {
+	return func(ctx context.Context, payload SendEmail) error {
+		return mailer.Send(ctx, mail.Message{
+			To:       payload.To,
+			Subject:  payload.Subject,
+			TextBody: payload.TextBody,
+			HTMLBody: payload.HTMLBody,
+		})
+	}
+}
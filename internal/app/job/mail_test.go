@@ -0,0 +1,52 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingMailer struct {
+	sent []mail.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func TestQueuedMailer(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewJobStore()
+	msg := mail.Message{
+		To:       "ada@example.com",
+		Subject:  "Your week",
+		TextBody: "text",
+		HTMLBody: "<p>html</p>",
+	}
+
+	err := NewQueuedMailer(NewEnqueuer(store, 3)).Send(ctx, msg)
+	require.NoError(t, err)
+
+	queued, err := store.Claim(ctx, time.Now().UTC(), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "mail.send", queued.Type)
+	assert.Equal(t, 3, queued.MaxAttempts)
+
+	var payload SendEmail
+	require.NoError(t, json.Unmarshal(queued.Payload, &payload))
+
+	mailer := &recordingMailer{}
+	require.NoError(t, SendEmailHandler(mailer)(ctx, payload))
+	assert.Equal(t, []mail.Message{msg}, mailer.sent)
+
+	_, err = store.Claim(ctx, time.Now().UTC(), time.Minute)
+	assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+}
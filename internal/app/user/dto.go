@@ -1,21 +1,115 @@
 package user
 
 import (
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 )
 
 type CreateUserRequest struct {
-	Email       string    `json:"email"`
-	Username    string    `json:"username"`
-	Password    string    `json:"password"`
-	DateOfBirth time.Time `json:"dob"`
+	Email       string     `json:"email"`
+	Username    string     `json:"username"`
+	Password    string     `json:"password"`
+	DateOfBirth time.Time  `json:"dob"`
+	WeekAnchor  WeekAnchor `json:"weekAnchor,omitzero"`
+	TimeZone    string     `json:"timeZone,omitempty"`
+}
+
+// LogValue leaves the password out of log output.
+func (r CreateUserRequest) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("email", r.Email),
+		slog.String("username", r.Username),
+		slog.String("password", "REDACTED"),
+		slog.Time("dob", r.DateOfBirth),
+		slog.String("time_zone", r.TimeZone),
+	)
 }
 
 type CreateUserResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Email       string    `json:"email"`
-	Username    string    `json:"username"`
-	DateOfBirth time.Time `json:"dob"`
+	ID            uuid.UUID  `json:"id"`
+	Email         string     `json:"email"`
+	Username      string     `json:"username"`
+	DateOfBirth   time.Time  `json:"dob"`
+	WeekAnchor    WeekAnchor `json:"weekAnchor"`
+	TimeZone      string     `json:"timeZone"`
+	CalendarToken string     `json:"calendarToken"`
+}
+
+// UpdateProfileRequest changes only the fields that are set. If IfMatch
+// is not empty, the update only applies to a profile that one of the
+// listed tags describes.
+type UpdateProfileRequest struct {
+	TimeZone   *string      `json:"timeZone,omitempty"`
+	WeekAnchor *WeekAnchor  `json:"weekAnchor,omitempty"`
+	IfMatch    []ProfileTag `json:"-"`
+}
+
+// ProfileTag identifies what a ProfileResponse shows: the stored version
+// and the current week, which changes without the profile being saved.
+type ProfileTag struct {
+	Version int64
+	Week    int
+}
+
+type ProfileResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	Email       string     `json:"email"`
+	Username    string     `json:"username"`
+	DateOfBirth time.Time  `json:"dob"`
+	WeekAnchor  WeekAnchor `json:"weekAnchor"`
+	TimeZone    string     `json:"timeZone"`
+	CurrentWeek int        `json:"currentWeek,omitempty"`
+	// Version changes whenever the stored profile does.
+	Version int64 `json:"-"`
+}
+
+func (p *ProfileResponse) Tag() ProfileTag {
+	return ProfileTag{Version: p.Version, Week: p.CurrentWeek}
+}
+
+// WeekAnchor selects how weeks are counted. Mode is one of "continuous",
+// "birthday" or "calendar"; FirstWeekday, such as "monday", only applies to
+// calendar weeks and defaults to Monday.
+type WeekAnchor struct {
+	Mode         string `json:"mode"`
+	FirstWeekday string `json:"firstWeekday,omitempty"`
+}
+
+func (a WeekAnchor) toDomain() (week.Anchor, error) {
+	anchor := week.Anchor{Mode: week.Mode(strings.ToLower(a.Mode))}
+	if anchor.Mode != week.ModeCalendar {
+		return anchor, nil
+	}
+
+	anchor.FirstWeekday = time.Monday
+	if a.FirstWeekday != "" {
+		wd, ok := weekdays[strings.ToLower(a.FirstWeekday)]
+		if !ok {
+			return week.Anchor{}, week.ErrInvalidWeekday
+		}
+		anchor.FirstWeekday = wd
+	}
+	return anchor, nil
+}
+
+func weekAnchorFromDomain(a week.Anchor) WeekAnchor {
+	dto := WeekAnchor{Mode: string(a.Mode)}
+	if a.Mode == week.ModeCalendar {
+		dto.FirstWeekday = strings.ToLower(a.FirstWeekday.String())
+	}
+	return dto
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
 }
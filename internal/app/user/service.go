@@ -3,35 +3,67 @@ package user
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/transaction"
 	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 )
 
-var ErrEmailExists = errors.New("email already exists")
+var (
+	ErrEmailExists    = errors.New("email already exists")
+	ErrUserNotFound   = errors.New("user not found")
+	ErrInvalidProfile = errors.New("invalid profile")
+	ErrInvalidUser    = errors.New("invalid user")
+	// ErrVersionMismatch means a conditional update found the profile at
+	// a version other than the ones it was conditional on.
+	ErrVersionMismatch = errors.New("profile has changed")
+	ErrConflict        = errors.New("profile was modified concurrently")
+	// ErrInvalidCredentials means no user has the email or the password is
+	// not theirs.
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrAccountLocked      = errors.New("account temporarily locked")
+)
 
 type Service interface {
 	CreateUser(ctx context.Context, req CreateUserRequest) (*CreateUserResponse, error)
+	Authenticate(ctx context.Context, email, password string) (uuid.UUID, error)
+	GetProfile(ctx context.Context, id uuid.UUID) (*ProfileResponse, error)
+	UpdateProfile(ctx context.Context, id uuid.UUID, req UpdateProfileRequest) (*ProfileResponse, error)
 }
 
 type userService struct {
 	userRepo       user.UserRepository
 	passwordHasher user.PasswordHasher
+	transactions   transaction.Manager
+	now            func() time.Time
 }
 
-func NewUserService(repo user.UserRepository, hasher user.PasswordHasher) *userService {
+func NewUserService(repo user.UserRepository, hasher user.PasswordHasher, transactions transaction.Manager) *userService {
 	return &userService{
 		userRepo:       repo,
 		passwordHasher: hasher,
+		transactions:   transactions,
+		now:            time.Now,
 	}
 }
 
+// CreateUser leaves it to the repository to reject a registered email, so
+// that of concurrent signups with the same email exactly one succeeds. The
+// user is stored in a transaction, which anything else written on signup
+// should join.
 func (s *userService) CreateUser(ctx context.Context, req CreateUserRequest) (*CreateUserResponse, error) {
-	_, err := s.userRepo.FindByEmail(ctx, req.Email)
-	if err == nil {
-		return nil, ErrEmailExists
+	weekAnchor, err := req.WeekAnchor.toDomain()
+	if err == nil && weekAnchor.Mode != "" {
+		err = weekAnchor.Validate()
 	}
-	if !errors.Is(err, user.ErrUserNotFound) {
-		return nil, err
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUser, err)
 	}
 
 	newUserParams := user.NewUserParams{
@@ -39,23 +71,186 @@ func (s *userService) CreateUser(ctx context.Context, req CreateUserRequest) (*C
 		Username:    req.Username,
 		Password:    req.Password,
 		DateOfBirth: req.DateOfBirth,
+		WeekAnchor:  weekAnchor,
+		TimeZone:    req.TimeZone,
 	}
 
-	newUser, err := user.NewUser(newUserParams, s.passwordHasher)
+	newUser, err := user.NewUser(ctx, newUserParams, s.passwordHasher)
+	if isInvalidUser(err) {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidUser, err)
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	if err := s.userRepo.Save(ctx, newUser); err != nil {
+	err = s.transactions.Run(ctx, func(ctx context.Context) error {
+		return s.userRepo.Create(ctx, newUser)
+	})
+	if errors.Is(err, user.ErrEmailTaken) {
+		return nil, ErrEmailExists
+	}
+	if err != nil {
 		return nil, err
 	}
+	slog.InfoContext(ctx, "user created", "user_id", newUser.ID())
 
 	resp := &CreateUserResponse{
-		ID:          newUser.ID(),
-		Email:       newUser.Email(),
-		Username:    newUser.Username(),
-		DateOfBirth: newUser.DateOfBirth(),
+		ID:            newUser.ID(),
+		Email:         newUser.Email(),
+		Username:      newUser.Username(),
+		DateOfBirth:   newUser.DateOfBirth(),
+		WeekAnchor:    weekAnchorFromDomain(newUser.WeekAnchor()),
+		TimeZone:      newUser.Location().String(),
+		CalendarToken: newUser.CalendarToken(),
 	}
 
 	return resp, nil
 }
+
+// isInvalidUser tells the user errors caused by what the client sent from
+// failures to create the user, such as the hasher's.
+func isInvalidUser(err error) bool {
+	for _, target := range []error{
+		user.ErrEmailRequired,
+		user.ErrInvalidEmailFormat,
+		user.ErrUsernameRequired,
+		user.ErrPasswordTooShort,
+		user.ErrInvalidTimeZone,
+		week.ErrInvalidMode,
+		week.ErrInvalidWeekday,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticate returns the ID of the user signed up with email if password
+// is theirs. A locked account fails with ErrAccountLocked whatever the
+// password.
+func (s *userService) Authenticate(ctx context.Context, email, password string) (uuid.UUID, error) {
+	u, err := s.userRepo.FindByEmail(ctx, email)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return uuid.Nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = s.passwordHasher.Compare(ctx, u.PasswordHash(), password)
+	if errors.Is(err, user.ErrPasswordMismatch) {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, err)
+	}
+	if errors.Is(err, user.ErrAccountLocked) {
+		return uuid.Nil, fmt.Errorf("%w: %w", ErrAccountLocked, err)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return u.ID(), nil
+}
+
+func (s *userService) GetProfile(ctx context.Context, id uuid.UUID) (*ProfileResponse, error) {
+	u, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.profileResponse(u), nil
+}
+
+// maxUpdateAttempts bounds how often an unconditional update is retried
+// when another update got in first.
+const maxUpdateAttempts = 3
+
+// UpdateProfile applies req to the stored profile. An update conditional on
+// IfMatch fails with ErrVersionMismatch if the profile has changed; others
+// are applied again to the latest profile, and fail with ErrConflict only
+// if that keeps changing under them.
+func (s *userService) UpdateProfile(ctx context.Context, id uuid.UUID, req UpdateProfileRequest) (*ProfileResponse, error) {
+	for attempt := 1; ; attempt++ {
+		u, err := s.updateProfile(ctx, id, req)
+		switch {
+		case errors.Is(err, aggregate.ErrConflict) && len(req.IfMatch) > 0:
+			return nil, fmt.Errorf("%w: %w", ErrVersionMismatch, err)
+		case errors.Is(err, aggregate.ErrConflict) && attempt < maxUpdateAttempts:
+			continue
+		case errors.Is(err, aggregate.ErrConflict):
+			return nil, fmt.Errorf("%w: %w", ErrConflict, err)
+		case err != nil:
+			return nil, err
+		}
+
+		slog.InfoContext(ctx, "profile updated", "user_id", u.ID())
+		return s.profileResponse(u), nil
+	}
+}
+
+func (s *userService) updateProfile(ctx context.Context, id uuid.UUID, req UpdateProfileRequest) (*user.User, error) {
+	u, err := s.findUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.IfMatch) > 0 && !slices.Contains(req.IfMatch, s.profileResponse(u).Tag()) {
+		return nil, ErrVersionMismatch
+	}
+
+	var weekAnchor *week.Anchor
+	if req.WeekAnchor != nil {
+		anchor, err := req.WeekAnchor.toDomain()
+		if err == nil {
+			err = anchor.Validate()
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidProfile, err)
+		}
+		weekAnchor = &anchor
+	}
+
+	if req.TimeZone != nil {
+		if err := u.ChangeTimeZone(*req.TimeZone); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidProfile, err)
+		}
+	}
+
+	if weekAnchor != nil {
+		if err := u.ChangeWeekAnchor(*weekAnchor); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidProfile, err)
+		}
+	}
+
+	if err := s.userRepo.Save(ctx, u); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *userService) findUser(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	u, err := s.userRepo.FindByID(ctx, id)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (s *userService) profileResponse(u *user.User) *ProfileResponse {
+	resp := &ProfileResponse{
+		ID:          u.ID(),
+		Email:       u.Email(),
+		Username:    u.Username(),
+		DateOfBirth: u.DateOfBirth(),
+		WeekAnchor:  weekAnchorFromDomain(u.WeekAnchor()),
+		TimeZone:    u.Location().String(),
+		Version:     int64(u.Version()),
+	}
+
+	if w, err := u.CurrentWeek(s.now()); err == nil {
+		resp.CurrentWeek = w.Number
+	}
+
+	return resp
+}
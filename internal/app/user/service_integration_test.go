@@ -2,6 +2,9 @@ package user
 
 import (
 	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -9,6 +12,7 @@ import (
 	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestCreateUserIntegration(t *testing.T) {
@@ -34,13 +38,19 @@ func TestCreateUserIntegration(t *testing.T) {
 			preExistingUsers: nil,
 			wantErr:          false,
 		},
+		{
+			name:             "reject a registered email",
+			request:          newUserRequest,
+			preExistingUsers: []CreateUserRequest{newUserRequest},
+			wantErr:          true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			userRepo := memory.NewUserRepository()
 			passwordHasher := auth.BcryptHasher{}
-			userService := NewUserService(userRepo, &passwordHasher)
+			userService := NewUserService(userRepo, &passwordHasher, memory.NewTransactionManager())
 
 			for _, req := range tt.preExistingUsers {
 				_, err := userService.CreateUser(context.Background(), req)
@@ -74,3 +84,35 @@ func TestCreateUserIntegration(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateUser_ConcurrentSignups(t *testing.T) {
+	userService := NewUserService(memory.NewUserRepository(), auth.NewBcryptHasher(bcrypt.MinCost), memory.NewTransactionManager())
+	req := CreateUserRequest{
+		Email:       "john@example.com",
+		Username:    "johndoe",
+		Password:    "12345678",
+		DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+	}
+
+	var created, rejected atomic.Int32
+	var wg sync.WaitGroup
+	for range 300 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := userService.CreateUser(context.Background(), req)
+			switch {
+			case err == nil:
+				created.Add(1)
+			case errors.Is(err, ErrEmailExists):
+				rejected.Add(1)
+			default:
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created.Load(), "exactly one signup wins")
+	assert.Equal(t, int32(299), rejected.Load())
+}
@@ -1,13 +1,19 @@
 package user
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
 	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user/usertest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -15,47 +21,28 @@ import (
 
 var errRepositoryFailure = errors.New("error in data repository")
 
-type MockUserRepository struct {
-	mock.Mock
-}
-
-func (m *MockUserRepository) Save(ctx context.Context, u *user.User) error {
-	args := m.Called(ctx, u)
-	return args.Error(0)
-}
-
-func (m *MockUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
-	args := m.Called(ctx, id)
-	var u *user.User
-	if args.Get(0) != nil {
-		u = args.Get(0).(*user.User)
-	}
-	return u, args.Error(1)
-}
-
-func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
-	args := m.Called(ctx, email)
-	var u *user.User
-	if args.Get(0) != nil {
-		u = args.Get(0).(*user.User)
-	}
-	return u, args.Error(1)
-}
-
 type MockPasswordHasher struct {
 	mock.Mock
 }
 
-func (m *MockPasswordHasher) Hash(password string) (string, error) {
+func (m *MockPasswordHasher) Hash(ctx context.Context, password string) (string, error) {
 	args := m.Called(password)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockPasswordHasher) Compare(hashedPassword, password string) error {
+func (m *MockPasswordHasher) Compare(ctx context.Context, hashedPassword, password string) error {
 	args := m.Called(hashedPassword, password)
 	return args.Error(0)
 }
 
+// passthroughTransactions runs use cases without a transaction, which the
+// mocks have no use for.
+type passthroughTransactions struct{}
+
+func (passthroughTransactions) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 func TestCreateUser(t *testing.T) {
 	dob := time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC)
 	createUserRequest := CreateUserRequest{
@@ -65,33 +52,19 @@ func TestCreateUser(t *testing.T) {
 		DateOfBirth: dob,
 	}
 
-	setupHasher := new(MockPasswordHasher)
-	setupHasher.On("Hash", "password").Return("hashed-password", nil)
-	existingUser, _ := user.NewUser(
-		user.NewUserParams{
-			Email:       "john@example.com",
-			Username:    "existing-user",
-			Password:    "password",
-			DateOfBirth: dob,
-		},
-		setupHasher,
-	)
-
 	tests := []struct {
 		name        string
 		req         CreateUserRequest
-		mockSetup   func(mockRepo *MockUserRepository, mockHasher *MockPasswordHasher)
+		mockSetup   func(mockRepo *usertest.MockRepository, mockHasher *MockPasswordHasher)
 		expectedErr error
 	}{
 		{
 			name: "successfully create user",
 			req:  createUserRequest,
-			mockSetup: func(mockRepo *MockUserRepository, mockHasher *MockPasswordHasher) {
+			mockSetup: func(mockRepo *usertest.MockRepository, mockHasher *MockPasswordHasher) {
 				mockHasher.On("Hash", createUserRequest.Password).
 					Return("hashed-password", nil).Once()
-				mockRepo.On("FindByEmail", mock.Anything, createUserRequest.Email).
-					Return(nil, user.ErrUserNotFound).Once()
-				mockRepo.On("Save", mock.Anything, mock.AnythingOfType("*user.User")).
+				mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).
 					Return(nil).Once()
 			},
 			expectedErr: nil,
@@ -99,30 +72,41 @@ func TestCreateUser(t *testing.T) {
 		{
 			name: "error on duplicate email",
 			req:  createUserRequest,
-			mockSetup: func(mockRepo *MockUserRepository, mockHasher *MockPasswordHasher) {
-				mockRepo.On("FindByEmail", mock.Anything, createUserRequest.Email).
-					Return(existingUser, nil).Once()
+			mockSetup: func(mockRepo *usertest.MockRepository, mockHasher *MockPasswordHasher) {
+				mockHasher.On("Hash", createUserRequest.Password).
+					Return("hashed-password", nil).Once()
+				mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).
+					Return(user.ErrEmailTaken).Once()
 			},
 			expectedErr: ErrEmailExists,
 		},
 		{
-			name: "repository error during email lookup",
-			req:  createUserRequest,
-			mockSetup: func(mockRepo *MockUserRepository, mockHasher *MockPasswordHasher) {
-				mockRepo.On("FindByEmail", mock.Anything, createUserRequest.Email).
-					Return(nil, errRepositoryFailure).Once()
-			},
-			expectedErr: errRepositoryFailure,
+			name: "unknown time zone",
+			req: func() CreateUserRequest {
+				req := createUserRequest
+				req.TimeZone = "Mars/Olympus_Mons"
+				return req
+			}(),
+			mockSetup:   func(mockRepo *usertest.MockRepository, mockHasher *MockPasswordHasher) {},
+			expectedErr: ErrInvalidUser,
 		},
 		{
-			name: "repository error during save",
+			name: "local time zone",
+			req: func() CreateUserRequest {
+				req := createUserRequest
+				req.TimeZone = "Local"
+				return req
+			}(),
+			mockSetup:   func(mockRepo *usertest.MockRepository, mockHasher *MockPasswordHasher) {},
+			expectedErr: ErrInvalidUser,
+		},
+		{
+			name: "repository error during create",
 			req:  createUserRequest,
-			mockSetup: func(mockRepo *MockUserRepository, mockHasher *MockPasswordHasher) {
+			mockSetup: func(mockRepo *usertest.MockRepository, mockHasher *MockPasswordHasher) {
 				mockHasher.On("Hash", createUserRequest.Password).
 					Return("hashed-password", nil).Once()
-				mockRepo.On("FindByEmail", mock.Anything, createUserRequest.Email).
-					Return(nil, user.ErrUserNotFound).Once()
-				mockRepo.On("Save", mock.Anything, mock.AnythingOfType("*user.User")).
+				mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).
 					Return(errRepositoryFailure)
 			},
 			expectedErr: errRepositoryFailure,
@@ -131,22 +115,18 @@ func TestCreateUser(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRepo := new(MockUserRepository)
+			mockRepo := new(usertest.MockRepository)
 			mockHasher := new(MockPasswordHasher)
 			tt.mockSetup(mockRepo, mockHasher)
 
-			userService := NewUserService(mockRepo, mockHasher)
+			userService := NewUserService(mockRepo, mockHasher, passthroughTransactions{})
 
 			resp, err := userService.CreateUser(context.Background(), tt.req)
 
 			if tt.expectedErr != nil {
 				require.Error(t, err)
 
-				if errors.Is(tt.expectedErr, ErrEmailExists) {
-					assert.ErrorIs(t, err, tt.expectedErr)
-				} else if errors.Is(tt.expectedErr, errRepositoryFailure) {
-					assert.ErrorIs(t, err, tt.expectedErr)
-				}
+				assert.ErrorIs(t, err, tt.expectedErr)
 				assert.Nil(t, resp, "response should be nil when error is returned")
 			} else {
 				require.NoError(t, err, "CreateUser failed unexpectedly")
@@ -155,3 +135,343 @@ func TestCreateUser(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateUser_WeekAnchor(t *testing.T) {
+	tests := []struct {
+		name           string
+		weekAnchor     WeekAnchor
+		expectedAnchor WeekAnchor
+		expectedErr    error
+	}{
+		{
+			name:           "defaults to continuous weeks",
+			weekAnchor:     WeekAnchor{},
+			expectedAnchor: WeekAnchor{Mode: "continuous"},
+		},
+		{
+			name:           "birthday weeks",
+			weekAnchor:     WeekAnchor{Mode: "Birthday"},
+			expectedAnchor: WeekAnchor{Mode: "birthday"},
+		},
+		{
+			name:           "calendar weeks default to monday",
+			weekAnchor:     WeekAnchor{Mode: "calendar"},
+			expectedAnchor: WeekAnchor{Mode: "calendar", FirstWeekday: "monday"},
+		},
+		{
+			name:           "calendar weeks starting on sunday",
+			weekAnchor:     WeekAnchor{Mode: "calendar", FirstWeekday: "Sunday"},
+			expectedAnchor: WeekAnchor{Mode: "calendar", FirstWeekday: "sunday"},
+		},
+		{
+			name:        "unknown first weekday",
+			weekAnchor:  WeekAnchor{Mode: "calendar", FirstWeekday: "someday"},
+			expectedErr: week.ErrInvalidWeekday,
+		},
+		{
+			name:        "unknown mode",
+			weekAnchor:  WeekAnchor{Mode: "lunar"},
+			expectedErr: week.ErrInvalidMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CreateUserRequest{
+				Email:       "john@example.com",
+				Username:    "johndoe",
+				Password:    "12345678",
+				DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+				WeekAnchor:  tt.weekAnchor,
+			}
+
+			mockRepo := new(usertest.MockRepository)
+			mockHasher := new(MockPasswordHasher)
+			mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*user.User")).Return(nil)
+			mockHasher.On("Hash", req.Password).Return("hashed-password", nil)
+
+			resp, err := NewUserService(mockRepo, mockHasher, passthroughTransactions{}).CreateUser(context.Background(), req)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.ErrorIs(t, err, ErrInvalidUser)
+				assert.Nil(t, resp)
+				mockHasher.AssertNotCalled(t, "Hash", req.Password)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedAnchor, resp.WeekAnchor)
+		})
+	}
+}
+
+func TestUpdateProfile(t *testing.T) {
+	now := time.Date(2025, time.August, 1, 12, 30, 0, 0, time.UTC)
+	auckland := "Pacific/Auckland"
+	nowhere := "Nowhere/Special"
+
+	newExistingUser := func(t *testing.T) *user.User {
+		setupHasher := new(MockPasswordHasher)
+		setupHasher.On("Hash", "12345678").Return("hashed-password", nil)
+		u, err := user.NewUser(
+			context.Background(),
+			user.NewUserParams{
+				Email:       "john@example.com",
+				Username:    "johndoe",
+				Password:    "12345678",
+				DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+			},
+			setupHasher,
+		)
+		require.NoError(t, err)
+		return u
+	}
+
+	tests := []struct {
+		name             string
+		req              UpdateProfileRequest
+		findErr          error
+		saveErr          error
+		expectedTimeZone string
+		expectedAnchor   WeekAnchor
+		expectedWeek     int
+		expectedErr      error
+	}{
+		{
+			name:             "change time zone moves current week",
+			req:              UpdateProfileRequest{TimeZone: &auckland},
+			expectedTimeZone: auckland,
+			expectedAnchor:   WeekAnchor{Mode: "continuous"},
+			expectedWeek:     1707,
+		},
+		{
+			name:             "change week anchor",
+			req:              UpdateProfileRequest{WeekAnchor: &WeekAnchor{Mode: "birthday"}},
+			expectedTimeZone: "UTC",
+			expectedAnchor:   WeekAnchor{Mode: "birthday"},
+			expectedWeek:     1701,
+		},
+		{
+			name:        "unknown time zone",
+			req:         UpdateProfileRequest{TimeZone: &nowhere},
+			expectedErr: ErrInvalidProfile,
+		},
+		{
+			name:        "invalid week anchor leaves time zone untouched",
+			req:         UpdateProfileRequest{TimeZone: &auckland, WeekAnchor: &WeekAnchor{Mode: "lunar"}},
+			expectedErr: ErrInvalidProfile,
+		},
+		{
+			name:        "user not found",
+			req:         UpdateProfileRequest{TimeZone: &auckland},
+			findErr:     user.ErrUserNotFound,
+			expectedErr: ErrUserNotFound,
+		},
+		{
+			name:        "repository error during save",
+			req:         UpdateProfileRequest{TimeZone: &auckland},
+			saveErr:     errRepositoryFailure,
+			expectedErr: errRepositoryFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			existingUser := newExistingUser(t)
+
+			mockRepo := new(usertest.MockRepository)
+			if tt.findErr != nil {
+				mockRepo.On("FindByID", mock.Anything, existingUser.ID()).Return(nil, tt.findErr).Once()
+			} else {
+				mockRepo.On("FindByID", mock.Anything, existingUser.ID()).Return(existingUser, nil).Once()
+			}
+			mockRepo.On("Save", mock.Anything, existingUser).Return(tt.saveErr).Maybe()
+
+			userService := NewUserService(mockRepo, new(MockPasswordHasher), passthroughTransactions{})
+			userService.now = func() time.Time { return now }
+
+			resp, err := userService.UpdateProfile(context.Background(), existingUser.ID(), tt.req)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, resp)
+				if errors.Is(err, ErrInvalidProfile) {
+					assert.Equal(t, "UTC", existingUser.Location().String(), "invalid update should not change the user")
+				}
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, existingUser.ID(), resp.ID)
+			assert.Equal(t, tt.expectedTimeZone, resp.TimeZone)
+			assert.Equal(t, tt.expectedAnchor, resp.WeekAnchor)
+			assert.Equal(t, tt.expectedWeek, resp.CurrentWeek)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateProfile_Conflicts(t *testing.T) {
+	auckland := "Pacific/Auckland"
+	conflict := fmt.Errorf("saving: %w", aggregate.ErrConflict)
+	now := time.Date(2025, time.August, 1, 12, 30, 0, 0, time.UTC)
+	const week = 1706
+
+	tests := []struct {
+		name          string
+		ifMatch       []ProfileTag
+		conflicts     int
+		expectedSaves int
+		expectedErr   error
+	}{
+		{
+			name:          "matching version",
+			ifMatch:       []ProfileTag{{Version: 7, Week: week}, {Version: 0, Week: week}},
+			expectedSaves: 1,
+		},
+		{
+			name:        "stale version",
+			ifMatch:     []ProfileTag{{Version: 7, Week: week}},
+			expectedErr: ErrVersionMismatch,
+		},
+		{
+			name:        "same version in another week",
+			ifMatch:     []ProfileTag{{Version: 0, Week: week - 1}},
+			expectedErr: ErrVersionMismatch,
+		},
+		{
+			name:          "concurrent change to a matching version",
+			ifMatch:       []ProfileTag{{Version: 0, Week: week}},
+			conflicts:     1,
+			expectedSaves: 1,
+			expectedErr:   ErrVersionMismatch,
+		},
+		{
+			name:          "unconditional update retries",
+			conflicts:     2,
+			expectedSaves: 3,
+		},
+		{
+			name:          "unconditional update gives up",
+			conflicts:     maxUpdateAttempts,
+			expectedSaves: maxUpdateAttempts,
+			expectedErr:   ErrConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupHasher := new(MockPasswordHasher)
+			setupHasher.On("Hash", "12345678").Return("hashed-password", nil)
+			existingUser, err := user.NewUser(
+				context.Background(),
+				user.NewUserParams{
+					Email:       "john@example.com",
+					Username:    "johndoe",
+					Password:    "12345678",
+					DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+				},
+				setupHasher,
+			)
+			require.NoError(t, err)
+
+			mockRepo := new(usertest.MockRepository)
+			mockRepo.On("FindByID", mock.Anything, existingUser.ID()).Return(existingUser, nil)
+			if tt.conflicts > 0 {
+				mockRepo.On("Save", mock.Anything, existingUser).Return(conflict).Times(tt.conflicts)
+			}
+			mockRepo.On("Save", mock.Anything, existingUser).Return(nil).Maybe()
+
+			userService := NewUserService(mockRepo, new(MockPasswordHasher), passthroughTransactions{})
+			userService.now = func() time.Time { return now }
+			resp, err := userService.UpdateProfile(context.Background(), existingUser.ID(),
+				UpdateProfileRequest{TimeZone: &auckland, IfMatch: tt.ifMatch})
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, resp)
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, auckland, resp.TimeZone)
+			}
+			mockRepo.AssertNumberOfCalls(t, "Save", tt.expectedSaves)
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	setupHasher := new(MockPasswordHasher)
+	setupHasher.On("Hash", "12345678").Return("hashed-password", nil)
+	existing, err := user.NewUser(
+		context.Background(),
+		user.NewUserParams{
+			Email:       "john@example.com",
+			Username:    "johndoe",
+			Password:    "12345678",
+			DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+		},
+		setupHasher,
+	)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		findErr     error
+		compareErr  error
+		expectedID  uuid.UUID
+		expectedErr error
+	}{
+		{name: "right password", expectedID: existing.ID()},
+		{name: "unknown email", findErr: user.ErrUserNotFound, expectedErr: ErrInvalidCredentials},
+		{name: "wrong password", compareErr: user.ErrPasswordMismatch, expectedErr: ErrInvalidCredentials},
+		{name: "locked account", compareErr: user.ErrAccountLocked, expectedErr: ErrAccountLocked},
+		{name: "failed attempts unreadable", compareErr: errRepositoryFailure, expectedErr: errRepositoryFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(usertest.MockRepository)
+			mockHasher := new(MockPasswordHasher)
+			if tt.findErr != nil {
+				mockRepo.On("FindByEmail", mock.Anything, "john@example.com").Return(nil, tt.findErr).Once()
+			} else {
+				mockRepo.On("FindByEmail", mock.Anything, "john@example.com").Return(existing, nil).Once()
+				mockHasher.On("Compare", "hashed-password", "12345678").Return(tt.compareErr).Once()
+			}
+
+			id, err := NewUserService(mockRepo, mockHasher, passthroughTransactions{}).Authenticate(context.Background(), "john@example.com", "12345678")
+
+			assert.ErrorIs(t, err, tt.expectedErr)
+			assert.Equal(t, tt.expectedID, id)
+			mockRepo.AssertExpectations(t)
+			mockHasher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetProfile(t *testing.T) {
+	id := uuid.New()
+
+	mockRepo := new(usertest.MockRepository)
+	mockRepo.On("FindByID", mock.Anything, id).Return(nil, user.ErrUserNotFound).Once()
+
+	resp, err := NewUserService(mockRepo, new(MockPasswordHasher), passthroughTransactions{}).GetProfile(context.Background(), id)
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	assert.Nil(t, resp)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateUserRequest_LogValue(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	logger.Info("signup", "request", CreateUserRequest{
+		Email:    "ada@example.com",
+		Username: "ada",
+		Password: "hunter2hunter2",
+	})
+
+	assert.NotContains(t, buf.String(), "hunter2")
+	assert.Contains(t, buf.String(), `"email":"ada@example.com"`)
+	assert.Contains(t, buf.String(), `"password":"REDACTED"`)
+}
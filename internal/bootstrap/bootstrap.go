@@ -0,0 +1,394 @@
+package bootstrap
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/app/calendar"
+	"github.com/mgwinsor/weekbyweek/internal/app/digest"
+	"github.com/mgwinsor/weekbyweek/internal/app/job"
+	appuser "github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/mgwinsor/weekbyweek/internal/config"
+	digestdomain "github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	maildomain "github.com/mgwinsor/weekbyweek/internal/domain/mail"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/health"
+	"github.com/mgwinsor/weekbyweek/internal/metrics"
+	"github.com/mgwinsor/weekbyweek/internal/primary/api"
+	"github.com/mgwinsor/weekbyweek/internal/primary/scheduler"
+	"github.com/mgwinsor/weekbyweek/internal/primary/server"
+	"github.com/mgwinsor/weekbyweek/internal/primary/worker"
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/auth"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/mail"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/boltstore"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/cache"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/sqlstore"
+	"github.com/mgwinsor/weekbyweek/internal/tracing"
+	bolt "go.etcd.io/bbolt"
+)
+
+// App is the fully wired application: the HTTP handler plus the background
+// components that run alongside it.
+type App struct {
+	handler   http.Handler
+	scheduler *scheduler.DigestScheduler
+	workers   *worker.Pool
+	persisted *memory.Persistence
+	health    *health.Registry
+	closers   []func(context.Context) error
+}
+
+// New builds the object graph described by cfg. Nothing runs until Start
+// is called; Stop releases what New acquired even if Start never was.
+func New(ctx context.Context, cfg config.Config) (*App, error) {
+	app := &App{health: health.NewRegistry(cfg.Health.CheckTimeout)}
+
+	db, err := app.openDatabase(ctx, cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+	kv, err := app.openBolt(cfg.Storage)
+	if err != nil {
+		return nil, errors.Join(err, app.Stop(ctx))
+	}
+	if db == nil && kv == nil {
+		app.health.Register(health.Check{Name: "storage", Run: func(context.Context) error { return nil }})
+	}
+	userRepo, milestoneRepo, deliveryRepo, err := app.openMemory(cfg.Storage, db == nil && kv == nil)
+	if err != nil {
+		return nil, errors.Join(err, app.Stop(ctx))
+	}
+	// Milestones stay in memory with sqlite, outside its transactions; see
+	// transaction.Manager.
+	jobStore := memory.NewJobStore()
+	transactions := memory.NewTransactionManager()
+	if db != nil {
+		userRepo = sqlstore.NewUserRepository(db)
+		deliveryRepo = sqlstore.NewDeliveryRepository(db)
+		jobStore = sqlstore.NewJobStore(db)
+		transactions = sqlstore.NewTransactionManager(db)
+	}
+	if kv != nil {
+		userRepo = boltstore.NewUserRepository(kv)
+		milestoneRepo = boltstore.NewMilestoneRepository(kv)
+		deliveryRepo = boltstore.NewDeliveryRepository(kv)
+		jobStore = boltstore.NewJobStore(kv)
+		transactions = boltstore.NewTransactionManager(kv)
+	}
+	var passwordHasher user.PasswordHasher = auth.NewBcryptHasher(cfg.Hasher.BcryptCost)
+	mailer := app.newMailer(cfg.Mailer)
+
+	var limiter *api.RateLimiter
+	if cfg.RateLimit.Enabled {
+		var lockout *ratelimit.Lockout
+		limiter, lockout = newRateLimiting(cfg.RateLimit, db)
+		if lockout != nil {
+			passwordHasher = lockout.PasswordHasher(passwordHasher)
+		}
+	}
+
+	var m *metrics.Metrics
+	if cfg.Metrics.Enabled {
+		m = metrics.New()
+		userRepo = m.UserRepository(userRepo)
+		passwordHasher = m.PasswordHasher(passwordHasher)
+	}
+
+	t, err := app.newTracing(ctx, cfg.Tracing)
+	if err != nil {
+		return nil, errors.Join(err, app.Stop(ctx))
+	}
+	if t != nil {
+		userRepo = t.UserRepository(userRepo)
+		passwordHasher = t.PasswordHasher(passwordHasher)
+	}
+	// The cache goes outermost, so that hits are not timed or traced as
+	// calls to storage; it counts them itself, in the user cache metrics.
+	if cfg.Cache.Enabled {
+		users := cache.NewUsers(cfg.Cache.Users)
+		userRepo = users.UserRepository(userRepo)
+		transactions = users.Transactions(transactions)
+		if m != nil {
+			m.UserCache(users.Stats)
+		}
+	}
+
+	var userService appuser.Service = appuser.NewUserService(userRepo, passwordHasher, transactions)
+	if m != nil {
+		userService = m.UserService(userService)
+	}
+	if t != nil {
+		userService = t.UserService(userService)
+	}
+	calendarService := calendar.NewCalendarService(userRepo, milestoneRepo)
+	enqueuer := job.NewEnqueuer(jobStore, cfg.Jobs.MaxAttempts)
+	digestService := digest.NewDigestService(userRepo, milestoneRepo, deliveryRepo, enqueuer)
+
+	app.scheduler = scheduler.NewDigestScheduler(digestService, cfg.Digest.Interval)
+	app.workers = worker.NewPool(jobStore, cfg.Worker)
+	worker.Register(app.workers, job.SendEmailHandler(mailer))
+	worker.Register(app.workers, digest.SendDigestHandler(mailer))
+	worker.OnBury(app.workers, digest.ReleaseDigestHandler(deliveryRepo))
+	app.health.Register(health.Check{Name: "job_queue", Run: app.workers.Check})
+
+	var idempotent *api.Idempotency
+	if cfg.Idempotency.Enabled {
+		idempotent = newIdempotency(cfg.Idempotency, db)
+	}
+
+	var validator *api.OpenAPIValidator
+	if cfg.OpenAPI.ValidateRequests {
+		if validator, err = api.NewOpenAPIValidator(); err != nil {
+			return nil, errors.Join(err, app.Stop(ctx))
+		}
+	}
+
+	userHandler := api.NewUserHandler(userService)
+	calendarHandler := api.NewCalendarHandler(calendarService, userService)
+	healthHandler := api.NewHealthHandler(app.health)
+
+	r := chi.NewRouter()
+	r.Use(api.RequestID)
+	if t != nil {
+		r.Use(t.Middleware)
+	}
+	r.Use(api.RequestLogger)
+	if m != nil {
+		r.Use(m.Middleware)
+	}
+	r.Use(api.Recoverer)
+	if limiter != nil {
+		r.Use(limiter.Middleware)
+	}
+	if validator != nil {
+		r.Use(validator.Middleware)
+	}
+	if idempotent != nil {
+		r.Use(idempotent.Middleware)
+	}
+
+	if m != nil {
+		r.Method(http.MethodGet, cfg.Metrics.Path, m.Handler())
+	}
+	healthHandler.RegisterRoutes(r)
+	if cfg.OpenAPI.Enabled {
+		api.NewOpenAPIHandler().RegisterRoutes(r)
+	}
+	api.Version{
+		Name:        "v1",
+		Deprecation: cfg.API.V1Deprecation.Time,
+		Sunset:      cfg.API.V1Sunset.Time,
+	}.Route(r, userHandler.RegisterV1, calendarHandler.RegisterV1)
+	// Clients from before versioning keep working until the legacy sunset.
+	api.Version{
+		Deprecation: cfg.API.LegacyDeprecation.Time,
+		Sunset:      cfg.API.LegacySunset.Time,
+	}.Route(r, userHandler.RegisterV1, calendarHandler.RegisterV1)
+	app.handler = r
+
+	return app, nil
+}
+
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// SetShuttingDown fails readiness checks from now on.
+func (a *App) SetShuttingDown() {
+	a.health.SetShuttingDown()
+}
+
+func (a *App) Start(ctx context.Context) {
+	if a.persisted != nil {
+		a.persisted.Start(ctx)
+	}
+	a.workers.Start(ctx)
+	a.scheduler.Start(ctx)
+}
+
+// Stop shuts the background components down in dependency order: the
+// scheduler enqueues mail for the workers, and the workers use the store.
+// Resources are released last, flushing any buffered trace spans.
+func (a *App) Stop(ctx context.Context) error {
+	var errs []error
+	if a.scheduler != nil {
+		errs = append(errs, a.scheduler.Stop(ctx))
+	}
+	if a.workers != nil {
+		errs = append(errs, a.workers.Stop(ctx))
+	}
+	for _, close := range a.closers {
+		errs = append(errs, close(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Run serves the application until ctx is cancelled and then shuts it down.
+func Run(ctx context.Context, cfg config.Config) error {
+	app, err := New(ctx, cfg)
+	if err != nil {
+		return err
+	}
+
+	srv := server.New(app.Handler(), cfg.Server)
+	if err := srv.Listen(); err != nil {
+		return errors.Join(err, app.Stop(ctx))
+	}
+	srv.OnDrain(app.SetShuttingDown)
+	srv.OnShutdown(app.Stop)
+
+	app.Start(context.Background())
+	return srv.Run(ctx)
+}
+
+// openDatabase opens and migrates the database for the sqlite backend. It
+// returns nil for other backends.
+func (a *App) openDatabase(ctx context.Context, cfg config.StorageConfig) (*sql.DB, error) {
+	if cfg.Backend != config.StorageSQLite {
+		return nil, nil
+	}
+
+	db, err := sqlstore.Open(ctx, cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	a.health.Register(health.Check{Name: "storage", Run: db.PingContext})
+	a.closers = append(a.closers, func(context.Context) error { return db.Close() })
+	return db, nil
+}
+
+// openBolt opens the database file for the bolt backend. It returns nil for
+// other backends.
+func (a *App) openBolt(cfg config.StorageConfig) (*bolt.DB, error) {
+	if cfg.Backend != config.StorageBolt {
+		return nil, nil
+	}
+
+	db, err := boltstore.Open(cfg.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+	a.health.Register(health.Check{Name: "storage", Run: func(context.Context) error {
+		return db.View(func(*bolt.Tx) error { return nil })
+	}})
+	a.closers = append(a.closers, func(context.Context) error { return db.Close() })
+	return db, nil
+}
+
+// openMemory opens the repositories kept in memory, replaying them from
+// disk if persistence is configured. Users are among them only when there
+// is no database.
+func (a *App) openMemory(cfg config.StorageConfig, users bool) (user.UserRepository, milestone.MilestoneRepository, digestdomain.DeliveryRepository, error) {
+	if cfg.Persistence.Dir == "" {
+		return memory.NewUserRepository(), memory.NewMilestoneRepository(), memory.NewDeliveryRepository(), nil
+	}
+
+	p, err := memory.OpenPersistence(cfg.Persistence)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	a.persisted = p
+	a.closers = append(a.closers, p.Close)
+
+	userRepo := memory.NewUserRepository()
+	if users {
+		if userRepo, err = p.UserRepository(); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	milestoneRepo, err := p.MilestoneRepository()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	deliveryRepo, err := p.DeliveryRepository()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return userRepo, milestoneRepo, deliveryRepo, nil
+}
+
+// newRateLimiting builds the per-route limits and, unless disabled, the
+// password lockout. They count in db when limits are shared through storage
+// and the storage is a database.
+func newRateLimiting(cfg config.RateLimitConfig, db *sql.DB) (*api.RateLimiter, *ratelimit.Lockout) {
+	limits, failures := memory.NewRateLimitStore(), memory.NewFailureStore()
+	if cfg.Store == config.RateLimitStoreStorage && db != nil {
+		limits, failures = sqlstore.NewRateLimitStore(db), sqlstore.NewFailureStore(db)
+	}
+
+	rules := []api.RateLimitRule{
+		{Route: "POST /v1/users", Name: "ip", Limit: cfg.SignupPerIP, Key: api.ClientIP},
+		{Route: "POST /v1/users", Name: "email", Limit: cfg.SignupPerEmail, Key: api.JSONField("email")},
+		{Route: "GET /v1/users/{id}", Name: "ip", Limit: cfg.ProfilePerIP, Key: api.ClientIP},
+		{Route: "PATCH /v1/users/{id}", Name: "ip", Limit: cfg.ProfilePerIP, Key: api.ClientIP},
+		{Route: "GET /v1/calendar/{userID}/{token}.ics", Name: "ip", Limit: cfg.CalendarPerIP, Key: api.ClientIP},
+	}
+	// The legacy routes count against the same buckets as v1, so that
+	// clients cannot double their allowance by mixing the two.
+	for _, rule := range rules[:len(rules):len(rules)] {
+		rule.Bucket = rule.Route
+		rule.Route = strings.Replace(rule.Route, " /v1/", " /", 1)
+		rules = append(rules, rule)
+	}
+	limiter := api.NewRateLimiter(limits, rules...)
+	if cfg.Lockout.Threshold == 0 {
+		return limiter, nil
+	}
+	return limiter, ratelimit.NewLockout(failures, cfg.Lockout)
+}
+
+// newIdempotency keeps keys in db when they are shared through storage and
+// the storage is a database.
+func newIdempotency(cfg config.IdempotencyConfig, db *sql.DB) *api.Idempotency {
+	store := memory.NewIdempotencyStore()
+	if cfg.Store == config.IdempotencyStoreStorage && db != nil {
+		store = sqlstore.NewIdempotencyStore(db)
+	}
+	return api.NewIdempotency(store, cfg.Policy)
+}
+
+func (a *App) newTracing(ctx context.Context, cfg config.TracingConfig) (*tracing.Tracing, error) {
+	if cfg.Exporter == tracing.ExporterNone {
+		return nil, nil
+	}
+
+	provider, err := tracing.NewProvider(ctx, tracing.ProviderOptions{
+		Exporter:     cfg.Exporter,
+		OTLPEndpoint: cfg.OTLPEndpoint,
+		OTLPInsecure: cfg.OTLPInsecure,
+		SampleRatio:  cfg.SampleRatio,
+		Stdout:       os.Stdout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, provider.Shutdown)
+	return tracing.New(provider), nil
+}
+
+func (a *App) newMailer(cfg config.MailerConfig) maildomain.Mailer {
+	if cfg.Backend == config.MailerLog {
+		a.health.Register(health.Check{Name: "mailer", Run: func(context.Context) error { return nil }})
+		return mail.NewLogMailer()
+	}
+
+	mailer := mail.NewSMTPMailer(mail.SMTPConfig{
+		Addr:     cfg.SMTPAddr,
+		From:     cfg.From,
+		Username: cfg.SMTPUsername,
+		Password: cfg.SMTPPassword,
+	})
+	// Mail is queued and retried, so an unreachable server is reported but
+	// does not take the instance out of rotation.
+	a.health.Register(health.Check{Name: "mailer", Optional: true, Run: mailer.Check})
+	return mailer
+}
@@ -0,0 +1,514 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/config"
+	"github.com/mgwinsor/weekbyweek/internal/primary/api"
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testConfig() config.Config {
+	cfg := config.Default()
+	cfg.Hasher.BcryptCost = bcrypt.MinCost
+	cfg.Mailer.Backend = config.MailerLog
+	return cfg
+}
+
+func startApp(t *testing.T, cfg config.Config) *httptest.Server {
+	t.Helper()
+
+	app, err := New(context.Background(), cfg)
+	require.NoError(t, err)
+	app.Start(context.Background())
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		assert.NoError(t, app.Stop(ctx))
+	})
+
+	srv := httptest.NewServer(checkContract(t, app.Handler()))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// checkContract fails the test if any response to a routed request does not
+// match the OpenAPI document.
+func checkContract(t *testing.T, next http.Handler) http.Handler {
+	t.Helper()
+	validator, err := api.NewOpenAPIValidator()
+	require.NoError(t, err)
+	router := next.(chi.Routes)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A route context set up front is the one the router fills in,
+		// except for the router itself.
+		rctx := chi.NewRouteContext()
+		rctx.Routes = router
+		r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		next.ServeHTTP(rec, r)
+
+		if pattern := rctx.RoutePattern(); pattern != "" {
+			assert.NoError(t, validator.ValidateResponse(documentedRoute(r.Method+" "+pattern), rec.Code, rec.Header(), rec.Body.Bytes()))
+		}
+		maps.Copy(w.Header(), rec.Header())
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	})
+}
+
+func postJSON(t *testing.T, url string, body any) *http.Response {
+	t.Helper()
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// getAs reads url with the credentials of the user who signed up with
+// email and password.
+func getAs(t *testing.T, url, email, password string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.SetBasicAuth(email, password)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestCreateUser_EndToEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		storage config.StorageConfig
+	}{
+		{
+			name:    "memory",
+			storage: config.StorageConfig{Backend: config.StorageMemory},
+		},
+		{
+			name: "sqlite",
+			storage: config.StorageConfig{
+				Backend: config.StorageSQLite,
+				DSN:     filepath.Join(t.TempDir(), "weekbyweek.db"),
+			},
+		},
+		{
+			name: "bolt",
+			storage: config.StorageConfig{
+				Backend: config.StorageBolt,
+				DSN:     filepath.Join(t.TempDir(), "weekbyweek.bolt"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testConfig()
+			cfg.Storage = tt.storage
+			srv := startApp(t, cfg)
+
+			signup := map[string]any{
+				"email":    "ada@example.com",
+				"username": "ada",
+				"password": "correct horse battery",
+				"dob":      "1990-12-10T00:00:00Z",
+			}
+
+			resp := postJSON(t, srv.URL+"/v1/users", signup)
+			require.Equal(t, http.StatusCreated, resp.StatusCode)
+
+			var created struct {
+				ID            string `json:"id"`
+				Email         string `json:"email"`
+				Username      string `json:"username"`
+				Password      string `json:"password"`
+				CalendarToken string `json:"calendarToken"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+			assert.NotEmpty(t, created.ID)
+			assert.Equal(t, "ada@example.com", created.Email)
+			assert.Equal(t, "ada", created.Username)
+			assert.Empty(t, created.Password)
+			assert.NotEmpty(t, created.CalendarToken)
+
+			anonymous, err := http.Get(srv.URL + "/v1/users/" + created.ID)
+			require.NoError(t, err)
+			defer anonymous.Body.Close()
+			assert.Equal(t, http.StatusUnauthorized, anonymous.StatusCode)
+			profile := getAs(t, srv.URL+"/v1/users/"+created.ID, "ada@example.com", "correct horse battery")
+			assert.Equal(t, http.StatusOK, profile.StatusCode)
+			cached := getAs(t, srv.URL+"/v1/users/"+created.ID, "ada@example.com", "correct horse battery")
+			assert.Equal(t, http.StatusOK, cached.StatusCode)
+
+			duplicate := postJSON(t, srv.URL+"/v1/users", signup)
+			assert.Equal(t, http.StatusConflict, duplicate.StatusCode)
+
+			metrics, err := http.Get(srv.URL + "/metrics")
+			require.NoError(t, err)
+			defer metrics.Body.Close()
+			body, err := io.ReadAll(metrics.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), "weekbyweek_users_created_total 1")
+			assert.Contains(t, string(body), "weekbyweek_user_email_conflicts_total 1")
+			assert.Contains(t, string(body), `weekbyweek_http_requests_total{method="POST",route="/v1/users",status="201"} 1`)
+			assert.Contains(t, string(body), `weekbyweek_cache_hits_total{cache="user"} 1`)
+			assert.Contains(t, string(body), `weekbyweek_cache_misses_total{cache="user"} 1`)
+		})
+	}
+}
+
+func TestCreateUser_InvalidInput(t *testing.T) {
+	srv := startApp(t, testConfig())
+
+	for name, override := range map[string]map[string]any{
+		"unknown time zone": {"timeZone": "Mars/Olympus_Mons"},
+		"local time zone":   {"timeZone": "Local"},
+		"unknown week mode": {"weekAnchor": map[string]any{"mode": "lunar"}},
+		"unknown weekday":   {"weekAnchor": map[string]any{"mode": "calendar", "firstWeekday": "someday"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			signup := map[string]any{
+				"email":    "ada@example.com",
+				"username": "ada",
+				"password": "correct horse battery",
+				"dob":      "1990-12-10T00:00:00Z",
+			}
+			maps.Copy(signup, override)
+
+			resp := postJSON(t, srv.URL+"/v1/users", signup)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			assert.Equal(t, "application/problem+json", resp.Header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestMemoryPersistence_SurvivesRestart(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage.Persistence.Dir = t.TempDir()
+
+	app, err := New(context.Background(), cfg)
+	require.NoError(t, err)
+	app.Start(context.Background())
+	srv := httptest.NewServer(app.Handler())
+	resp := postJSON(t, srv.URL+"/v1/users", map[string]any{
+		"email":    "ada@example.com",
+		"username": "ada",
+		"password": "correct horse battery",
+		"dob":      "1990-12-10T00:00:00Z",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	srv.Close()
+	require.NoError(t, app.Stop(context.Background()))
+
+	restarted := startApp(t, cfg)
+	profile := getAs(t, restarted.URL+"/v1/users/"+created.ID, "ada@example.com", "correct horse battery")
+	assert.Equal(t, http.StatusOK, profile.StatusCode)
+}
+
+// documentedRoute maps the unversioned routes kept from before /v1, which
+// the OpenAPI document does not list, to the v1 operations they serve.
+func documentedRoute(route string) string {
+	method, path, _ := strings.Cut(route, " ")
+	if strings.HasPrefix(path, "/users") || strings.HasPrefix(path, "/calendar/") {
+		return method + " /v1" + path
+	}
+	return route
+}
+
+func TestOpenAPI_DocumentsEveryRoute(t *testing.T) {
+	app, err := New(context.Background(), testConfig())
+	require.NoError(t, err)
+	t.Cleanup(func() { app.Stop(context.Background()) })
+
+	var routes []string
+	err = chi.Walk(app.Handler().(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+		routes = append(routes, documentedRoute(method+" "+route))
+		return nil
+	})
+	require.NoError(t, err)
+	slices.Sort(routes)
+
+	validator, err := api.NewOpenAPIValidator()
+	require.NoError(t, err)
+	assert.Equal(t, slices.Compact(routes), validator.Operations())
+}
+
+func TestAPIVersion_Deprecation(t *testing.T) {
+	cfg := testConfig()
+	cfg.API.V1Deprecation = config.Timestamp{Time: time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC)}
+	cfg.API.V1Sunset = config.Timestamp{Time: time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC)}
+	srv := startApp(t, cfg)
+
+	resp, err := http.Get(srv.URL + "/v1/users/4762e4fb-b6bd-487d-834d-7a8c20c78be9")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Equal(t, "@1782777600", resp.Header.Get("Deprecation"))
+	assert.Equal(t, "Fri, 01 Jan 2027 00:00:00 GMT", resp.Header.Get("Sunset"))
+
+	health, err := http.Get(srv.URL + "/healthz")
+	require.NoError(t, err)
+	health.Body.Close()
+	assert.Empty(t, health.Header.Get("Deprecation"), "only versioned routes are deprecated")
+}
+
+func TestAPIVersion_Legacy(t *testing.T) {
+	cfg := testConfig()
+	cfg.API.LegacyDeprecation = config.Timestamp{Time: time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)}
+	cfg.API.LegacySunset = config.Timestamp{Time: time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)}
+	srv := startApp(t, cfg)
+
+	resp := postJSON(t, srv.URL+"/users", map[string]any{
+		"email":    "legacy@example.com",
+		"username": "legacy",
+		"password": "correct horse battery",
+		"dob":      "1990-12-10T00:00:00Z",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+
+	for _, path := range []string{"/users/" + created.ID, "/v1/users/" + created.ID} {
+		profile := getAs(t, srv.URL+path, "legacy@example.com", "correct horse battery")
+		assert.Equal(t, http.StatusOK, profile.StatusCode, path)
+
+		if strings.HasPrefix(path, "/v1/") {
+			assert.Empty(t, profile.Header.Get("Deprecation"), path)
+			continue
+		}
+		assert.Equal(t, "@1792368000", profile.Header.Get("Deprecation"), path)
+		assert.Equal(t, "Mon, 19 Apr 2027 00:00:00 GMT", profile.Header.Get("Sunset"), path)
+	}
+	assert.Equal(t, "@1792368000", resp.Header.Get("Deprecation"))
+}
+
+func TestIdempotentSignup(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage = config.StorageConfig{
+		Backend: config.StorageSQLite,
+		DSN:     filepath.Join(t.TempDir(), "weekbyweek.db"),
+	}
+	srv := startApp(t, cfg)
+
+	signup := func(body string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/users", strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "2f1c9a4e-signup")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		data, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp, string(data)
+	}
+	body := `{"email":"ada@example.com","username":"ada","password":"correct horse battery","dob":"1990-12-10T00:00:00Z"}`
+
+	first, created := signup(body)
+	require.Equal(t, http.StatusCreated, first.StatusCode)
+
+	retry, replayed := signup(body)
+	assert.Equal(t, http.StatusCreated, retry.StatusCode, "not a conflict with the user the first attempt created")
+	assert.Equal(t, "true", retry.Header.Get("Idempotent-Replayed"))
+	assert.Equal(t, created, replayed)
+
+	changed, _ := signup(strings.Replace(body, "ada@", "grace@", 1))
+	assert.Equal(t, http.StatusUnprocessableEntity, changed.StatusCode)
+}
+
+func TestProfilePreconditions(t *testing.T) {
+	srv := startApp(t, testConfig())
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	resp := postJSON(t, srv.URL+"/v1/users", map[string]string{
+		"email":    "ada@example.com",
+		"username": "ada",
+		"password": "correct horse battery",
+		"dob":      "1990-12-10T00:00:00Z",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	profileURL := srv.URL + "/v1/users/" + created.ID
+
+	do := func(method, body string, header ...string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, profileURL, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("ada@example.com", "correct horse battery")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	read := do(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, read.StatusCode)
+	etag := read.Header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	assert.Equal(t, http.StatusNotModified, do(http.MethodGet, "", "If-None-Match", etag).StatusCode)
+
+	first := do(http.MethodPatch, `{"timeZone":"Europe/Berlin"}`, "If-Match", etag)
+	require.Equal(t, http.StatusOK, first.StatusCode)
+	assert.NotEqual(t, etag, first.Header.Get("ETag"))
+
+	second := do(http.MethodPatch, `{"timeZone":"Asia/Tokyo"}`, "If-Match", etag)
+	assert.Equal(t, http.StatusPreconditionFailed, second.StatusCode, "the second writer read the profile before the first wrote it")
+
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "", "If-None-Match", etag).StatusCode)
+}
+
+func TestNew_InvalidStorage(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage = config.StorageConfig{
+		Backend: config.StorageSQLite,
+		DSN:     filepath.Join(t.TempDir(), "missing", "weekbyweek.db"),
+	}
+
+	_, err := New(context.Background(), cfg)
+	assert.ErrorContains(t, err, "opening database")
+}
+
+func TestRun(t *testing.T) {
+	cfg := testConfig()
+	cfg.Server.Addr = "127.0.0.1:0"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- Run(ctx, cfg) }()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after cancellation")
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage = config.StorageConfig{
+		Backend: config.StorageSQLite,
+		DSN:     filepath.Join(t.TempDir(), "weekbyweek.db"),
+	}
+	app, err := New(context.Background(), cfg)
+	require.NoError(t, err)
+	srv := httptest.NewServer(app.Handler())
+	defer srv.Close()
+
+	ready := func() (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Get(srv.URL + "/readyz")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		var report struct {
+			Checks map[string]any `json:"checks"`
+		}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		return resp.StatusCode, report.Checks
+	}
+
+	status, _ := ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status, "ready before the workers started")
+
+	app.Start(context.Background())
+	defer app.Stop(context.Background())
+
+	status, checks := ready()
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, checks, "storage")
+	assert.Contains(t, checks, "mailer")
+	assert.Contains(t, checks, "job_queue")
+
+	app.SetShuttingDown()
+	status, checks = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.Contains(t, checks, "shutdown")
+
+	live, err := http.Get(srv.URL + "/healthz")
+	require.NoError(t, err)
+	live.Body.Close()
+	assert.Equal(t, http.StatusOK, live.StatusCode)
+}
+
+func TestPasswordLockout(t *testing.T) {
+	cfg := testConfig()
+	cfg.RateLimit.Lockout = ratelimit.LockoutPolicy{Threshold: 2, Base: time.Hour, Max: time.Hour}
+	srv := startApp(t, cfg)
+
+	resp := postJSON(t, srv.URL+"/v1/users", map[string]any{
+		"email":    "ada@example.com",
+		"username": "ada",
+		"password": "correct horse battery",
+		"dob":      "1990-12-10T00:00:00Z",
+	})
+	require.Equal(t, http.StatusCreated, resp.StatusCode)
+	var created struct {
+		ID string `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	profileURL := srv.URL + "/v1/users/" + created.ID
+
+	for range 2 {
+		assert.Equal(t, http.StatusUnauthorized, getAs(t, profileURL, "ada@example.com", "tr0ub4dor&3").StatusCode)
+	}
+	assert.Equal(t, http.StatusTooManyRequests, getAs(t, profileURL, "ada@example.com", "correct horse battery").StatusCode,
+		"the right password does not help while the account is locked")
+}
+
+func TestRateLimit(t *testing.T) {
+	cfg := testConfig()
+	cfg.Storage = config.StorageConfig{
+		Backend: config.StorageSQLite,
+		DSN:     filepath.Join(t.TempDir(), "weekbyweek.db"),
+	}
+	cfg.RateLimit.SignupPerIP = ratelimit.Limit{Requests: 2, Per: time.Hour}
+	srv := startApp(t, cfg)
+
+	for i, want := range []int{http.StatusCreated, http.StatusCreated, http.StatusTooManyRequests} {
+		resp := postJSON(t, srv.URL+"/v1/users", map[string]any{
+			"email":    fmt.Sprintf("user%d@example.com", i),
+			"username": fmt.Sprintf("user%d", i),
+			"password": "correct horse battery",
+			"dob":      "1990-12-10T00:00:00Z",
+		})
+		assert.Equal(t, want, resp.StatusCode)
+	}
+}
@@ -0,0 +1,349 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/idempotency"
+	"github.com/mgwinsor/weekbyweek/internal/primary/server"
+	"github.com/mgwinsor/weekbyweek/internal/primary/worker"
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/cache"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/mgwinsor/weekbyweek/internal/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+const (
+	StorageMemory = "memory"
+	StorageSQLite = "sqlite"
+	StorageBolt   = "bolt"
+
+	HasherBcrypt = "bcrypt"
+
+	MailerSMTP = "smtp"
+	MailerLog  = "log"
+
+	LogFormatJSON = "json"
+	LogFormatText = "text"
+
+	RateLimitStoreMemory  = "memory"
+	RateLimitStoreStorage = "storage"
+
+	IdempotencyStoreMemory  = "memory"
+	IdempotencyStoreStorage = "storage"
+)
+
+type Config struct {
+	Log     LogConfig
+	Server  server.Config
+	Storage StorageConfig
+	Cache   CacheConfig
+	Hasher  HasherConfig
+	Mailer  MailerConfig
+	Digest  DigestConfig
+	Worker  worker.Config
+	Jobs    JobsConfig
+	Metrics MetricsConfig
+	Tracing TracingConfig
+	Health  HealthConfig
+	OpenAPI OpenAPIConfig
+	API     APIConfig
+
+	RateLimit   RateLimitConfig
+	Idempotency IdempotencyConfig
+
+	// PrintConfig asks for the resolved configuration to be printed
+	// instead of starting the server. It can only be set by flag.
+	PrintConfig bool
+}
+
+type LogConfig struct {
+	Level  slog.Level
+	Format string
+}
+
+// StorageConfig selects where data is kept. The bolt backend, whose DSN is
+// the path of its file, persists everything; the sqlite backend persists
+// all but milestones, which stay in memory. Memory repositories are lost
+// on restart unless Persistence.Dir is set.
+type StorageConfig struct {
+	Backend     string
+	DSN         string
+	Persistence memory.PersistenceConfig
+}
+
+// CacheConfig puts a cache of users, looked up by ID, in front of the
+// storage backend. Instances do not share it, so a user changed through
+// one may be served stale by another for up to Users.TTL.
+type CacheConfig struct {
+	Enabled bool
+	Users   cache.Config
+}
+
+type HasherConfig struct {
+	Algorithm  string
+	BcryptCost int
+}
+
+type MailerConfig struct {
+	Backend      string
+	From         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+type DigestConfig struct {
+	Interval time.Duration
+}
+
+type JobsConfig struct {
+	MaxAttempts int
+}
+
+type TracingConfig struct {
+	Exporter     string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	SampleRatio  float64
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration
+}
+
+// RateLimitConfig sets per-route request limits and the password lockout.
+// Store "storage" shares state through the storage backend, so that limits
+// hold across instances; "memory" keeps it per process.
+type RateLimitConfig struct {
+	Enabled        bool
+	Store          string
+	SignupPerIP    ratelimit.Limit
+	SignupPerEmail ratelimit.Limit
+	ProfilePerIP   ratelimit.Limit
+	CalendarPerIP  ratelimit.Limit
+	Lockout        ratelimit.LockoutPolicy
+}
+
+// IdempotencyConfig controls replay of requests sent with an
+// Idempotency-Key. Store "storage" shares keys through the storage backend,
+// so that a retry reaching another instance is still recognised.
+type IdempotencyConfig struct {
+	Enabled bool
+	Store   string
+	Policy  idempotency.Policy
+}
+
+// OpenAPIConfig controls the API description. Enabled serves it at
+// /openapi.json with a viewer at /docs; ValidateRequests rejects requests
+// that do not match it before they reach a handler.
+type OpenAPIConfig struct {
+	Enabled          bool
+	ValidateRequests bool
+}
+
+// APIConfig schedules the retirement of API versions. A zero Deprecation
+// or Sunset is not announced. Legacy is the unversioned API from before
+// /v1, which answers as v1 does.
+type APIConfig struct {
+	LegacyDeprecation Timestamp
+	LegacySunset      Timestamp
+	V1Deprecation     Timestamp
+	V1Sunset          Timestamp
+}
+
+type MetricsConfig struct {
+	Enabled bool
+	Path    string
+}
+
+func Default() Config {
+	return Config{
+		Log: LogConfig{
+			Level:  slog.LevelInfo,
+			Format: LogFormatJSON,
+		},
+		Server: server.DefaultConfig,
+		Storage: StorageConfig{
+			Backend:     StorageMemory,
+			Persistence: memory.DefaultPersistenceConfig,
+		},
+		Cache: CacheConfig{
+			Enabled: true,
+			Users:   cache.DefaultConfig,
+		},
+		Hasher: HasherConfig{
+			Algorithm:  HasherBcrypt,
+			BcryptCost: bcrypt.DefaultCost,
+		},
+		Mailer: MailerConfig{
+			Backend:  MailerSMTP,
+			From:     "weekbyweek@localhost",
+			SMTPAddr: "localhost:1025",
+		},
+		Digest: DigestConfig{
+			Interval: 5 * time.Minute,
+		},
+		Worker: worker.DefaultConfig,
+		Jobs: JobsConfig{
+			MaxAttempts: 5,
+		},
+		Metrics: MetricsConfig{
+			Enabled: true,
+			Path:    "/metrics",
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+		},
+		Idempotency: IdempotencyConfig{
+			Enabled: true,
+			Store:   IdempotencyStoreStorage,
+			Policy: idempotency.Policy{
+				TTL:   24 * time.Hour,
+				Wait:  5 * time.Second,
+				Lease: time.Minute,
+			},
+		},
+		OpenAPI: OpenAPIConfig{
+			Enabled:          true,
+			ValidateRequests: true,
+		},
+		API: APIConfig{
+			LegacyDeprecation: Timestamp{time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)},
+			LegacySunset:      Timestamp{time.Date(2027, time.April, 19, 0, 0, 0, 0, time.UTC)},
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:        true,
+			Store:          RateLimitStoreStorage,
+			SignupPerIP:    ratelimit.Limit{Requests: 20, Per: time.Hour},
+			SignupPerEmail: ratelimit.Limit{Requests: 5, Per: time.Hour},
+			ProfilePerIP:   ratelimit.Limit{Requests: 120, Per: time.Minute},
+			CalendarPerIP:  ratelimit.Limit{Requests: 60, Per: time.Minute},
+			Lockout: ratelimit.LockoutPolicy{
+				Threshold: 5,
+				Base:      time.Minute,
+				Max:       time.Hour,
+			},
+		},
+		Tracing: TracingConfig{
+			Exporter:     tracing.ExporterNone,
+			OTLPEndpoint: "localhost:4318",
+			OTLPInsecure: true,
+			SampleRatio:  1,
+		},
+	}
+}
+
+// Validate reports every invalid setting at once, naming each by the key
+// used in files and flags.
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+		}
+	}
+
+	check(c.Log.Format == LogFormatJSON || c.Log.Format == LogFormatText,
+		"log.format", "must be %q or %q, got %q", LogFormatJSON, LogFormatText, c.Log.Format)
+
+	check(c.Server.Addr != "", "server.addr", "must not be empty")
+	check(c.Server.ReadTimeout > 0, "server.read-timeout", "must be positive")
+	check(c.Server.ReadHeaderTimeout > 0, "server.read-header-timeout", "must be positive")
+	check(c.Server.WriteTimeout > 0, "server.write-timeout", "must be positive")
+	check(c.Server.IdleTimeout > 0, "server.idle-timeout", "must be positive")
+	check(c.Server.MaxHeaderBytes >= 1<<10, "server.max-header-bytes", "must be at least 1024")
+	check(c.Server.ShutdownTimeout > 0, "server.shutdown-timeout", "must be positive")
+	check(c.Server.DrainDelay >= 0, "server.drain-delay", "must not be negative")
+
+	switch c.Storage.Backend {
+	case StorageMemory:
+	case StorageSQLite, StorageBolt:
+		check(c.Storage.DSN != "", "storage.dsn", "is required for the %s backend", c.Storage.Backend)
+	default:
+		check(false, "storage.backend", "must be %q, %q or %q, got %q", StorageMemory, StorageSQLite, StorageBolt, c.Storage.Backend)
+	}
+	if p := c.Storage.Persistence; p.Dir != "" {
+		check(p.SnapshotInterval > 0, "storage.snapshot-interval", "must be positive")
+		switch p.Fsync {
+		case memory.FsyncAlways, memory.FsyncNever:
+		case memory.FsyncInterval:
+			check(p.FsyncInterval > 0, "storage.fsync-interval", "must be positive")
+		default:
+			check(false, "storage.fsync", "must be %q, %q or %q, got %q",
+				memory.FsyncAlways, memory.FsyncInterval, memory.FsyncNever, p.Fsync)
+		}
+	}
+
+	if c.Cache.Enabled {
+		check(c.Cache.Users.Size >= 1, "cache.size", "must be at least 1")
+		check(c.Cache.Users.TTL > 0, "cache.ttl", "must be positive")
+		check(c.Cache.Users.NegativeTTL >= 0, "cache.negative-ttl", "must not be negative")
+	}
+
+	check(c.Hasher.Algorithm == HasherBcrypt, "hasher.algorithm", "must be %q, got %q", HasherBcrypt, c.Hasher.Algorithm)
+	check(c.Hasher.BcryptCost >= bcrypt.MinCost && c.Hasher.BcryptCost <= bcrypt.MaxCost,
+		"hasher.bcrypt-cost", "must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+
+	switch c.Mailer.Backend {
+	case MailerLog:
+	case MailerSMTP:
+		check(c.Mailer.From != "", "mailer.from", "is required for the %s backend", c.Mailer.Backend)
+		check(c.Mailer.SMTPAddr != "", "mailer.smtp-addr", "is required for the %s backend", c.Mailer.Backend)
+		check(c.Mailer.SMTPUsername != "" || c.Mailer.SMTPPassword == "",
+			"mailer.smtp-username", "is required when a password is set")
+	default:
+		check(false, "mailer.backend", "must be %q or %q, got %q", MailerSMTP, MailerLog, c.Mailer.Backend)
+	}
+
+	check(c.Digest.Interval >= time.Second, "digest.interval", "must be at least 1s")
+
+	check(c.Worker.Concurrency >= 1, "worker.concurrency", "must be at least 1")
+	check(c.Worker.PollInterval > 0, "worker.poll-interval", "must be positive")
+	check(c.Worker.Lease > 0, "worker.lease", "must be positive")
+	check(c.Worker.BaseBackoff > 0, "worker.base-backoff", "must be positive")
+	check(c.Worker.MaxBackoff >= c.Worker.BaseBackoff, "worker.max-backoff", "must not be less than worker.base-backoff")
+	check(c.Jobs.MaxAttempts >= 1, "jobs.max-attempts", "must be at least 1")
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	case tracing.ExporterOTLP:
+		check(c.Tracing.OTLPEndpoint != "", "tracing.otlp-endpoint", "is required for the %s exporter", c.Tracing.Exporter)
+	default:
+		check(false, "tracing.exporter", "must be %q, %q or %q, got %q",
+			tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP, c.Tracing.Exporter)
+	}
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample-ratio", "must be between 0 and 1")
+
+	check(c.RateLimit.Store == RateLimitStoreMemory || c.RateLimit.Store == RateLimitStoreStorage,
+		"ratelimit.store", "must be %q or %q, got %q", RateLimitStoreMemory, RateLimitStoreStorage, c.RateLimit.Store)
+	check(c.RateLimit.Lockout.Threshold >= 0, "ratelimit.lockout-threshold", "must not be negative")
+	if c.RateLimit.Lockout.Threshold > 0 {
+		check(c.RateLimit.Lockout.Base > 0, "ratelimit.lockout-base", "must be positive")
+		check(c.RateLimit.Lockout.Max >= c.RateLimit.Lockout.Base, "ratelimit.lockout-max", "must not be less than ratelimit.lockout-base")
+	}
+
+	check(c.Idempotency.Store == IdempotencyStoreMemory || c.Idempotency.Store == IdempotencyStoreStorage,
+		"idempotency.store", "must be %q or %q, got %q", IdempotencyStoreMemory, IdempotencyStoreStorage, c.Idempotency.Store)
+	check(c.Idempotency.Policy.TTL > 0, "idempotency.ttl", "must be positive")
+	check(c.Idempotency.Policy.Wait >= 0, "idempotency.wait", "must not be negative")
+	check(c.Idempotency.Policy.Lease > 0, "idempotency.lease", "must be positive")
+
+	check(c.API.LegacySunset.IsZero() || !c.API.LegacySunset.Before(c.API.LegacyDeprecation.Time),
+		"api.legacy-sunset", "must not be before api.legacy-deprecation")
+	check(c.API.V1Sunset.IsZero() || !c.API.V1Sunset.Before(c.API.V1Deprecation.Time),
+		"api.v1-sunset", "must not be before api.v1-deprecation")
+
+	check(c.Health.CheckTimeout > 0, "health.check-timeout", "must be positive")
+	check(!c.Metrics.Enabled || strings.HasPrefix(c.Metrics.Path, "/"), "metrics.path", "must start with /")
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w:\n%w", ErrInvalidConfig, errors.Join(errs...))
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func env(vars map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := vars[key]
+		return value, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	c, err := Load(nil, env(nil))
+	require.NoError(t, err)
+
+	assert.Equal(t, Default(), c)
+	assert.Equal(t, ":8080", c.Server.Addr)
+	assert.Equal(t, StorageMemory, c.Storage.Backend)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	yamlFile := writeFile(t, "config.yaml", `
+server:
+  addr: ":7000"
+  write-timeout: 45s
+hasher:
+  bcrypt-cost: 12
+mailer:
+  backend: log
+digest:
+  interval: 1h
+ratelimit:
+  signup-per-email: 3/1h
+api:
+  v1-deprecation: 2026-06-30
+  v1-sunset: 2027-01-01T00:00:00Z
+`)
+	tomlFile := writeFile(t, "config.toml", `
+[server]
+addr = ":7000"
+write-timeout = "45s"
+
+[hasher]
+bcrypt-cost = 12
+
+[mailer]
+backend = "log"
+
+[digest]
+interval = "1h"
+
+[ratelimit]
+signup-per-email = "3/1h"
+
+[api]
+v1-deprecation = 2026-06-30
+v1-sunset = 2027-01-01T00:00:00Z
+`)
+
+	for _, path := range []string{yamlFile, tomlFile} {
+		t.Run(filepath.Ext(path), func(t *testing.T) {
+			c, err := Load(
+				[]string{"--config", path, "--server.addr", ":9000"},
+				env(map[string]string{
+					"WEEKBYWEEK_SERVER_ADDR":               ":8000",
+					"WEEKBYWEEK_HASHER_BCRYPT_COST":        "11",
+					"WEEKBYWEEK_OPENAPI_VALIDATE_REQUESTS": "false",
+					"WEEKBYWEEK_STORAGE_DIR":               "/var/lib/weekbyweek",
+					"WEEKBYWEEK_STORAGE_FSYNC":             "always",
+				}),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, ":9000", c.Server.Addr, "flag beats env and file")
+			assert.Equal(t, "/var/lib/weekbyweek", c.Storage.Persistence.Dir)
+			assert.Equal(t, memory.FsyncAlways, c.Storage.Persistence.Fsync)
+			assert.Equal(t, 11, c.Hasher.BcryptCost, "env beats file")
+			assert.Equal(t, 45*time.Second, c.Server.WriteTimeout, "file beats default")
+			assert.Equal(t, MailerLog, c.Mailer.Backend)
+			assert.Equal(t, time.Hour, c.Digest.Interval)
+			assert.Equal(t, ratelimit.Limit{Requests: 3, Per: time.Hour}, c.RateLimit.SignupPerEmail)
+			assert.False(t, c.OpenAPI.ValidateRequests)
+			assert.Equal(t, time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC), c.API.V1Deprecation.UTC())
+			assert.Equal(t, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC), c.API.V1Sunset.UTC())
+			assert.Equal(t, Default().Server.IdleTimeout, c.Server.IdleTimeout, "default kept")
+		})
+	}
+
+	t.Run("config file from environment", func(t *testing.T) {
+		c, err := Load(nil, env(map[string]string{"WEEKBYWEEK_CONFIG": yamlFile}))
+		require.NoError(t, err)
+		assert.Equal(t, ":7000", c.Server.Addr)
+	})
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		env         map[string]string
+		file        string
+		fileName    string
+		errContains []string
+	}{
+		{
+			name:        "unknown flag",
+			args:        []string{"--nope"},
+			errContains: []string{"flag provided but not defined: -nope"},
+		},
+		{
+			name:        "malformed environment value",
+			env:         map[string]string{"WEEKBYWEEK_DIGEST_INTERVAL": "weekly"},
+			errContains: []string{"WEEKBYWEEK_DIGEST_INTERVAL"},
+		},
+		{
+			name:        "malformed limit",
+			args:        []string{"--ratelimit.signup-per-ip", "20"},
+			errContains: []string{"limit must look like 20/1m"},
+		},
+		{
+			name:        "malformed timestamp",
+			env:         map[string]string{"WEEKBYWEEK_API_V1_SUNSET": "next year"},
+			errContains: []string{"WEEKBYWEEK_API_V1_SUNSET", "must be an RFC 3339 time or a date"},
+		},
+		{
+			name:        "unknown file setting",
+			fileName:    "config.yaml",
+			file:        "server:\n  port: 80\n",
+			errContains: []string{`unknown setting "server.port"`},
+		},
+		{
+			name:        "unsupported file format",
+			fileName:    "config.json",
+			file:        "{}",
+			errContains: []string{"unsupported format"},
+		},
+		{
+			name: "invalid persistence",
+			args: []string{
+				"--storage.dir", "data",
+				"--storage.snapshot-interval", "0s",
+				"--storage.fsync", "sometimes",
+			},
+			errContains: []string{
+				"storage.snapshot-interval: must be positive",
+				`storage.fsync: must be "always", "interval" or "never", got "sometimes"`,
+			},
+		},
+		{
+			name: "invalid cache",
+			args: []string{
+				"--cache.size", "0",
+				"--cache.ttl", "0s",
+				"--cache.negative-ttl", "-1s",
+			},
+			errContains: []string{
+				"cache.size: must be at least 1",
+				"cache.ttl: must be positive",
+				"cache.negative-ttl: must not be negative",
+			},
+		},
+		{
+			name: "every invalid value is reported",
+			args: []string{
+				"--storage.backend", "sqlite",
+				"--hasher.bcrypt-cost", "2",
+				"--mailer.backend", "carrier-pigeon",
+				"--worker.concurrency", "0",
+				"--ratelimit.store", "redis",
+				"--idempotency.ttl", "0s",
+				"--api.v1-deprecation", "2026-06-30",
+				"--api.v1-sunset", "2026-01-01",
+				"--api.legacy-sunset", "2026-01-01",
+			},
+			errContains: []string{
+				"invalid configuration",
+				"storage.dsn: is required for the sqlite backend",
+				"hasher.bcrypt-cost: must be between 4 and 31",
+				`mailer.backend: must be "smtp" or "log", got "carrier-pigeon"`,
+				"worker.concurrency: must be at least 1",
+				`ratelimit.store: must be "memory" or "storage", got "redis"`,
+				"idempotency.ttl: must be positive",
+				"api.v1-sunset: must not be before api.v1-deprecation",
+				"api.legacy-sunset: must not be before api.legacy-deprecation",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := tt.args
+			if tt.file != "" {
+				args = append(args, "--config", writeFile(t, tt.fileName, tt.file))
+			}
+
+			_, err := Load(args, env(tt.env))
+
+			require.Error(t, err)
+			for _, msg := range tt.errContains {
+				assert.ErrorContains(t, err, msg)
+			}
+		})
+	}
+
+	t.Run("help", func(t *testing.T) {
+		_, err := Load([]string{"-h"}, env(nil))
+		assert.ErrorIs(t, err, flag.ErrHelp)
+	})
+}
+
+func TestPrint(t *testing.T) {
+	c, err := Load(
+		[]string{"--print-config", "--storage.backend", "sqlite"},
+		env(map[string]string{
+			"WEEKBYWEEK_STORAGE_DSN":          "file:/var/lib/weekbyweek.db",
+			"WEEKBYWEEK_MAILER_SMTP_USERNAME": "mailer",
+			"WEEKBYWEEK_MAILER_SMTP_PASSWORD": "hunter2",
+		}),
+	)
+	require.NoError(t, err)
+	assert.True(t, c.PrintConfig)
+
+	var buf bytes.Buffer
+	require.NoError(t, Print(&buf, c))
+
+	out := buf.String()
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "/var/lib/weekbyweek.db")
+	assert.NotContains(t, out, "smtp-password")
+	assert.NotContains(t, out, "dsn")
+	assert.Contains(t, out, "smtp-username: mailer")
+
+	path := writeFile(t, "printed.yaml", out)
+	reloaded, err := Load([]string{"--config", path}, env(map[string]string{
+		"WEEKBYWEEK_STORAGE_DSN":          "file:/var/lib/weekbyweek.db",
+		"WEEKBYWEEK_MAILER_SMTP_PASSWORD": "hunter2",
+	}))
+	require.NoError(t, err)
+	c.PrintConfig = false
+	assert.Equal(t, c, reloaded, "the printed configuration loads back with the secrets from the environment")
+}
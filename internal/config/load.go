@@ -0,0 +1,294 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvPrefix  = "WEEKBYWEEK_"
+	configFlag = "config"
+	printFlag  = "print-config"
+)
+
+// secrets are left out when the configuration is printed.
+var secrets = map[string]bool{
+	"storage.dsn":          true,
+	"mailer.smtp-password": true,
+}
+
+// settings registers every setting as a flag bound to the matching field of
+// c. The flag name doubles as the dotted key used in configuration files and,
+// upper-cased with dots and dashes turned into underscores, as the
+// environment variable name.
+func settings(c *Config) *flag.FlagSet {
+	fs := flag.NewFlagSet("weekbyweek", flag.ContinueOnError)
+
+	fs.TextVar(&c.Log.Level, "log.level", c.Log.Level, "minimum log level: debug, info, warn or error")
+	fs.StringVar(&c.Log.Format, "log.format", c.Log.Format, "log format: json or text")
+
+	fs.StringVar(&c.Server.Addr, "server.addr", c.Server.Addr, "address to listen on")
+	fs.DurationVar(&c.Server.ReadTimeout, "server.read-timeout", c.Server.ReadTimeout, "maximum time to read a request")
+	fs.DurationVar(&c.Server.ReadHeaderTimeout, "server.read-header-timeout", c.Server.ReadHeaderTimeout, "maximum time to read request headers")
+	fs.DurationVar(&c.Server.WriteTimeout, "server.write-timeout", c.Server.WriteTimeout, "maximum time to write a response")
+	fs.DurationVar(&c.Server.IdleTimeout, "server.idle-timeout", c.Server.IdleTimeout, "how long idle keep-alive connections are kept")
+	fs.IntVar(&c.Server.MaxHeaderBytes, "server.max-header-bytes", c.Server.MaxHeaderBytes, "maximum size of request headers")
+	fs.DurationVar(&c.Server.ShutdownTimeout, "server.shutdown-timeout", c.Server.ShutdownTimeout, "how long shutdown waits for requests and workers")
+	fs.DurationVar(&c.Server.DrainDelay, "server.drain-delay", c.Server.DrainDelay, "how long to keep serving with failing readiness before shutting down")
+
+	fs.StringVar(&c.Storage.Backend, "storage.backend", c.Storage.Backend, "storage backend: memory, sqlite or bolt")
+	fs.StringVar(&c.Storage.DSN, "storage.dsn", c.Storage.DSN, "data source name for the sqlite backend, or database file for bolt")
+	fs.StringVar(&c.Storage.Persistence.Dir, "storage.dir", c.Storage.Persistence.Dir, "directory to keep in-memory data in across restarts; empty keeps nothing")
+	fs.DurationVar(&c.Storage.Persistence.SnapshotInterval, "storage.snapshot-interval", c.Storage.Persistence.SnapshotInterval, "how often in-memory data is snapshotted, compacting its change log")
+	fs.StringVar(&c.Storage.Persistence.Fsync, "storage.fsync", c.Storage.Persistence.Fsync, "when the change log is synced to disk: always, interval or never")
+	fs.DurationVar(&c.Storage.Persistence.FsyncInterval, "storage.fsync-interval", c.Storage.Persistence.FsyncInterval, "how often the change log is synced under the interval policy")
+
+	fs.BoolVar(&c.Cache.Enabled, "cache.enabled", c.Cache.Enabled, "cache users looked up by ID")
+	fs.IntVar(&c.Cache.Users.Size, "cache.size", c.Cache.Users.Size, "most users kept in the cache")
+	fs.DurationVar(&c.Cache.Users.TTL, "cache.ttl", c.Cache.Users.TTL, "how long a cached user is served before being read again")
+	fs.DurationVar(&c.Cache.Users.NegativeTTL, "cache.negative-ttl", c.Cache.Users.NegativeTTL, "how long a user that was not found is remembered; 0 remembers nothing")
+
+	fs.StringVar(&c.Hasher.Algorithm, "hasher.algorithm", c.Hasher.Algorithm, "password hashing algorithm: bcrypt")
+	fs.IntVar(&c.Hasher.BcryptCost, "hasher.bcrypt-cost", c.Hasher.BcryptCost, "bcrypt cost factor")
+
+	fs.StringVar(&c.Mailer.Backend, "mailer.backend", c.Mailer.Backend, "mailer: smtp or log")
+	fs.StringVar(&c.Mailer.From, "mailer.from", c.Mailer.From, "sender address for outgoing mail")
+	fs.StringVar(&c.Mailer.SMTPAddr, "mailer.smtp-addr", c.Mailer.SMTPAddr, "SMTP server host:port")
+	fs.StringVar(&c.Mailer.SMTPUsername, "mailer.smtp-username", c.Mailer.SMTPUsername, "SMTP username")
+	fs.StringVar(&c.Mailer.SMTPPassword, "mailer.smtp-password", c.Mailer.SMTPPassword, "SMTP password")
+
+	fs.DurationVar(&c.Digest.Interval, "digest.interval", c.Digest.Interval, "how often due weekly digests are sent")
+
+	fs.IntVar(&c.Worker.Concurrency, "worker.concurrency", c.Worker.Concurrency, "number of background job workers")
+	fs.DurationVar(&c.Worker.PollInterval, "worker.poll-interval", c.Worker.PollInterval, "how often idle workers poll for jobs")
+	fs.DurationVar(&c.Worker.Lease, "worker.lease", c.Worker.Lease, "how long a claimed job is reserved for its worker")
+	fs.DurationVar(&c.Worker.BaseBackoff, "worker.base-backoff", c.Worker.BaseBackoff, "delay before the first retry of a failed job")
+	fs.DurationVar(&c.Worker.MaxBackoff, "worker.max-backoff", c.Worker.MaxBackoff, "maximum delay between retries")
+	fs.IntVar(&c.Jobs.MaxAttempts, "jobs.max-attempts", c.Jobs.MaxAttempts, "attempts before a job is dead-lettered")
+
+	fs.DurationVar(&c.Health.CheckTimeout, "health.check-timeout", c.Health.CheckTimeout, "timeout for each readiness check")
+
+	fs.BoolVar(&c.RateLimit.Enabled, "ratelimit.enabled", c.RateLimit.Enabled, "limit request rates and lock accounts after failed password checks")
+	fs.StringVar(&c.RateLimit.Store, "ratelimit.store", c.RateLimit.Store, "where limits are counted: memory, or storage to share them between instances")
+	fs.TextVar(&c.RateLimit.SignupPerIP, "ratelimit.signup-per-ip", c.RateLimit.SignupPerIP, "sign-ups per client IP, e.g. 20/1h; 0 for unlimited")
+	fs.TextVar(&c.RateLimit.SignupPerEmail, "ratelimit.signup-per-email", c.RateLimit.SignupPerEmail, "sign-up attempts per email address")
+	fs.TextVar(&c.RateLimit.ProfilePerIP, "ratelimit.profile-per-ip", c.RateLimit.ProfilePerIP, "profile reads and updates per client IP")
+	fs.TextVar(&c.RateLimit.CalendarPerIP, "ratelimit.calendar-per-ip", c.RateLimit.CalendarPerIP, "calendar feed requests per client IP")
+	fs.IntVar(&c.RateLimit.Lockout.Threshold, "ratelimit.lockout-threshold", c.RateLimit.Lockout.Threshold, "failed password checks before an account is locked; 0 disables lockout")
+	fs.DurationVar(&c.RateLimit.Lockout.Base, "ratelimit.lockout-base", c.RateLimit.Lockout.Base, "first lockout duration, doubled for each further failure")
+	fs.DurationVar(&c.RateLimit.Lockout.Max, "ratelimit.lockout-max", c.RateLimit.Lockout.Max, "longest lockout, and how long failures are remembered")
+
+	fs.BoolVar(&c.Idempotency.Enabled, "idempotency.enabled", c.Idempotency.Enabled, "replay responses to POST and PATCH requests retried with the same Idempotency-Key")
+	fs.StringVar(&c.Idempotency.Store, "idempotency.store", c.Idempotency.Store, "where keys are kept: memory, or storage to share them between instances")
+	fs.DurationVar(&c.Idempotency.Policy.TTL, "idempotency.ttl", c.Idempotency.Policy.TTL, "how long a response is replayed for its key")
+	fs.DurationVar(&c.Idempotency.Policy.Wait, "idempotency.wait", c.Idempotency.Policy.Wait, "how long a retry waits for the original request before getting 409")
+	fs.DurationVar(&c.Idempotency.Policy.Lease, "idempotency.lease", c.Idempotency.Policy.Lease, "how long an unfinished request holds its key")
+
+	fs.BoolVar(&c.OpenAPI.Enabled, "openapi.enabled", c.OpenAPI.Enabled, "serve the OpenAPI document at /openapi.json and a viewer at /docs")
+	fs.BoolVar(&c.OpenAPI.ValidateRequests, "openapi.validate-requests", c.OpenAPI.ValidateRequests, "reject requests that do not match the OpenAPI document")
+
+	fs.TextVar(&c.API.LegacyDeprecation, "api.legacy-deprecation", c.API.LegacyDeprecation, "when the unversioned API was deprecated; announced in a Deprecation header")
+	fs.TextVar(&c.API.LegacySunset, "api.legacy-sunset", c.API.LegacySunset, "when the unversioned API will be removed; announced in a Sunset header")
+	fs.TextVar(&c.API.V1Deprecation, "api.v1-deprecation", c.API.V1Deprecation, "when API v1 was deprecated, as an RFC 3339 time or date; announced in a Deprecation header")
+	fs.TextVar(&c.API.V1Sunset, "api.v1-sunset", c.API.V1Sunset, "when API v1 will be removed; announced in a Sunset header")
+
+	fs.BoolVar(&c.Metrics.Enabled, "metrics.enabled", c.Metrics.Enabled, "expose Prometheus metrics")
+	fs.StringVar(&c.Metrics.Path, "metrics.path", c.Metrics.Path, "path of the Prometheus metrics endpoint")
+
+	fs.StringVar(&c.Tracing.Exporter, "tracing.exporter", c.Tracing.Exporter, "trace exporter: none, stdout or otlp")
+	fs.StringVar(&c.Tracing.OTLPEndpoint, "tracing.otlp-endpoint", c.Tracing.OTLPEndpoint, "OTLP/HTTP collector host:port")
+	fs.BoolVar(&c.Tracing.OTLPInsecure, "tracing.otlp-insecure", c.Tracing.OTLPInsecure, "send traces to the collector without TLS")
+	fs.Float64Var(&c.Tracing.SampleRatio, "tracing.sample-ratio", c.Tracing.SampleRatio, "fraction of new traces to sample")
+
+	return fs
+}
+
+// Load resolves the configuration from, in increasing precedence, the
+// defaults, an optional YAML or TOML file, WEEKBYWEEK_* environment
+// variables and command-line flags, and validates the result. The file is
+// named by --config or WEEKBYWEEK_CONFIG.
+func Load(args []string, lookupEnv func(string) (string, bool)) (Config, error) {
+	// Flags are parsed first to find the config file, but applied last.
+	parsed := Default()
+	fs := settings(&parsed)
+	fs.SetOutput(io.Discard)
+	path := fs.String(configFlag, "", "path to a YAML or TOML configuration file")
+	fs.Bool(printFlag, false, "print the resolved configuration without secrets and exit")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	c := Default()
+	target := settings(&c)
+
+	if *path == "" {
+		*path, _ = lookupEnv(EnvPrefix + "CONFIG")
+	}
+	if *path != "" {
+		values, err := readFile(*path)
+		if err != nil {
+			return Config{}, err
+		}
+		for _, key := range sortedKeys(values) {
+			if err := set(target, key, values[key]); err != nil {
+				return Config{}, fmt.Errorf("%s: %w", *path, err)
+			}
+		}
+	}
+
+	var errs []error
+	target.VisitAll(func(f *flag.Flag) {
+		name := envName(f.Name)
+		if value, ok := lookupEnv(name); ok {
+			if err := target.Set(f.Name, value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	})
+	if err := errors.Join(errs...); err != nil {
+		return Config{}, err
+	}
+
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case configFlag:
+		case printFlag:
+			c.PrintConfig = true
+		default:
+			target.Set(f.Name, f.Value.String())
+		}
+	})
+
+	if err := c.Validate(); err != nil {
+		return Config{}, err
+	}
+	return c, nil
+}
+
+// Usage writes the flag documentation to w.
+func Usage(w io.Writer) {
+	c := Default()
+	fs := settings(&c)
+	fs.String(configFlag, "", "path to a YAML or TOML configuration file")
+	fs.Bool(printFlag, false, "print the resolved configuration without secrets and exit")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// Print writes c as YAML that Load accepts. Secrets are left out rather
+// than masked, so that the output can be loaded as it is, with the secrets
+// supplied through the environment or flags as before.
+func Print(w io.Writer, c Config) error {
+	sections := map[string]map[string]any{}
+	settings(&c).VisitAll(func(f *flag.Flag) {
+		if secrets[f.Name] {
+			return
+		}
+		section, key, _ := strings.Cut(f.Name, ".")
+		if sections[section] == nil {
+			sections[section] = map[string]any{}
+		}
+		var value any = f.Value.String()
+		switch v := f.Value.(flag.Getter).Get().(type) {
+		case int, bool, float64:
+			value = v
+		}
+		sections[section][key] = value
+	})
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(sections); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+func set(fs *flag.FlagSet, key string, value any) error {
+	if fs.Lookup(key) == nil {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	// TOML dates and times arrive decoded.
+	if t, ok := value.(time.Time); ok {
+		value = t.Format(time.RFC3339)
+	}
+	if err := fs.Set(key, fmt.Sprint(value)); err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	return nil
+}
+
+func readFile(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := map[string]any{}
+	if err := flatten("", doc, values); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return values, nil
+}
+
+// flatten turns nested sections into dotted keys, so that
+// {server: {addr: ":80"}} becomes {"server.addr": ":80"}.
+func flatten(prefix string, doc map[string]any, into map[string]any) error {
+	for key, value := range doc {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			if err := flatten(key, v, into); err != nil {
+				return err
+			}
+		case nil:
+			into[key] = ""
+		case []any:
+			return fmt.Errorf("%s: lists are not supported", key)
+		default:
+			into[key] = v
+		}
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidTimestamp = errors.New("must be an RFC 3339 time or a date such as 2026-06-30")
+
+// Timestamp is a point in time that may be left unset. It is written as an
+// RFC 3339 time or, meaning midnight UTC, a date.
+type Timestamp struct {
+	time.Time
+}
+
+func (t Timestamp) MarshalText() ([]byte, error) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	return []byte(t.Format(time.RFC3339)), nil
+}
+
+func (t *Timestamp) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		*t = Timestamp{}
+		return nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			*t = Timestamp{parsed}
+			return nil
+		}
+	}
+	return ErrInvalidTimestamp
+}
@@ -0,0 +1,36 @@
+package aggregate
+
+import "errors"
+
+// ErrConflict is returned by repositories that refuse to save an aggregate
+// because it changed since it was loaded.
+var ErrConflict = errors.New("modified concurrently")
+
+// Version counts how often an aggregate has been saved; one that never was
+// is at version 0. Repositories store an aggregate only if the version it
+// was loaded at is still the stored one, and then advance it, so that of
+// two concurrent edits the second fails with ErrConflict instead of
+// silently overwriting the first.
+type Version int64
+
+// Root is embedded in aggregate roots that can be changed after they are
+// stored, to version them.
+type Root struct {
+	version Version
+}
+
+func (r Root) Version() Version {
+	return r.version
+}
+
+// SetVersion is for repositories, which set the version an aggregate was
+// loaded at and advance it when they save it.
+func (r *Root) SetVersion(v Version) {
+	r.version = v
+}
+
+// Versioned is an aggregate root as repositories see it.
+type Versioned interface {
+	Version() Version
+	SetVersion(v Version)
+}
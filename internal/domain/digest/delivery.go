@@ -0,0 +1,26 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrAlreadyDelivered = errors.New("digest already delivered")
+
+// Delivery records that a user's digest for a given week of life has been
+// claimed for sending, so that it is never sent twice.
+type Delivery struct {
+	UserID uuid.UUID
+	Week   int
+	SentAt time.Time
+}
+
+type DeliveryRepository interface {
+	// Record stores d, failing with ErrAlreadyDelivered if a delivery for
+	// the same user and week already exists.
+	Record(ctx context.Context, d Delivery) error
+	Delete(ctx context.Context, userID uuid.UUID, week int) error
+}
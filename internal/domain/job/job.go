@@ -0,0 +1,95 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrNoJobAvailable = errors.New("no job available")
+	ErrJobNotFound    = errors.New("job not found")
+	ErrLeaseLost      = errors.New("job lease lost")
+)
+
+const DefaultMaxAttempts = 5
+
+type State string
+
+const (
+	StatePending State = "pending"
+	StateRunning State = "running"
+	StateDone    State = "done"
+	StateDead    State = "dead"
+)
+
+// Payload is the typed body of a job. JobType names the handler that
+// processes it and must be stable, since it is persisted with the job.
+type Payload interface {
+	JobType() string
+}
+
+type Job struct {
+	ID          uuid.UUID
+	Type        string
+	Payload     []byte
+	State       State
+	Attempts    int
+	MaxAttempts int
+	RunAt       time.Time
+	LockedUntil time.Time
+	LastError   string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func New(payload Payload, maxAttempts int, runAt time.Time) (*Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxAttempts < 1 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	now := time.Now().UTC()
+	if runAt.IsZero() {
+		runAt = now
+	}
+
+	return &Job{
+		ID:          uuid.New(),
+		Type:        payload.JobType(),
+		Payload:     data,
+		State:       StatePending,
+		MaxAttempts: maxAttempts,
+		RunAt:       runAt.UTC(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}, nil
+}
+
+// Store persists jobs. Claim hands out a pending job that is due, or a
+// running job whose lease has expired because its worker went away, and
+// increments its attempt count.
+//
+// Complete, Retry and Bury take the attempt the job was claimed with and
+// return ErrLeaseLost if it is no longer running under that attempt, so a
+// worker that outlived its lease cannot overwrite the outcome of the one
+// that claimed the job after it.
+type Store interface {
+	Enqueue(ctx context.Context, j *Job) error
+	Claim(ctx context.Context, now time.Time, lease time.Duration) (*Job, error)
+	Complete(ctx context.Context, id uuid.UUID, attempt int) error
+	Retry(ctx context.Context, id uuid.UUID, attempt int, runAt time.Time, lastErr string) error
+	Bury(ctx context.Context, id uuid.UUID, attempt int, lastErr string) error
+	DeadLetters(ctx context.Context) ([]*Job, error)
+}
+
+type Enqueuer interface {
+	Enqueue(ctx context.Context, payload Payload) error
+}
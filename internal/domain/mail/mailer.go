@@ -0,0 +1,14 @@
+package mail
+
+import "context"
+
+type Message struct {
+	To       string
+	Subject  string
+	TextBody string
+	HTMLBody string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
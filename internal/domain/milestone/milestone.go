@@ -0,0 +1,92 @@
+package milestone
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUserIDRequired = errors.New("user id cannot be empty")
+	ErrTitleRequired  = errors.New("title cannot be empty")
+	ErrDateRequired   = errors.New("date cannot be empty")
+)
+
+type NewMilestoneParams struct {
+	UserID      uuid.UUID
+	Title       string
+	Description string
+	Date        time.Time
+	ImportID    uuid.UUID
+}
+
+// Milestone is never changed once created, only deleted with its import,
+// so unlike a user it has no version to guard concurrent edits.
+type Milestone struct {
+	id          uuid.UUID
+	userID      uuid.UUID
+	title       string
+	description string
+	date        time.Time
+	importID    uuid.UUID
+	createdAt   time.Time
+}
+
+func NewMilestone(params NewMilestoneParams) (*Milestone, error) {
+	if params.UserID == uuid.Nil {
+		return nil, ErrUserIDRequired
+	}
+
+	title := strings.TrimSpace(params.Title)
+	if title == "" {
+		return nil, ErrTitleRequired
+	}
+
+	if params.Date.IsZero() {
+		return nil, ErrDateRequired
+	}
+
+	return &Milestone{
+		id:          uuid.New(),
+		userID:      params.UserID,
+		title:       title,
+		description: params.Description,
+		date:        params.Date,
+		importID:    params.ImportID,
+		createdAt:   time.Now().UTC(),
+	}, nil
+}
+
+// RestoreParams holds the fields of a stored milestone.
+type RestoreParams struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Title       string
+	Description string
+	Date        time.Time
+	ImportID    uuid.UUID
+	CreatedAt   time.Time
+}
+
+// Restore rebuilds a milestone that a repository stored.
+func Restore(params RestoreParams) *Milestone {
+	return &Milestone{
+		id:          params.ID,
+		userID:      params.UserID,
+		title:       params.Title,
+		description: params.Description,
+		date:        params.Date,
+		importID:    params.ImportID,
+		createdAt:   params.CreatedAt,
+	}
+}
+
+func (m *Milestone) ID() uuid.UUID        { return m.id }
+func (m *Milestone) UserID() uuid.UUID    { return m.userID }
+func (m *Milestone) Title() string        { return m.title }
+func (m *Milestone) Description() string  { return m.description }
+func (m *Milestone) Date() time.Time      { return m.date }
+func (m *Milestone) ImportID() uuid.UUID  { return m.importID }
+func (m *Milestone) CreatedAt() time.Time { return m.createdAt }
@@ -0,0 +1,80 @@
+package milestone
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var validNewMilestoneParams = NewMilestoneParams{
+	UserID:      uuid.New(),
+	Title:       "Graduated",
+	Description: "BSc Computer Science",
+	Date:        time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC),
+	ImportID:    uuid.New(),
+}
+
+func withParams(modifier func(p *NewMilestoneParams)) NewMilestoneParams {
+	params := validNewMilestoneParams
+	modifier(&params)
+	return params
+}
+
+func TestNewMilestone(t *testing.T) {
+	tests := []struct {
+		name        string
+		params      NewMilestoneParams
+		expectedErr error
+	}{
+		{
+			name:        "valid milestone",
+			params:      validNewMilestoneParams,
+			expectedErr: nil,
+		},
+		{
+			name:        "missing user id",
+			params:      withParams(func(p *NewMilestoneParams) { p.UserID = uuid.Nil }),
+			expectedErr: ErrUserIDRequired,
+		},
+		{
+			name:        "empty title",
+			params:      withParams(func(p *NewMilestoneParams) { p.Title = "" }),
+			expectedErr: ErrTitleRequired,
+		},
+		{
+			name:        "blank title",
+			params:      withParams(func(p *NewMilestoneParams) { p.Title = "   " }),
+			expectedErr: ErrTitleRequired,
+		},
+		{
+			name:        "missing date",
+			params:      withParams(func(p *NewMilestoneParams) { p.Date = time.Time{} }),
+			expectedErr: ErrDateRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewMilestone(tt.params)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				assert.Nil(t, m, "milestone should be nil when error is returned")
+			} else {
+				require.NoError(t, err)
+				require.NotNil(t, m)
+
+				assert.NotEqual(t, uuid.Nil, m.ID())
+				assert.Equal(t, tt.params.UserID, m.UserID())
+				assert.Equal(t, tt.params.Title, m.Title())
+				assert.Equal(t, tt.params.Description, m.Description())
+				assert.Equal(t, tt.params.Date, m.Date())
+				assert.Equal(t, tt.params.ImportID, m.ImportID())
+				assert.NotEmpty(t, m.CreatedAt())
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package milestone
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+var ErrImportNotFound = errors.New("import not found")
+
+type MilestoneRepository interface {
+	Save(ctx context.Context, milestone *Milestone) error
+	SaveAll(ctx context.Context, milestones []*Milestone) error
+	FindByUserID(ctx context.Context, userID uuid.UUID) ([]*Milestone, error)
+	DeleteByImportID(ctx context.Context, userID, importID uuid.UUID) error
+}
@@ -0,0 +1,17 @@
+package transaction
+
+import "context"
+
+// Manager lets a use case write several aggregates all or not at all.
+// Only repositories on the manager's own store take part: with a database,
+// milestones stay in memory and are written straight away, so a use case
+// that writes them must not rely on a rollback undoing them. If the
+// transaction is rolled back, repositories put back the versions of the
+// aggregates they wrote in it.
+type Manager interface {
+	// Run calls fn with a context that carries a transaction. Repositories
+	// given that context take part in it: what they write is committed
+	// if fn returns nil and discarded otherwise. Run called within a
+	// transaction joins it, so that use cases can be composed.
+	Run(ctx context.Context, fn func(ctx context.Context) error) error
+}
@@ -1,6 +1,20 @@
 package user
 
+import (
+	"context"
+	"errors"
+)
+
+var (
+	// ErrPasswordMismatch is returned by Compare when the password is not
+	// the one hashed.
+	ErrPasswordMismatch = errors.New("password does not match")
+	// ErrAccountLocked is returned by Compare when too many recent
+	// attempts for the same account have failed.
+	ErrAccountLocked = errors.New("account temporarily locked")
+)
+
 type PasswordHasher interface {
-	Hash(password string) (string, error)
-	Compare(hashedPassword, password string) error
+	Hash(ctx context.Context, password string) (string, error)
+	Compare(ctx context.Context, hashedPassword, password string) error
 }
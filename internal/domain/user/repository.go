@@ -3,14 +3,42 @@ package user
 import (
 	"context"
 	"errors"
+	"strings"
 
 	"github.com/google/uuid"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email already registered")
+)
 
 type UserRepository interface {
+	// Create stores a new user and advances its version. It returns
+	// ErrEmailTaken if another user has the same email, ignoring case; the
+	// check and the write are atomic.
+	Create(ctx context.Context, user *User) error
+	// Save stores changes to a created user if the stored user is still at
+	// its version, and advances its version. Otherwise it returns an error
+	// matching aggregate.ErrConflict, or ErrUserNotFound if it was never
+	// created.
 	Save(ctx context.Context, user *User) error
 	FindByID(ctx context.Context, id uuid.UUID) (*User, error)
+	// FindByEmail and FindByUsername compare normalised forms, see
+	// NormaliseEmail and NormaliseUsername. Usernames are not unique, so
+	// FindByUsername returns every match, oldest first.
 	FindByEmail(ctx context.Context, email string) (*User, error)
+	FindByUsername(ctx context.Context, username string) ([]*User, error)
+	FindAll(ctx context.Context) ([]*User, error)
+}
+
+// NormaliseEmail returns the form in which emails are compared, so that
+// addresses differing only in case belong to the same user.
+func NormaliseEmail(email string) string {
+	return strings.ToLower(email)
+}
+
+// NormaliseUsername returns the form in which usernames are compared.
+func NormaliseUsername(username string) string {
+	return strings.ToLower(username)
 }
@@ -1,11 +1,15 @@
 package user
 
 import (
+	"context"
+	"crypto/rand"
 	"errors"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 )
 
 var (
@@ -13,26 +17,36 @@ var (
 	ErrInvalidEmailFormat = errors.New("incorrect email format")
 	ErrUsernameRequired   = errors.New("username cannot be empty")
 	ErrPasswordTooShort   = errors.New("password must be at least 8 characters long")
+	ErrInvalidTimeZone    = errors.New("unknown time zone")
 )
 
+const defaultTimeZone = "UTC"
+
 type NewUserParams struct {
 	Email       string
 	Username    string
 	Password    string
 	DateOfBirth time.Time
+	WeekAnchor  week.Anchor
+	TimeZone    string
 }
 
 type User struct {
-	id           uuid.UUID
-	email        string
-	username     string
-	passwordHash string
-	dateOfBirth  time.Time
-	createdAt    time.Time
-	updatedAt    time.Time
+	aggregate.Root
+
+	id            uuid.UUID
+	email         string
+	username      string
+	passwordHash  string
+	dateOfBirth   time.Time
+	weekAnchor    week.Anchor
+	location      *time.Location
+	calendarToken string
+	createdAt     time.Time
+	updatedAt     time.Time
 }
 
-func NewUser(params NewUserParams, hasher PasswordHasher) (*User, error) {
+func NewUser(ctx context.Context, params NewUserParams, hasher PasswordHasher) (*User, error) {
 	if err := validateEmail(params.Email); err != nil {
 		return nil, err
 	}
@@ -45,29 +59,125 @@ func NewUser(params NewUserParams, hasher PasswordHasher) (*User, error) {
 		return nil, err
 	}
 
-	hashedPassword, err := hasher.Hash(params.Password)
+	weekAnchor := params.WeekAnchor
+	if weekAnchor.Mode == "" {
+		weekAnchor = week.DefaultAnchor
+	}
+	if err := weekAnchor.Validate(); err != nil {
+		return nil, err
+	}
+
+	timeZone := params.TimeZone
+	if timeZone == "" {
+		timeZone = defaultTimeZone
+	}
+	location, err := loadLocation(timeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := hasher.Hash(ctx, params.Password)
 	if err != nil {
 		return nil, err
 	}
 
 	return &User{
-		id:           uuid.New(),
-		email:        params.Email,
-		username:     params.Username,
-		passwordHash: hashedPassword,
-		dateOfBirth:  params.DateOfBirth,
-		createdAt:    time.Now().UTC(),
-		updatedAt:    time.Now().UTC(),
+		id:            uuid.New(),
+		email:         params.Email,
+		username:      params.Username,
+		passwordHash:  hashedPassword,
+		dateOfBirth:   params.DateOfBirth,
+		weekAnchor:    weekAnchor,
+		location:      location,
+		calendarToken: rand.Text(),
+		createdAt:     time.Now().UTC(),
+		updatedAt:     time.Now().UTC(),
 	}, nil
 }
 
-func (u *User) ID() uuid.UUID          { return u.id }
-func (u *User) Email() string          { return u.email }
-func (u *User) Username() string       { return u.username }
-func (u *User) PasswordHash() string   { return u.passwordHash }
-func (u *User) DateOfBirth() time.Time { return u.dateOfBirth }
-func (u *User) CreatedAt() time.Time   { return u.createdAt }
-func (u *User) UpdatedAt() time.Time   { return u.updatedAt }
+// RestoreParams holds the fields of a stored user.
+type RestoreParams struct {
+	ID            uuid.UUID
+	Email         string
+	Username      string
+	PasswordHash  string
+	DateOfBirth   time.Time
+	WeekAnchor    week.Anchor
+	TimeZone      string
+	CalendarToken string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	Version       aggregate.Version
+}
+
+// Restore rebuilds a user that a repository stored. Unlike NewUser it
+// accepts the fields as they are, except that the time zone must still be
+// known.
+func Restore(params RestoreParams) (*User, error) {
+	location, err := loadLocation(params.TimeZone)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &User{
+		id:            params.ID,
+		email:         params.Email,
+		username:      params.Username,
+		passwordHash:  params.PasswordHash,
+		dateOfBirth:   params.DateOfBirth,
+		weekAnchor:    params.WeekAnchor,
+		location:      location,
+		calendarToken: params.CalendarToken,
+		createdAt:     params.CreatedAt,
+		updatedAt:     params.UpdatedAt,
+	}
+	u.SetVersion(params.Version)
+	return u, nil
+}
+
+func (u *User) ID() uuid.UUID            { return u.id }
+func (u *User) Email() string            { return u.email }
+func (u *User) Username() string         { return u.username }
+func (u *User) PasswordHash() string     { return u.passwordHash }
+func (u *User) DateOfBirth() time.Time   { return u.dateOfBirth }
+func (u *User) WeekAnchor() week.Anchor  { return u.weekAnchor }
+func (u *User) Location() *time.Location { return u.location }
+func (u *User) CalendarToken() string    { return u.calendarToken }
+func (u *User) CreatedAt() time.Time     { return u.createdAt }
+func (u *User) UpdatedAt() time.Time     { return u.updatedAt }
+
+// Clone returns a copy of u that can be changed without affecting u.
+func (u *User) Clone() *User {
+	c := *u
+	return &c
+}
+
+// CurrentWeek returns the week of life that now falls in, as seen from the
+// user's time zone.
+func (u *User) CurrentWeek(now time.Time) (week.Week, error) {
+	return u.weekAnchor.At(u.dateOfBirth, now.In(u.location))
+}
+
+func (u *User) ChangeTimeZone(name string) error {
+	location, err := loadLocation(name)
+	if err != nil {
+		return err
+	}
+
+	u.location = location
+	u.updatedAt = time.Now().UTC()
+	return nil
+}
+
+func (u *User) ChangeWeekAnchor(anchor week.Anchor) error {
+	if err := anchor.Validate(); err != nil {
+		return err
+	}
+
+	u.weekAnchor = anchor
+	u.updatedAt = time.Now().UTC()
+	return nil
+}
 
 func validateEmail(email string) error {
 	if email == "" {
@@ -94,3 +204,17 @@ func validatePassword(password string) error {
 	}
 	return nil
 }
+
+// loadLocation resolves an IANA time zone name. "Local" is rejected because
+// it depends on the server rather than the user.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, ErrInvalidTimeZone
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, ErrInvalidTimeZone
+	}
+	return location, nil
+}
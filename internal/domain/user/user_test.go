@@ -1,11 +1,13 @@
 package user
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -31,12 +33,12 @@ type MockPasswordHasher struct {
 	mock.Mock
 }
 
-func (m *MockPasswordHasher) Hash(password string) (string, error) {
+func (m *MockPasswordHasher) Hash(ctx context.Context, password string) (string, error) {
 	args := m.Called(password)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockPasswordHasher) Compare(hashedPassword, password string) error {
+func (m *MockPasswordHasher) Compare(ctx context.Context, hashedPassword, password string) error {
 	args := m.Called(hashedPassword, password)
 	return args.Error(0)
 }
@@ -98,6 +100,50 @@ func TestNewUser(t *testing.T) {
 			mockSetup:   func(m *MockPasswordHasher) {},
 			expectedErr: ErrPasswordTooShort,
 		},
+		{
+			name: "birthday week anchor",
+			params: withParams(func(p *NewUserParams) {
+				p.WeekAnchor = week.Anchor{Mode: week.ModeBirthday}
+			}),
+			mockSetup: func(m *MockPasswordHasher) {
+				m.On("Hash", validPassword).
+					Return(hashedPassword, nil).
+					Once()
+			},
+			expectedErr: nil,
+		},
+		{
+			name: "invalid week anchor",
+			params: withParams(func(p *NewUserParams) {
+				p.WeekAnchor = week.Anchor{Mode: "lunar"}
+			}),
+			mockSetup:   func(m *MockPasswordHasher) {},
+			expectedErr: week.ErrInvalidMode,
+		},
+		{
+			name: "valid time zone",
+			params: withParams(func(p *NewUserParams) {
+				p.TimeZone = "Pacific/Auckland"
+			}),
+			mockSetup: func(m *MockPasswordHasher) {
+				m.On("Hash", validPassword).
+					Return(hashedPassword, nil).
+					Once()
+			},
+			expectedErr: nil,
+		},
+		{
+			name:        "unknown time zone",
+			params:      withParams(func(p *NewUserParams) { p.TimeZone = "Mars/Olympus_Mons" }),
+			mockSetup:   func(m *MockPasswordHasher) {},
+			expectedErr: ErrInvalidTimeZone,
+		},
+		{
+			name:        "server local time zone",
+			params:      withParams(func(p *NewUserParams) { p.TimeZone = "Local" }),
+			mockSetup:   func(m *MockPasswordHasher) {},
+			expectedErr: ErrInvalidTimeZone,
+		},
 		{
 			name:   "hashing error",
 			params: validNewUserParams,
@@ -115,7 +161,7 @@ func TestNewUser(t *testing.T) {
 			mockHasher := new(MockPasswordHasher)
 			tt.mockSetup(mockHasher)
 
-			user, err := NewUser(tt.params, mockHasher)
+			user, err := NewUser(context.Background(), tt.params, mockHasher)
 
 			if tt.expectedErr != nil {
 				require.ErrorIs(t, err, tt.expectedErr, "expected an error but got none")
@@ -130,6 +176,19 @@ func TestNewUser(t *testing.T) {
 				assert.NotEmpty(t, user.PasswordHash(), "password hash should be set on successful creation")
 				assert.NotEqual(t, tt.params.Password, user.PasswordHash(), "password hash should not be the same as the raw password")
 				assert.Equal(t, tt.params.DateOfBirth, user.DateOfBirth(), "date of birth does not match expected")
+				assert.NotEmpty(t, user.CalendarToken(), "calendar token should be generated on creation")
+
+				expectedAnchor := tt.params.WeekAnchor
+				if expectedAnchor.Mode == "" {
+					expectedAnchor = week.DefaultAnchor
+				}
+				assert.Equal(t, expectedAnchor, user.WeekAnchor(), "week anchor does not match expected")
+
+				expectedTimeZone := tt.params.TimeZone
+				if expectedTimeZone == "" {
+					expectedTimeZone = "UTC"
+				}
+				assert.Equal(t, expectedTimeZone, user.Location().String(), "time zone does not match expected")
 			}
 			mockHasher.AssertExpectations(t)
 		})
@@ -146,11 +205,69 @@ func TestNewUser_UniqueIDs(t *testing.T) {
 	params2 := validNewUserParams
 	params2.Email = "john2@example.com"
 
-	user1, err1 := NewUser(params1, mockHasher)
+	user1, err1 := NewUser(context.Background(), params1, mockHasher)
 	require.NoError(t, err1)
 
-	user2, err2 := NewUser(params2, mockHasher)
+	user2, err2 := NewUser(context.Background(), params2, mockHasher)
 	require.NoError(t, err2)
 
 	assert.NotEqual(t, user1.ID(), user2.ID(), "expected users to have different IDs")
+	assert.NotEqual(t, user1.CalendarToken(), user2.CalendarToken(), "expected users to have different calendar tokens")
+}
+
+func TestUser_CurrentWeek(t *testing.T) {
+	// Friday 12:30 UTC is already Saturday in Auckland, and weeks of a
+	// user born on a Saturday start on Saturdays.
+	now := time.Date(2025, time.August, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		timeZone     string
+		expectedWeek int
+	}{
+		{name: "utc", timeZone: "UTC", expectedWeek: 1706},
+		{name: "auckland rolls over at local midnight", timeZone: "Pacific/Auckland", expectedWeek: 1707},
+		{name: "honolulu is still on friday", timeZone: "Pacific/Honolulu", expectedWeek: 1706},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockHasher := new(MockPasswordHasher)
+			mockHasher.On("Hash", validPassword).Return(hashedPassword, nil)
+
+			user, err := NewUser(context.Background(), withParams(func(p *NewUserParams) { p.TimeZone = tt.timeZone }), mockHasher)
+			require.NoError(t, err)
+
+			w, err := user.CurrentWeek(now)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedWeek, w.Number)
+		})
+	}
+}
+
+func TestUser_ChangeProfile(t *testing.T) {
+	mockHasher := new(MockPasswordHasher)
+	mockHasher.On("Hash", validPassword).Return(hashedPassword, nil)
+
+	user, err := NewUser(context.Background(), validNewUserParams, mockHasher)
+	require.NoError(t, err)
+	createdAt := user.UpdatedAt()
+
+	t.Run("change time zone", func(t *testing.T) {
+		require.NoError(t, user.ChangeTimeZone("Europe/Berlin"))
+		assert.Equal(t, "Europe/Berlin", user.Location().String())
+		assert.False(t, user.UpdatedAt().Before(createdAt))
+
+		assert.ErrorIs(t, user.ChangeTimeZone("Nowhere/Special"), ErrInvalidTimeZone)
+		assert.Equal(t, "Europe/Berlin", user.Location().String(), "failed change should keep the old time zone")
+	})
+
+	t.Run("change week anchor", func(t *testing.T) {
+		anchor := week.Anchor{Mode: week.ModeCalendar, FirstWeekday: time.Sunday}
+		require.NoError(t, user.ChangeWeekAnchor(anchor))
+		assert.Equal(t, anchor, user.WeekAnchor())
+
+		assert.ErrorIs(t, user.ChangeWeekAnchor(week.Anchor{Mode: "lunar"}), week.ErrInvalidMode)
+		assert.Equal(t, anchor, user.WeekAnchor(), "failed change should keep the old week anchor")
+	})
 }
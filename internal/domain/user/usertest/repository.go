@@ -0,0 +1,61 @@
+// Package usertest provides a mock user.Repository for the tests of the
+// services built on it.
+package usertest
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Create(ctx context.Context, u *user.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Save(ctx context.Context, u *user.User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	args := m.Called(ctx, id)
+	var u *user.User
+	if args.Get(0) != nil {
+		u = args.Get(0).(*user.User)
+	}
+	return u, args.Error(1)
+}
+
+func (m *MockRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	args := m.Called(ctx, email)
+	var u *user.User
+	if args.Get(0) != nil {
+		u = args.Get(0).(*user.User)
+	}
+	return u, args.Error(1)
+}
+
+func (m *MockRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	args := m.Called(ctx, username)
+	var users []*user.User
+	if args.Get(0) != nil {
+		users = args.Get(0).([]*user.User)
+	}
+	return users, args.Error(1)
+}
+
+func (m *MockRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	args := m.Called(ctx)
+	var users []*user.User
+	if args.Get(0) != nil {
+		users = args.Get(0).([]*user.User)
+	}
+	return users, args.Error(1)
+}
@@ -0,0 +1,128 @@
+package week
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrBeforeBirth    = errors.New("date is before date of birth")
+	ErrInvalidMode    = errors.New("invalid week anchoring mode")
+	ErrInvalidWeekday = errors.New("invalid first weekday")
+)
+
+const (
+	daysPerWeek  = 7
+	weeksPerYear = 52
+)
+
+// Mode decides where week one starts and how later weeks are laid out.
+type Mode string
+
+const (
+	// ModeContinuous counts unbroken 7-day weeks from the date of birth.
+	ModeContinuous Mode = "continuous"
+	// ModeBirthday restarts the weeks on every birthday, giving 52 weeks a
+	// year with the one or two remaining days folded into the 52nd.
+	ModeBirthday Mode = "birthday"
+	// ModeCalendar follows calendar weeks starting on FirstWeekday, with
+	// week one being the calendar week that contains the date of birth.
+	// A Monday first weekday gives ISO 8601 weeks.
+	ModeCalendar Mode = "calendar"
+)
+
+type Anchor struct {
+	Mode         Mode
+	FirstWeekday time.Weekday
+}
+
+var DefaultAnchor = Anchor{Mode: ModeContinuous}
+
+// Week is a single week of a life. Start and End are calendar dates
+// represented as midnight UTC; End is exclusive.
+type Week struct {
+	Number int
+	Start  time.Time
+	End    time.Time
+}
+
+func (a Anchor) Validate() error {
+	switch a.Mode {
+	case ModeContinuous, ModeBirthday:
+		return nil
+	case ModeCalendar:
+		if a.FirstWeekday < time.Sunday || a.FirstWeekday > time.Saturday {
+			return ErrInvalidWeekday
+		}
+		return nil
+	default:
+		return ErrInvalidMode
+	}
+}
+
+// Nth returns the nth week of life, counting from one. An anchor that does
+// not validate is treated as continuous.
+func (a Anchor) Nth(dateOfBirth time.Time, n int) Week {
+	birth := Date(dateOfBirth)
+
+	switch a.Mode {
+	case ModeBirthday:
+		year, index := (n-1)/weeksPerYear, (n-1)%weeksPerYear
+		birthday := birth.AddDate(year, 0, 0)
+		start := birthday.AddDate(0, 0, index*daysPerWeek)
+		end := start.AddDate(0, 0, daysPerWeek)
+		if index == weeksPerYear-1 {
+			end = birth.AddDate(year+1, 0, 0)
+		}
+		return Week{Number: n, Start: start, End: end}
+	case ModeCalendar:
+		start := a.calendarWeekStart(birth).AddDate(0, 0, (n-1)*daysPerWeek)
+		return Week{Number: n, Start: start, End: start.AddDate(0, 0, daysPerWeek)}
+	default:
+		start := birth.AddDate(0, 0, (n-1)*daysPerWeek)
+		return Week{Number: n, Start: start, End: start.AddDate(0, 0, daysPerWeek)}
+	}
+}
+
+// At returns the week of life containing the calendar date of t. Callers
+// should convert t to the user's location before calling At.
+func (a Anchor) At(dateOfBirth, t time.Time) (Week, error) {
+	if err := a.Validate(); err != nil {
+		return Week{}, err
+	}
+
+	birth := Date(dateOfBirth)
+	day := Date(t)
+	if day.Before(birth) {
+		return Week{}, ErrBeforeBirth
+	}
+
+	switch a.Mode {
+	case ModeBirthday:
+		year := day.Year() - birth.Year()
+		if birth.AddDate(year, 0, 0).After(day) {
+			year--
+		}
+		index := min(daysBetween(birth.AddDate(year, 0, 0), day)/daysPerWeek, weeksPerYear-1)
+		return a.Nth(dateOfBirth, year*weeksPerYear+index+1), nil
+	case ModeCalendar:
+		return a.Nth(dateOfBirth, daysBetween(a.calendarWeekStart(birth), day)/daysPerWeek+1), nil
+	default:
+		return a.Nth(dateOfBirth, daysBetween(birth, day)/daysPerWeek+1), nil
+	}
+}
+
+func (a Anchor) calendarWeekStart(day time.Time) time.Time {
+	offset := (int(day.Weekday()) - int(a.FirstWeekday) + daysPerWeek) % daysPerWeek
+	return day.AddDate(0, 0, -offset)
+}
+
+// Date drops the clock and location from t, keeping its calendar date.
+func Date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func daysBetween(from, to time.Time) int {
+	return int(to.Sub(from).Hours() / 24)
+}
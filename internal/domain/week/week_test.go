@@ -0,0 +1,185 @@
+package week
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var validDOB = time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAnchorAt(t *testing.T) {
+	continuous := Anchor{Mode: ModeContinuous}
+	birthday := Anchor{Mode: ModeBirthday}
+	isoWeeks := Anchor{Mode: ModeCalendar, FirstWeekday: time.Monday}
+	sundayWeeks := Anchor{Mode: ModeCalendar, FirstWeekday: time.Sunday}
+
+	tests := []struct {
+		name          string
+		anchor        Anchor
+		t             time.Time
+		expectedWeek  int
+		expectedStart time.Time
+		expectedEnd   time.Time
+		expectedErr   error
+	}{
+		{
+			name:          "continuous day of birth is week one",
+			anchor:        continuous,
+			t:             validDOB,
+			expectedWeek:  1,
+			expectedStart: validDOB,
+			expectedEnd:   date(1992, time.November, 28),
+		},
+		{
+			name:          "continuous last moment of first week",
+			anchor:        continuous,
+			t:             time.Date(1992, time.November, 27, 23, 59, 0, 0, time.UTC),
+			expectedWeek:  1,
+			expectedStart: validDOB,
+			expectedEnd:   date(1992, time.November, 28),
+		},
+		{
+			name:          "continuous uses local date regardless of location",
+			anchor:        continuous,
+			t:             time.Date(1992, time.November, 28, 1, 0, 0, 0, time.FixedZone("NZDT", 13*3600)),
+			expectedWeek:  2,
+			expectedStart: date(1992, time.November, 28),
+			expectedEnd:   date(1992, time.December, 5),
+		},
+		{
+			name:          "continuous many years later",
+			anchor:        continuous,
+			t:             time.Date(2025, time.August, 1, 12, 0, 0, 0, time.UTC),
+			expectedWeek:  1706,
+			expectedStart: date(2025, time.July, 26),
+			expectedEnd:   date(2025, time.August, 2),
+		},
+		{
+			name:          "birthday restarts on each birthday",
+			anchor:        birthday,
+			t:             date(1993, time.November, 21),
+			expectedWeek:  53,
+			expectedStart: date(1993, time.November, 21),
+			expectedEnd:   date(1993, time.November, 28),
+		},
+		{
+			name:          "birthday mid year",
+			anchor:        birthday,
+			t:             date(2025, time.August, 1),
+			expectedWeek:  1701,
+			expectedStart: date(2025, time.July, 31),
+			expectedEnd:   date(2025, time.August, 7),
+		},
+		{
+			name:          "birthday remainder days fold into week 52",
+			anchor:        birthday,
+			t:             date(2025, time.November, 20),
+			expectedWeek:  1716,
+			expectedStart: date(2025, time.November, 13),
+			expectedEnd:   date(2025, time.November, 21),
+		},
+		{
+			name:          "calendar week containing birth is week one",
+			anchor:        isoWeeks,
+			t:             validDOB,
+			expectedWeek:  1,
+			expectedStart: date(1992, time.November, 16),
+			expectedEnd:   date(1992, time.November, 23),
+		},
+		{
+			name:          "calendar week rolls over on first weekday",
+			anchor:        isoWeeks,
+			t:             date(1992, time.November, 23),
+			expectedWeek:  2,
+			expectedStart: date(1992, time.November, 23),
+			expectedEnd:   date(1992, time.November, 30),
+		},
+		{
+			name:          "calendar weeks many years later",
+			anchor:        isoWeeks,
+			t:             date(2025, time.August, 1),
+			expectedWeek:  1707,
+			expectedStart: date(2025, time.July, 28),
+			expectedEnd:   date(2025, time.August, 4),
+		},
+		{
+			name:          "calendar weeks starting on sunday",
+			anchor:        sundayWeeks,
+			t:             date(2025, time.August, 1),
+			expectedWeek:  1707,
+			expectedStart: date(2025, time.July, 27),
+			expectedEnd:   date(2025, time.August, 3),
+		},
+		{
+			name:        "before birth",
+			anchor:      continuous,
+			t:           validDOB.AddDate(0, 0, -1),
+			expectedErr: ErrBeforeBirth,
+		},
+		{
+			name:        "invalid mode",
+			anchor:      Anchor{Mode: "fortnightly"},
+			t:           validDOB,
+			expectedErr: ErrInvalidMode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, err := tt.anchor.At(validDOB, tt.t)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedWeek, w.Number)
+			assert.Equal(t, tt.expectedStart, w.Start)
+			assert.Equal(t, tt.expectedEnd, w.End)
+		})
+	}
+}
+
+func TestAnchorNth_Contiguous(t *testing.T) {
+	anchors := []Anchor{
+		{Mode: ModeContinuous},
+		{Mode: ModeBirthday},
+		{Mode: ModeCalendar, FirstWeekday: time.Wednesday},
+	}
+	leapDOB := date(2000, time.February, 29)
+
+	for _, anchor := range anchors {
+		t.Run(string(anchor.Mode), func(t *testing.T) {
+			prev := anchor.Nth(leapDOB, 1)
+			for n := 2; n <= 52*10; n++ {
+				w := anchor.Nth(leapDOB, n)
+				require.Equal(t, prev.End, w.Start, "week %d does not follow week %d", n, n-1)
+
+				found, err := anchor.At(leapDOB, w.Start)
+				require.NoError(t, err)
+				require.Equal(t, n, found.Number, "week %d does not contain its own start", n)
+
+				found, err = anchor.At(leapDOB, w.End.AddDate(0, 0, -1))
+				require.NoError(t, err)
+				require.Equal(t, n, found.Number, "week %d does not contain its last day", n)
+
+				prev = w
+			}
+		})
+	}
+}
+
+func TestAnchorValidate(t *testing.T) {
+	assert.NoError(t, DefaultAnchor.Validate())
+	assert.NoError(t, Anchor{Mode: ModeBirthday}.Validate())
+	assert.NoError(t, Anchor{Mode: ModeCalendar, FirstWeekday: time.Saturday}.Validate())
+	assert.ErrorIs(t, Anchor{Mode: ModeCalendar, FirstWeekday: 7}.Validate(), ErrInvalidWeekday)
+	assert.ErrorIs(t, Anchor{}.Validate(), ErrInvalidMode)
+}
@@ -0,0 +1,143 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrShuttingDown = errors.New("shutting down")
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+// CheckFunc reports whether a dependency is usable. It should honour ctx,
+// which carries the check's timeout.
+type CheckFunc func(ctx context.Context) error
+
+type Check struct {
+	Name string
+	// Timeout bounds a single run. Zero uses the registry default.
+	Timeout time.Duration
+	// Optional checks are reported but do not fail readiness. They suit
+	// dependencies whose outage degrades the service without stopping it,
+	// such as a mail server behind the job queue.
+	Optional bool
+	Run      CheckFunc
+}
+
+type Result struct {
+	Status   string  `json:"status"`
+	Optional bool    `json:"optional,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Duration float64 `json:"durationMs"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+func (r Report) Healthy() bool {
+	return r.Status == StatusOK
+}
+
+// Registry holds the readiness checks adapters register at start-up.
+type Registry struct {
+	defaultTimeout time.Duration
+	shuttingDown   atomic.Bool
+
+	mu     sync.RWMutex
+	checks []Check
+}
+
+func NewRegistry(defaultTimeout time.Duration) *Registry {
+	return &Registry{defaultTimeout: defaultTimeout}
+}
+
+func (r *Registry) Register(c Check) {
+	if c.Timeout <= 0 {
+		c.Timeout = r.defaultTimeout
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, c)
+}
+
+// SetShuttingDown makes every later report fail, so load balancers stop
+// routing new requests while in-flight ones drain.
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Check runs all checks concurrently, each bounded by its own timeout.
+func (r *Registry) Check(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := append([]Check(nil), r.checks...)
+	r.mu.RUnlock()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks)+1)}
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result := run(ctx, c)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.Name] = result
+		}()
+	}
+	wg.Wait()
+
+	if r.shuttingDown.Load() {
+		report.Checks["shutdown"] = Result{Status: StatusFail, Error: ErrShuttingDown.Error()}
+	}
+	for _, result := range report.Checks {
+		if result.Status != StatusOK && !result.Optional {
+			report.Status = StatusFail
+		}
+	}
+	return report
+}
+
+// run executes one check. A check that ignores its context is abandoned
+// when the timeout expires rather than holding up the whole report.
+func run(ctx context.Context, c Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result := Result{
+		Status:   StatusOK,
+		Optional: c.Optional,
+		Duration: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ok(context.Context) error { return nil }
+
+func TestRegistry_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		checks     []Check
+		wantStatus string
+		wantChecks map[string]string
+	}{
+		{
+			name:       "no checks",
+			wantStatus: StatusOK,
+			wantChecks: map[string]string{},
+		},
+		{
+			name: "all passing",
+			checks: []Check{
+				{Name: "storage", Run: ok},
+				{Name: "mailer", Run: ok},
+			},
+			wantStatus: StatusOK,
+			wantChecks: map[string]string{"storage": StatusOK, "mailer": StatusOK},
+		},
+		{
+			name: "one failing",
+			checks: []Check{
+				{Name: "storage", Run: func(context.Context) error { return errors.New("connection refused") }},
+				{Name: "mailer", Run: ok},
+			},
+			wantStatus: StatusFail,
+			wantChecks: map[string]string{"storage": StatusFail, "mailer": StatusOK},
+		},
+		{
+			name: "optional failing",
+			checks: []Check{
+				{Name: "storage", Run: ok},
+				{Name: "mailer", Optional: true, Run: func(context.Context) error { return errors.New("connection refused") }},
+			},
+			wantStatus: StatusOK,
+			wantChecks: map[string]string{"storage": StatusOK, "mailer": StatusFail},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := NewRegistry(time.Second)
+			for _, c := range tt.checks {
+				registry.Register(c)
+			}
+
+			report := registry.Check(context.Background())
+
+			assert.Equal(t, tt.wantStatus, report.Status)
+			got := make(map[string]string, len(report.Checks))
+			for name, result := range report.Checks {
+				got[name] = result.Status
+				if result.Status == StatusFail {
+					assert.Equal(t, "connection refused", result.Error)
+				}
+			}
+			assert.Equal(t, tt.wantChecks, got)
+		})
+	}
+}
+
+func TestRegistry_CheckTimeout(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	block := make(chan struct{})
+	defer close(block)
+
+	registry.Register(Check{Name: "honours ctx", Timeout: 20 * time.Millisecond, Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+	registry.Register(Check{Name: "ignores ctx", Timeout: 20 * time.Millisecond, Run: func(context.Context) error {
+		<-block
+		return nil
+	}})
+
+	start := time.Now()
+	report := registry.Check(context.Background())
+
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Equal(t, StatusFail, report.Status)
+	for _, name := range []string{"honours ctx", "ignores ctx"} {
+		assert.Equal(t, StatusFail, report.Checks[name].Status, name)
+		assert.Equal(t, context.DeadlineExceeded.Error(), report.Checks[name].Error, name)
+	}
+}
+
+func TestRegistry_ShuttingDown(t *testing.T) {
+	registry := NewRegistry(time.Second)
+	registry.Register(Check{Name: "storage", Run: ok})
+	assert.True(t, registry.Check(context.Background()).Healthy())
+
+	registry.SetShuttingDown()
+
+	report := registry.Check(context.Background())
+	assert.False(t, report.Healthy())
+	assert.Equal(t, StatusOK, report.Checks["storage"].Status)
+	assert.Equal(t, ErrShuttingDown.Error(), report.Checks["shutdown"].Error)
+}
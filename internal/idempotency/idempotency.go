@@ -0,0 +1,62 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrClaimLost is returned by Complete when the caller's claim has expired
+// and the key has been claimed again or released since.
+var ErrClaimLost = errors.New("idempotency claim lost")
+
+// Policy says how long keys are kept. A completed request is replayed for
+// TTL. A retry that arrives while the first request is still in flight
+// waits up to Wait for it to finish. An in-flight claim is given up after
+// Lease, in case the instance serving it died.
+type Policy struct {
+	TTL   time.Duration
+	Wait  time.Duration
+	Lease time.Duration
+}
+
+// Response is a response as stored for replay.
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// Record is what a Store keeps per key. Response is nil while the first
+// request is in flight.
+type Record struct {
+	Fingerprint string
+	Response    *Response
+	ExpiresAt   time.Time
+}
+
+// Store keeps one record per key. Claim must be atomic per key, so that of
+// concurrent requests with the same key, possibly served by several
+// instances sharing the store, exactly one runs.
+type Store interface {
+	// Claim records a request with fingerprint as in flight until the
+	// given time and returns a token for the claim, unless the key holds a
+	// record that has not expired by now, which it returns instead with an
+	// empty token.
+	Claim(ctx context.Context, key, fingerprint string, now, until time.Time) (rec Record, claim string, err error)
+	// Complete stores the response to the request claimed with claim until
+	// expires.
+	Complete(ctx context.Context, key, claim string, resp Response, expires time.Time) error
+	// Release forgets the claim on key so that the request can be retried.
+	// It does nothing if the key is no longer held by claim.
+	Release(ctx context.Context, key, claim string) error
+}
+
+// Fingerprint identifies a request body.
+func Fingerprint(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]any
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo, "json")
+	ctx := WithRequestID(context.Background(), "req-1")
+
+	logger.DebugContext(ctx, "hidden")
+	logger.With("component", "test").InfoContext(ctx, "signed up",
+		"password", "hunter2",
+		slog.Group("user", slog.String("Password", "hunter2"), slog.String("email", "ada@example.com")),
+	)
+	logger.Info("no request")
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, "signed up", records[0]["msg"])
+	assert.Equal(t, "req-1", records[0]["request_id"])
+	assert.Equal(t, "test", records[0]["component"])
+	assert.Equal(t, Redacted, records[0]["password"])
+	assert.Equal(t, map[string]any{"Password": Redacted, "email": "ada@example.com"}, records[0]["user"])
+
+	assert.NotContains(t, records[1], "request_id")
+}
+
+func TestRequestID(t *testing.T) {
+	assert.Empty(t, RequestID(context.Background()))
+	assert.Equal(t, "abc", RequestID(WithRequestID(context.Background(), "abc")))
+}
+
+func TestLogger_TraceIDs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, slog.LevelInfo, "json")
+
+	traceID, _ := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	spanID, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}))
+
+	logger.InfoContext(ctx, "traced")
+
+	records := decodeLines(t, &buf)
+	require.Len(t, records, 1)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", records[0]["trace_id"])
+	assert.Equal(t, "00f067aa0ba902b7", records[0]["span_id"])
+}
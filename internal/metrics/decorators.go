@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	appuser "github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+)
+
+type userService struct {
+	appuser.Service
+	metrics *Metrics
+}
+
+// UserService counts sign-ups and email conflicts.
+func (m *Metrics) UserService(next appuser.Service) appuser.Service {
+	return &userService{Service: next, metrics: m}
+}
+
+func (s *userService) CreateUser(ctx context.Context, req appuser.CreateUserRequest) (*appuser.CreateUserResponse, error) {
+	resp, err := s.Service.CreateUser(ctx, req)
+	switch {
+	case err == nil:
+		s.metrics.usersCreated.Inc()
+	case errors.Is(err, appuser.ErrEmailExists):
+		s.metrics.emailConflicts.Inc()
+	}
+	return resp, err
+}
+
+type userRepository struct {
+	next    user.UserRepository
+	metrics *Metrics
+}
+
+// UserRepository times every repository call.
+func (m *Metrics) UserRepository(next user.UserRepository) user.UserRepository {
+	return &userRepository{next: next, metrics: m}
+}
+
+// duration records a call. A lookup that finds nothing or a signup with a
+// registered email is a normal outcome, not an error.
+func (r *userRepository) duration(operation string, start time.Time, err error) {
+	if errors.Is(err, user.ErrUserNotFound) || errors.Is(err, user.ErrEmailTaken) {
+		err = nil
+	}
+	r.metrics.repositoryDuration.WithLabelValues("user", operation, outcome(err)).Observe(time.Since(start).Seconds())
+}
+
+func (r *userRepository) Create(ctx context.Context, u *user.User) error {
+	start := time.Now()
+	err := r.next.Create(ctx, u)
+	r.duration("create", start, err)
+	return err
+}
+
+func (r *userRepository) Save(ctx context.Context, u *user.User) error {
+	start := time.Now()
+	err := r.next.Save(ctx, u)
+	r.duration("save", start, err)
+	return err
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	start := time.Now()
+	u, err := r.next.FindByID(ctx, id)
+	r.duration("find_by_id", start, err)
+	return u, err
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	start := time.Now()
+	u, err := r.next.FindByEmail(ctx, email)
+	r.duration("find_by_email", start, err)
+	return u, err
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	start := time.Now()
+	users, err := r.next.FindByUsername(ctx, username)
+	r.duration("find_by_username", start, err)
+	return users, err
+}
+
+func (r *userRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	start := time.Now()
+	users, err := r.next.FindAll(ctx)
+	r.duration("find_all", start, err)
+	return users, err
+}
+
+type passwordHasher struct {
+	next    user.PasswordHasher
+	metrics *Metrics
+}
+
+// PasswordHasher times hashing and counts passwords that fail to match,
+// which is what a failed login looks like from the hasher's side.
+func (m *Metrics) PasswordHasher(next user.PasswordHasher) user.PasswordHasher {
+	return &passwordHasher{next: next, metrics: m}
+}
+
+func (h *passwordHasher) Hash(ctx context.Context, password string) (string, error) {
+	start := time.Now()
+	hash, err := h.next.Hash(ctx, password)
+	h.metrics.hashDuration.WithLabelValues("hash").Observe(time.Since(start).Seconds())
+	return hash, err
+}
+
+func (h *passwordHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	start := time.Now()
+	err := h.next.Compare(ctx, hashedPassword, password)
+	h.metrics.hashDuration.WithLabelValues("compare").Observe(time.Since(start).Seconds())
+	if err != nil {
+		h.metrics.loginFailures.Inc()
+	}
+	return err
+}
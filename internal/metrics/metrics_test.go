@@ -0,0 +1,173 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	appuser "github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/cache"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubService struct {
+	appuser.Service
+	err error
+}
+
+func (s *stubService) CreateUser(ctx context.Context, req appuser.CreateUserRequest) (*appuser.CreateUserResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &appuser.CreateUserResponse{}, nil
+}
+
+type stubRepository struct {
+	user.UserRepository
+	err error
+}
+
+func (r *stubRepository) Create(ctx context.Context, u *user.User) error { return user.ErrEmailTaken }
+
+func (r *stubRepository) Save(ctx context.Context, u *user.User) error { return r.err }
+
+func (r *stubRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	return nil, user.ErrUserNotFound
+}
+
+type stubHasher struct{}
+
+func (stubHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hash:" + password, nil
+}
+
+func (stubHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	if hashedPassword != "hash:"+password {
+		return errors.New("mismatch")
+	}
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	m := New()
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil),
+		httptest.NewRequest(http.MethodGet, "/users/"+uuid.NewString(), nil),
+		httptest.NewRequest(http.MethodPost, "/users", nil),
+		httptest.NewRequest(http.MethodGet, "/nowhere", nil),
+	} {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "/users/{id}", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("POST", "/users", "409")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("GET", "unmatched", "404")))
+	assert.Equal(t, 3, testutil.CollectAndCount(m.httpRequestDuration))
+}
+
+func TestUserService(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	_, err := m.UserService(&stubService{}).CreateUser(ctx, appuser.CreateUserRequest{})
+	require.NoError(t, err)
+	_, err = m.UserService(&stubService{err: appuser.ErrEmailExists}).CreateUser(ctx, appuser.CreateUserRequest{})
+	require.ErrorIs(t, err, appuser.ErrEmailExists)
+	_, err = m.UserService(&stubService{err: errors.New("db down")}).CreateUser(ctx, appuser.CreateUserRequest{})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.usersCreated))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.emailConflicts))
+}
+
+func TestUserRepository(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+
+	repo := m.UserRepository(&stubRepository{})
+	_, err := repo.FindByEmail(ctx, "ada@example.com")
+	require.ErrorIs(t, err, user.ErrUserNotFound)
+	require.ErrorIs(t, repo.Create(ctx, nil), user.ErrEmailTaken)
+	require.NoError(t, repo.Save(ctx, nil))
+
+	failing := m.UserRepository(&stubRepository{err: errors.New("disk full")})
+	require.Error(t, failing.Save(ctx, nil))
+
+	assert.Equal(t, 4, testutil.CollectAndCount(m.repositoryDuration))
+	for _, labels := range [][]string{
+		{"user", "find_by_email", "ok"},
+		{"user", "create", "ok"},
+		{"user", "save", "ok"},
+		{"user", "save", "error"},
+	} {
+		_, err := m.repositoryDuration.GetMetricWithLabelValues(labels...)
+		assert.NoError(t, err, "labels %v", labels)
+	}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	m := New()
+	ctx := context.Background()
+	hasher := m.PasswordHasher(stubHasher{})
+
+	hash, err := hasher.Hash(ctx, "secret")
+	require.NoError(t, err)
+	assert.NoError(t, hasher.Compare(ctx, hash, "secret"))
+	assert.Error(t, hasher.Compare(ctx, hash, "guess"))
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.loginFailures))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.hashDuration))
+}
+
+func TestUserCache(t *testing.T) {
+	m := New()
+	m.UserCache(func() cache.Stats { return cache.Stats{Hits: 3, Misses: 1} })
+
+	expected := `
+# HELP weekbyweek_cache_hits_total Lookups answered from a cache, including remembered misses.
+# TYPE weekbyweek_cache_hits_total counter
+weekbyweek_cache_hits_total{cache="user"} 3
+# HELP weekbyweek_cache_misses_total Lookups a cache passed on to the store behind it.
+# TYPE weekbyweek_cache_misses_total counter
+weekbyweek_cache_misses_total{cache="user"} 1
+`
+	assert.NoError(t, testutil.GatherAndCompare(m.registry, strings.NewReader(expected),
+		"weekbyweek_cache_hits_total", "weekbyweek_cache_misses_total"))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.usersCreated.Inc()
+
+	srv := httptest.NewServer(m.Handler())
+	defer srv.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), "weekbyweek_users_created_total 1")
+	assert.Contains(t, string(body), "go_goroutines")
+}
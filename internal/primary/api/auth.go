@@ -0,0 +1,57 @@
+package api
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/app/user"
+)
+
+// requireUser lets a request through only if it carries the email and
+// password of the user named by the route parameter param, as HTTP Basic
+// credentials. A request for any ID but the user's own is forbidden,
+// whether or not a user has it.
+func requireUser(users user.Service, param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			want, err := uuid.Parse(chi.URLParam(r, param))
+			if err != nil {
+				http.NotFound(w, r)
+				return
+			}
+			email, password, ok := r.BasicAuth()
+			if !ok {
+				unauthorized(w, "credentials required")
+				return
+			}
+
+			id, err := users.Authenticate(r.Context(), email, password)
+			switch {
+			case errors.Is(err, user.ErrInvalidCredentials):
+				unauthorized(w, user.ErrInvalidCredentials.Error())
+				return
+			case errors.Is(err, user.ErrAccountLocked):
+				http.Error(w, user.ErrAccountLocked.Error(), http.StatusTooManyRequests)
+				return
+			case err != nil:
+				slog.ErrorContext(r.Context(), "authenticating user", "error", err)
+				http.Error(w, "Failed to authenticate", http.StatusInternalServerError)
+				return
+			}
+
+			if id != want {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter, msg string) {
+	w.Header().Set("WWW-Authenticate", `Basic realm="weekbyweek", charset="UTF-8"`)
+	http.Error(w, msg, http.StatusUnauthorized)
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRequireUser(t *testing.T) {
+	id := uuid.New()
+
+	tests := []struct {
+		name               string
+		path               string
+		credentials        bool
+		authErr            error
+		expectedStatusCode int
+		expectedBody       string
+	}{
+		{
+			name:               "own user",
+			path:               "/users/" + id.String(),
+			credentials:        true,
+			expectedStatusCode: http.StatusOK,
+			expectedBody:       "ok",
+		},
+		{
+			name:               "no credentials",
+			path:               "/users/" + id.String(),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "credentials required",
+		},
+		{
+			name:               "wrong password",
+			path:               "/users/" + id.String(),
+			credentials:        true,
+			authErr:            fmt.Errorf("%w: password does not match", user.ErrInvalidCredentials),
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "invalid email or password",
+		},
+		{
+			name:               "locked account",
+			path:               "/users/" + id.String(),
+			credentials:        true,
+			authErr:            fmt.Errorf("%w: until later", user.ErrAccountLocked),
+			expectedStatusCode: http.StatusTooManyRequests,
+			expectedBody:       "account temporarily locked",
+		},
+		{
+			name:               "service failure",
+			path:               "/users/" + id.String(),
+			credentials:        true,
+			authErr:            errors.New("boom"),
+			expectedStatusCode: http.StatusInternalServerError,
+			expectedBody:       "Failed to authenticate",
+		},
+		{
+			name:               "another user",
+			path:               "/users/" + uuid.NewString(),
+			credentials:        true,
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       "Forbidden",
+		},
+		{
+			name:               "malformed id",
+			path:               "/users/not-a-uuid",
+			credentials:        true,
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       "404 page not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockUserService)
+			authenticated := id
+			if tt.authErr != nil {
+				authenticated = uuid.Nil
+			}
+			service.On("Authenticate", mock.Anything, "john@example.com", "correct horse").Return(authenticated, tt.authErr).Maybe()
+
+			r := chi.NewRouter()
+			r.With(requireUser(service, "id")).Get("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+			})
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.credentials {
+				req.SetBasicAuth("john@example.com", "correct horse")
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rec.Code)
+			assert.Equal(t, tt.expectedBody, strings.TrimSpace(rec.Body.String()))
+			if tt.expectedStatusCode == http.StatusUnauthorized {
+				assert.Equal(t, `Basic realm="weekbyweek", charset="UTF-8"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
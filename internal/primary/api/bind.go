@@ -0,0 +1,273 @@
+package api
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxJSONBody bounds request bodies handed to decodeJSON.
+const maxJSONBody = 64 << 10
+
+// Problem is an RFC 9457 problem details object. Pointer, an extension
+// member, locates the offending value in the request body as a JSON
+// Pointer.
+type Problem struct {
+	Type    string `json:"type"`
+	Title   string `json:"title"`
+	Status  int    `json:"status"`
+	Detail  string `json:"detail,omitempty"`
+	Pointer string `json:"pointer,omitempty"`
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail, pointer string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(Problem{
+		Type:    "about:blank",
+		Title:   http.StatusText(status),
+		Status:  status,
+		Detail:  detail,
+		Pointer: pointer,
+	})
+}
+
+// decodeJSON binds a JSON object in the request body to v, which must
+// point to a struct. Decoding is strict: the body must be application/json,
+// at most maxJSONBody bytes, and hold exactly one object whose members all
+// exist in v and appear once, matched case-sensitively. Otherwise it writes
+// a problem response and returns false.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if !isJSON(r.Header.Get("Content-Type")) {
+		writeProblem(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json", "")
+		return false
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxJSONBody))
+	if err != nil {
+		if _, ok := errors.AsType[*http.MaxBytesError](err); ok {
+			writeProblem(w, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body must not exceed %d bytes", maxJSONBody), "")
+		} else {
+			writeProblem(w, http.StatusBadRequest, "reading request body: "+err.Error(), "")
+		}
+		return false
+	}
+
+	switch trimmed := bytes.TrimSpace(data); {
+	case len(trimmed) == 0:
+		writeProblem(w, http.StatusBadRequest, "request body is empty", "")
+		return false
+	case trimmed[0] != '{':
+		writeProblem(w, http.StatusBadRequest, "request body must be a JSON object", "")
+		return false
+	}
+
+	if err := checkJSON(data, reflect.TypeOf(v).Elem()); err != nil {
+		writeProblem(w, http.StatusBadRequest, err.detail, err.pointer)
+		return false
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		writeProblem(w, http.StatusBadRequest, "malformed JSON: "+err.Error(), "")
+		return false
+	}
+	return true
+}
+
+// isJSON accepts application/json and +json types in UTF-8, the only
+// encoding JSON allows.
+func isJSON(contentType string) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if charset, ok := params["charset"]; ok && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+	return mediaType == "application/json" ||
+		strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json")
+}
+
+// jsonError explains why a body was refused and where.
+type jsonError struct {
+	detail  string
+	pointer string
+}
+
+// checkJSON holds data to what decodeJSON promises before encoding/json,
+// which is laxer, decodes it into a t: one value and nothing after it,
+// members that t has, matched case-sensitively and given once, and values
+// that fit their fields. Errors locate the value at fault.
+func checkJSON(data []byte, t reflect.Type) *jsonError {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if err := checkValue(dec, t, ""); err != nil {
+		return err
+	}
+	offset := int(dec.InputOffset())
+	for offset < len(data) && strings.ContainsRune(" \t\r\n", rune(data[offset])) {
+		offset++
+	}
+	if offset < len(data) {
+		return &jsonError{detail: fmt.Sprintf("malformed JSON at byte %d: invalid character %q after top-level value", offset, rune(data[offset]))}
+	}
+	return nil
+}
+
+var (
+	jsonUnmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+	textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+)
+
+// checkValue reads the next value from dec, which is to be decoded into a
+// t at pointer.
+func checkValue(dec *json.Decoder, t reflect.Type, pointer string) *jsonError {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	custom := reflect.PointerTo(t).Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(textUnmarshalerType)
+	composite := t.Kind() == reflect.Struct || t.Kind() == reflect.Map ||
+		t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 || t.Kind() == reflect.Array
+	if custom || !composite {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return syntaxError(err, pointer)
+		}
+		if err := json.Unmarshal(raw, reflect.New(t).Interface()); err != nil {
+			return &jsonError{detail: "must be " + describeType(t), pointer: pointer}
+		}
+		return nil
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return syntaxError(err, pointer)
+	}
+	switch tok {
+	case nil:
+		return nil
+	case json.Delim('{'):
+		if t.Kind() == reflect.Struct || t.Kind() == reflect.Map {
+			return checkObject(dec, t, pointer)
+		}
+	case json.Delim('['):
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			return checkArray(dec, t, pointer)
+		}
+	}
+	return &jsonError{detail: "must be " + describeType(t), pointer: pointer}
+}
+
+func checkObject(dec *json.Decoder, t reflect.Type, pointer string) *jsonError {
+	var fields map[string]reflect.Type
+	if t.Kind() == reflect.Struct {
+		fields = jsonFields(t)
+	}
+	seen := make(map[string]bool)
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return syntaxError(err, pointer)
+		}
+		name := tok.(string)
+		member := pointer + "/" + strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+		if seen[name] {
+			return &jsonError{detail: "duplicate field", pointer: member}
+		}
+		seen[name] = true
+
+		elem := t.Elem
+		if fields != nil {
+			ft, ok := fields[name]
+			if !ok {
+				return &jsonError{detail: "unknown field", pointer: member}
+			}
+			elem = func() reflect.Type { return ft }
+		}
+		if err := checkValue(dec, elem(), member); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return syntaxError(err, pointer)
+	}
+	return nil
+}
+
+func checkArray(dec *json.Decoder, t reflect.Type, pointer string) *jsonError {
+	for i := 0; dec.More(); i++ {
+		if err := checkValue(dec, t.Elem(), pointer+"/"+strconv.Itoa(i)); err != nil {
+			return err
+		}
+	}
+	if _, err := dec.Token(); err != nil {
+		return syntaxError(err, pointer)
+	}
+	return nil
+}
+
+// jsonFields returns the members encoding/json decodes into a struct of
+// type t, by name.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for f := range t.Fields() {
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func syntaxError(err error, pointer string) *jsonError {
+	if se, ok := errors.AsType[*json.SyntaxError](err); ok {
+		return &jsonError{detail: fmt.Sprintf("malformed JSON at byte %d: %s", se.Offset-1, se), pointer: pointer}
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &jsonError{detail: "unexpected end of JSON input", pointer: pointer}
+	}
+	return &jsonError{detail: "malformed JSON", pointer: pointer}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func describeType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "an RFC 3339 timestamp"
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "an integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "a non-negative integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Struct, reflect.Map:
+		return "an object"
+	}
+	return "a valid " + t.String()
+}
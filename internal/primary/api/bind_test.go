@@ -0,0 +1,164 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type bindTarget struct {
+	Name   string `json:"name"`
+	Anchor struct {
+		Mode string `json:"mode"`
+	} `json:"anchor"`
+	Weeks []int `json:"weeks"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantDetail  string
+		wantPointer string
+	}{
+		{
+			name:        "valid",
+			contentType: "application/json",
+			body:        `{"name":"ada","anchor":{"mode":"birthday"},"weeks":[1,2]}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "charset and +json suffix",
+			contentType: "application/merge-patch+json; charset=UTF-8",
+			body:        `{"name":"ada"}`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "missing content type",
+			body:       `{"name":"ada"}`,
+			wantStatus: http.StatusUnsupportedMediaType,
+			wantDetail: "Content-Type must be application/json",
+		},
+		{
+			name:        "form content type",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `name=ada`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "Content-Type must be application/json",
+		},
+		{
+			name:        "non UTF-8 charset",
+			contentType: "application/json; charset=latin1",
+			body:        `{"name":"ada"}`,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "Content-Type must be application/json",
+		},
+		{
+			name:        "too large",
+			contentType: "application/json",
+			body:        `{"name":"` + strings.Repeat("a", maxJSONBody) + `"}`,
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantDetail:  "request body must not exceed 65536 bytes",
+		},
+		{
+			name:        "empty",
+			contentType: "application/json",
+			body:        "  \n",
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body is empty",
+		},
+		{
+			name:        "not an object",
+			contentType: "application/json",
+			body:        `null`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "request body must be a JSON object",
+		},
+		{
+			name:        "unknown nested field",
+			contentType: "application/json",
+			body:        `{"anchor":{"mode":"birthday","day":3}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "unknown field",
+			wantPointer: "/anchor/day",
+		},
+		{
+			name:        "field names are case-sensitive",
+			contentType: "application/json",
+			body:        `{"Name":"ada"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "unknown field",
+			wantPointer: "/Name",
+		},
+		{
+			name:        "wrong type in array",
+			contentType: "application/json",
+			body:        `{"weeks":[1,"two"]}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "must be an integer",
+			wantPointer: "/weeks/1",
+		},
+		{
+			name:        "duplicate field",
+			contentType: "application/json",
+			body:        `{"name":"ada","name":"bob"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "duplicate field",
+			wantPointer: "/name",
+		},
+		{
+			name:        "trailing data",
+			contentType: "application/json",
+			body:        `{"name":"ada"} {"name":"bob"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "malformed JSON at byte 15: invalid character '{' after top-level value",
+		},
+		{
+			name:        "truncated",
+			contentType: "application/json",
+			body:        `{"anchor":{"mode":`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "unexpected end of JSON input",
+			wantPointer: "/anchor/mode",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+
+			var target bindTarget
+			ok := decodeJSON(rec, req, &target)
+
+			if tt.wantStatus == http.StatusOK {
+				require.True(t, ok, rec.Body.String())
+				assert.Equal(t, "ada", target.Name)
+				return
+			}
+			require.False(t, ok)
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			assert.Equal(t, Problem{
+				Type:    "about:blank",
+				Title:   http.StatusText(tt.wantStatus),
+				Status:  tt.wantStatus,
+				Detail:  tt.wantDetail,
+				Pointer: tt.wantPointer,
+			}, problem)
+		})
+	}
+}
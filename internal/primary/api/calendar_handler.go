@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/app/calendar"
+	"github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/mgwinsor/weekbyweek/internal/primary/ical"
+)
+
+type CalendarHandler struct {
+	calendarService calendar.Service
+	userService     user.Service
+}
+
+// NewCalendarHandler serves calendars from service. Users authenticate
+// imports through users.
+func NewCalendarHandler(service calendar.Service, users user.Service) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: service,
+		userService:     users,
+	}
+}
+
+const maxImportSize = 10 << 20
+
+// RegisterV1 registers the calendar feed and imports of API version 1 on
+// r. The feed is authenticated by the secret token in its URL so that
+// calendar apps can subscribe to it. The token only reads: imports change
+// the calendar and need the user's own credentials.
+func (h *CalendarHandler) RegisterV1(r chi.Router) {
+	r.Get("/calendar/{userID}/{token}.ics", h.handleFeed)
+	r.Route("/calendar/{userID}/imports", func(r chi.Router) {
+		r.Use(requireUser(h.userService, "userID"))
+		r.Post("/", h.handleImport)
+		r.Delete("/{importID}", h.handleUndoImport)
+	})
+}
+
+func (h *CalendarHandler) handleFeed(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	feed, err := h.calendarService.Feed(r.Context(), userID, chi.URLParam(r, "token"))
+	if err != nil {
+		if errors.Is(err, calendar.ErrInvalidFeedToken) {
+			http.NotFound(w, r)
+		} else {
+			slog.ErrorContext(r.Context(), "building calendar feed", "error", err)
+			http.Error(w, "Failed to build calendar", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	cal := ical.Calendar{
+		ProdID: "-//weekbyweek//weekbyweek//EN",
+		Name:   fmt.Sprintf("%s, week by week", feed.Username),
+		Events: make([]ical.Event, 0, len(feed.Events)),
+	}
+	for _, e := range feed.Events {
+		cal.Events = append(cal.Events, ical.Event{
+			UID:         e.UID,
+			Summary:     e.Summary,
+			Description: e.Description,
+			Start:       e.Start,
+			End:         e.End,
+			AllDay:      true,
+			Stamp:       feed.GeneratedAt,
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	if err := ical.Encode(w, cal); err != nil {
+		slog.ErrorContext(r.Context(), "writing calendar feed", "error", err)
+	}
+}
+
+func (h *CalendarHandler) handleImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, "Calendar file too large", http.StatusRequestEntityTooLarge)
+		} else {
+			http.Error(w, "Missing calendar file", http.StatusBadRequest)
+		}
+		return
+	}
+	defer file.Close()
+
+	cal, err := ical.Decode(file)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	req := calendar.ImportRequest{
+		UserID: userID,
+		DryRun: dryRun,
+		Events: make([]calendar.ImportEvent, 0, len(cal.Events)),
+	}
+	for _, e := range cal.Events {
+		req.Events = append(req.Events, newImportEvent(e))
+	}
+
+	importResponse, err := h.calendarService.ImportEvents(r.Context(), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, calendar.ErrUserNotFound):
+			http.NotFound(w, r)
+		case errors.Is(err, calendar.ErrImportTooLarge):
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		default:
+			slog.ErrorContext(r.Context(), "importing calendar", "error", err)
+			http.Error(w, "Failed to import calendar", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	status := http.StatusCreated
+	if dryRun {
+		status = http.StatusOK
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(newImportResponseV1(importResponse))
+}
+
+func newImportEvent(e ical.Event) calendar.ImportEvent {
+	return calendar.ImportEvent{
+		UID:         e.UID,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Start:       e.Start,
+		Occurrences: func(until time.Time, limit int) ([]time.Time, error) {
+			starts, err := e.Occurrences(until, limit)
+			if errors.Is(err, ical.ErrTooManyOccurrences) {
+				return nil, calendar.ErrImportTooLarge
+			}
+			return starts, err
+		},
+	}
+}
+
+func (h *CalendarHandler) handleUndoImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	importID, err := uuid.Parse(chi.URLParam(r, "importID"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := h.calendarService.UndoImport(r.Context(), userID, importID); err != nil {
+		switch {
+		case errors.Is(err, calendar.ErrImportNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			slog.ErrorContext(r.Context(), "undoing calendar import", "error", err)
+			http.Error(w, "Failed to undo import", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,375 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/app/calendar"
+	"github.com/mgwinsor/weekbyweek/internal/primary/ical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCalendarService struct {
+	mock.Mock
+}
+
+func (m *MockCalendarService) Feed(ctx context.Context, userID uuid.UUID, token string) (*calendar.FeedResponse, error) {
+	args := m.Called(ctx, userID, token)
+
+	var resp *calendar.FeedResponse
+	if args.Get(0) != nil {
+		resp = args.Get(0).(*calendar.FeedResponse)
+	}
+
+	return resp, args.Error(1)
+}
+
+func (m *MockCalendarService) ImportEvents(ctx context.Context, req calendar.ImportRequest) (*calendar.ImportResponse, error) {
+	args := m.Called(ctx, req)
+
+	var resp *calendar.ImportResponse
+	if args.Get(0) != nil {
+		resp = args.Get(0).(*calendar.ImportResponse)
+	}
+
+	return resp, args.Error(1)
+}
+
+func (m *MockCalendarService) UndoImport(ctx context.Context, userID, importID uuid.UUID) error {
+	args := m.Called(ctx, userID, importID)
+	return args.Error(0)
+}
+
+// authenticatedAs stands in for the user service, knowing only the user
+// with id, who signs in as john@example.com.
+func authenticatedAs(id uuid.UUID) *MockUserService {
+	users := new(MockUserService)
+	users.On("Authenticate", mock.Anything, "john@example.com", "correct horse").Return(id, nil).Maybe()
+	return users
+}
+
+func TestHandleFeed(t *testing.T) {
+	id, _ := uuid.Parse("4762e4fb-b6bd-487d-834d-7a8c20c78be9")
+	feed := &calendar.FeedResponse{
+		UserID:      id,
+		Username:    "johndoe",
+		GeneratedAt: time.Date(2025, time.August, 1, 12, 0, 0, 0, time.UTC),
+		Events: []calendar.Event{
+			{
+				UID:     "week-1706@weekbyweek",
+				Summary: "Week 1706",
+				Start:   time.Date(2025, time.July, 26, 0, 0, 0, 0, time.UTC),
+				End:     time.Date(2025, time.August, 2, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	tests := []struct {
+		name               string
+		path               string
+		mockSetup          func(m *MockCalendarService)
+		expectedStatusCode int
+		expectedContent    []string
+	}{
+		{
+			name: "successfully serve feed",
+			path: "/calendar/" + id.String() + "/secret.ics",
+			mockSetup: func(m *MockCalendarService) {
+				m.On("Feed", mock.Anything, id, "secret").
+					Return(feed, nil).
+					Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedContent: []string{
+				"BEGIN:VCALENDAR\r\n",
+				"X-WR-CALNAME:johndoe\\, week by week\r\n",
+				"DTSTART;VALUE=DATE:20250726\r\n",
+				"SUMMARY:Week 1706\r\n",
+			},
+		},
+		{
+			name: "invalid token",
+			path: "/calendar/" + id.String() + "/wrong.ics",
+			mockSetup: func(m *MockCalendarService) {
+				m.On("Feed", mock.Anything, id, "wrong").
+					Return(nil, calendar.ErrInvalidFeedToken).
+					Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "malformed user id",
+			path:               "/calendar/not-a-uuid/secret.ics",
+			mockSetup:          func(m *MockCalendarService) {},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name: "service failure",
+			path: "/calendar/" + id.String() + "/secret.ics",
+			mockSetup: func(m *MockCalendarService) {
+				m.On("Feed", mock.Anything, id, "secret").
+					Return(nil, errors.New("boom")).
+					Once()
+			},
+			expectedStatusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCalendarService)
+			tt.mockSetup(mockService)
+
+			handler := NewCalendarHandler(mockService, new(MockUserService))
+			router := chi.NewRouter()
+			handler.RegisterV1(router)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+
+			body, err := io.ReadAll(rr.Body)
+			require.NoError(t, err)
+			if tt.expectedStatusCode == http.StatusOK {
+				assert.Equal(t, "text/calendar; charset=utf-8", rr.Header().Get("Content-Type"))
+			}
+			for _, content := range tt.expectedContent {
+				assert.Contains(t, string(body), content)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func newImportBody(t *testing.T, ics string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	if ics != "" {
+		fw, err := mw.CreateFormFile("file", "history.ics")
+		require.NoError(t, err)
+		_, err = io.WriteString(fw, ics)
+		require.NoError(t, err)
+	}
+	require.NoError(t, mw.Close())
+
+	return &body, mw.FormDataContentType()
+}
+
+func TestHandleImport(t *testing.T) {
+	id, _ := uuid.Parse("4762e4fb-b6bd-487d-834d-7a8c20c78be9")
+	importID, _ := uuid.Parse("0b0e3ad1-5d4e-4d43-9c8b-7e0f0f0b6f5e")
+
+	validICS := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:grad@example",
+		"DTSTART;VALUE=DATE:20140612",
+		"SUMMARY:Graduated",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:book-club@example",
+		"DTSTART;VALUE=DATE:20150105",
+		"RRULE:FREQ=WEEKLY;COUNT=2",
+		"SUMMARY:Book club",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:odd@example",
+		"DTSTART;VALUE=DATE:20150105",
+		"RRULE:FREQ=MONTHLY;BYSETPOS=-1",
+		"SUMMARY:Odd rule",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	// expectedRequest matches the request for validICS by the start times its
+	// events expand to.
+	expectedRequest := func(dryRun bool) any {
+		type occurrence struct {
+			UID   string
+			Start time.Time
+		}
+		expected := []occurrence{
+			{UID: "grad@example", Start: time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC)},
+			{UID: "book-club@example", Start: time.Date(2015, time.January, 5, 0, 0, 0, 0, time.UTC)},
+			{UID: "book-club@example", Start: time.Date(2015, time.January, 12, 0, 0, 0, 0, time.UTC)},
+		}
+		until := time.Date(2025, time.August, 1, 0, 0, 0, 0, time.UTC)
+
+		return mock.MatchedBy(func(req calendar.ImportRequest) bool {
+			if req.UserID != id || req.DryRun != dryRun || len(req.Events) != 3 {
+				return false
+			}
+			var got []occurrence
+			for _, e := range req.Events[:2] {
+				starts, err := e.Occurrences(until, calendar.MaxImportOccurrences)
+				if err != nil {
+					return false
+				}
+				for _, start := range starts {
+					got = append(got, occurrence{UID: e.UID, Start: start})
+				}
+			}
+			_, err := req.Events[2].Occurrences(until, calendar.MaxImportOccurrences)
+			return assert.ObjectsAreEqual(expected, got) && errors.Is(err, ical.ErrUnsupportedRecurrence)
+		})
+	}
+
+	tests := []struct {
+		name               string
+		path               string
+		anonymous          bool
+		ics                string
+		mockSetup          func(m *MockCalendarService)
+		expectedStatusCode int
+	}{
+		{
+			name: "dry run",
+			path: "/calendar/" + id.String() + "/imports?dryRun=true",
+			ics:  validICS,
+			mockSetup: func(m *MockCalendarService) {
+				m.On("ImportEvents", mock.Anything, expectedRequest(true)).
+					Return(&calendar.ImportResponse{DryRun: true}, nil).
+					Once()
+			},
+			expectedStatusCode: http.StatusOK,
+		},
+		{
+			name: "import",
+			path: "/calendar/" + id.String() + "/imports",
+			ics:  validICS,
+			mockSetup: func(m *MockCalendarService) {
+				m.On("ImportEvents", mock.Anything, expectedRequest(false)).
+					Return(&calendar.ImportResponse{ImportID: importID}, nil).
+					Once()
+			},
+			expectedStatusCode: http.StatusCreated,
+		},
+		{
+			name:               "missing file",
+			path:               "/calendar/" + id.String() + "/imports",
+			mockSetup:          func(m *MockCalendarService) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "malformed calendar",
+			path:               "/calendar/" + id.String() + "/imports",
+			ics:                "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\n",
+			mockSetup:          func(m *MockCalendarService) {},
+			expectedStatusCode: http.StatusBadRequest,
+		},
+		{
+			name:               "without credentials",
+			path:               "/calendar/" + id.String() + "/imports",
+			anonymous:          true,
+			ics:                validICS,
+			mockSetup:          func(m *MockCalendarService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+		},
+		{
+			name:               "feed token in place of credentials",
+			path:               "/calendar/" + id.String() + "/secret/imports",
+			ics:                validICS,
+			mockSetup:          func(m *MockCalendarService) {},
+			expectedStatusCode: http.StatusNotFound,
+		},
+		{
+			name:               "another user's calendar",
+			path:               "/calendar/" + uuid.NewString() + "/imports",
+			ics:                validICS,
+			mockSetup:          func(m *MockCalendarService) {},
+			expectedStatusCode: http.StatusForbidden,
+		},
+		{
+			name: "user gone",
+			path: "/calendar/" + id.String() + "/imports",
+			ics:  validICS,
+			mockSetup: func(m *MockCalendarService) {
+				m.On("ImportEvents", mock.Anything, mock.Anything).
+					Return(nil, calendar.ErrUserNotFound).
+					Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCalendarService)
+			tt.mockSetup(mockService)
+
+			handler := NewCalendarHandler(mockService, authenticatedAs(id))
+			router := chi.NewRouter()
+			handler.RegisterV1(router)
+
+			body, contentType := newImportBody(t, tt.ics)
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			req.Header.Set("Content-Type", contentType)
+			if !tt.anonymous {
+				req.SetBasicAuth("john@example.com", "correct horse")
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleUndoImport(t *testing.T) {
+	id, _ := uuid.Parse("4762e4fb-b6bd-487d-834d-7a8c20c78be9")
+	importID, _ := uuid.Parse("0b0e3ad1-5d4e-4d43-9c8b-7e0f0f0b6f5e")
+
+	tests := []struct {
+		name               string
+		anonymous          bool
+		mockErr            error
+		expectedStatusCode int
+	}{
+		{name: "undo import", mockErr: nil, expectedStatusCode: http.StatusNoContent},
+		{name: "without credentials", anonymous: true, expectedStatusCode: http.StatusUnauthorized},
+		{name: "unknown import", mockErr: calendar.ErrImportNotFound, expectedStatusCode: http.StatusNotFound},
+		{name: "service failure", mockErr: errors.New("boom"), expectedStatusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCalendarService)
+			if !tt.anonymous {
+				mockService.On("UndoImport", mock.Anything, id, importID).Return(tt.mockErr).Once()
+			}
+
+			handler := NewCalendarHandler(mockService, authenticatedAs(id))
+			router := chi.NewRouter()
+			handler.RegisterV1(router)
+
+			path := "/calendar/" + id.String() + "/imports/" + importID.String()
+			req := httptest.NewRequest(http.MethodDelete, path, nil)
+			if !tt.anonymous {
+				req.SetBasicAuth("john@example.com", "correct horse")
+			}
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/app/calendar"
+	"github.com/mgwinsor/weekbyweek/internal/app/user"
+)
+
+// The v1 DTOs are the wire format of API version 1. They are mapped to and
+// from the application DTOs, so that the application can change without
+// changing what v1 clients send and receive.
+
+type createUserRequestV1 struct {
+	Email       string       `json:"email"`
+	Username    string       `json:"username"`
+	Password    string       `json:"password"`
+	DateOfBirth time.Time    `json:"dob"`
+	WeekAnchor  weekAnchorV1 `json:"weekAnchor,omitzero"`
+	TimeZone    string       `json:"timeZone,omitempty"`
+}
+
+func (r createUserRequestV1) toApp() user.CreateUserRequest {
+	return user.CreateUserRequest{
+		Email:       r.Email,
+		Username:    r.Username,
+		Password:    r.Password,
+		DateOfBirth: r.DateOfBirth,
+		WeekAnchor:  r.WeekAnchor.toApp(),
+		TimeZone:    r.TimeZone,
+	}
+}
+
+type createUserResponseV1 struct {
+	ID            uuid.UUID    `json:"id"`
+	Email         string       `json:"email"`
+	Username      string       `json:"username"`
+	DateOfBirth   time.Time    `json:"dob"`
+	WeekAnchor    weekAnchorV1 `json:"weekAnchor"`
+	TimeZone      string       `json:"timeZone"`
+	CalendarToken string       `json:"calendarToken"`
+}
+
+func newCreateUserResponseV1(r *user.CreateUserResponse) createUserResponseV1 {
+	return createUserResponseV1{
+		ID:            r.ID,
+		Email:         r.Email,
+		Username:      r.Username,
+		DateOfBirth:   r.DateOfBirth,
+		WeekAnchor:    newWeekAnchorV1(r.WeekAnchor),
+		TimeZone:      r.TimeZone,
+		CalendarToken: r.CalendarToken,
+	}
+}
+
+type updateProfileRequestV1 struct {
+	TimeZone   *string       `json:"timeZone,omitempty"`
+	WeekAnchor *weekAnchorV1 `json:"weekAnchor,omitempty"`
+}
+
+func (r updateProfileRequestV1) toApp() user.UpdateProfileRequest {
+	req := user.UpdateProfileRequest{TimeZone: r.TimeZone}
+	if r.WeekAnchor != nil {
+		anchor := r.WeekAnchor.toApp()
+		req.WeekAnchor = &anchor
+	}
+	return req
+}
+
+type profileResponseV1 struct {
+	ID          uuid.UUID    `json:"id"`
+	Email       string       `json:"email"`
+	Username    string       `json:"username"`
+	DateOfBirth time.Time    `json:"dob"`
+	WeekAnchor  weekAnchorV1 `json:"weekAnchor"`
+	TimeZone    string       `json:"timeZone"`
+	CurrentWeek int          `json:"currentWeek,omitempty"`
+}
+
+func newProfileResponseV1(r *user.ProfileResponse) profileResponseV1 {
+	return profileResponseV1{
+		ID:          r.ID,
+		Email:       r.Email,
+		Username:    r.Username,
+		DateOfBirth: r.DateOfBirth,
+		WeekAnchor:  newWeekAnchorV1(r.WeekAnchor),
+		TimeZone:    r.TimeZone,
+		CurrentWeek: r.CurrentWeek,
+	}
+}
+
+type weekAnchorV1 struct {
+	Mode         string `json:"mode"`
+	FirstWeekday string `json:"firstWeekday,omitempty"`
+}
+
+func (a weekAnchorV1) toApp() user.WeekAnchor {
+	return user.WeekAnchor{Mode: a.Mode, FirstWeekday: a.FirstWeekday}
+}
+
+func newWeekAnchorV1(a user.WeekAnchor) weekAnchorV1 {
+	return weekAnchorV1{Mode: a.Mode, FirstWeekday: a.FirstWeekday}
+}
+
+type importResponseV1 struct {
+	ImportID uuid.UUID       `json:"importId"`
+	DryRun   bool            `json:"dryRun"`
+	Items    []importItemV1  `json:"items"`
+	Skipped  []skippedItemV1 `json:"skipped"`
+}
+
+type importItemV1 struct {
+	Title string    `json:"title"`
+	Date  time.Time `json:"date"`
+	Week  int       `json:"week"`
+}
+
+type skippedItemV1 struct {
+	Title  string    `json:"title"`
+	Date   time.Time `json:"date,omitzero"`
+	Reason string    `json:"reason"`
+}
+
+func newImportResponseV1(r *calendar.ImportResponse) importResponseV1 {
+	resp := importResponseV1{ImportID: r.ImportID, DryRun: r.DryRun}
+	for _, item := range r.Items {
+		resp.Items = append(resp.Items, importItemV1{Title: item.Title, Date: item.Date, Week: item.Week})
+	}
+	for _, item := range r.Skipped {
+		resp.Skipped = append(resp.Skipped, skippedItemV1{Title: item.Title, Date: item.Date, Reason: item.Reason})
+	}
+	return resp
+}
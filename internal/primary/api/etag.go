@@ -0,0 +1,85 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mgwinsor/weekbyweek/internal/app/user"
+)
+
+// profileETag identifies a profile representation by the stored version
+// and, since the representation includes it, the current week.
+func profileETag(p *user.ProfileResponse) string {
+	tag := p.Tag()
+	return fmt.Sprintf(`"%d-%d"`, tag.Version, tag.Week)
+}
+
+// etagList splits a comma-separated If-Match or If-None-Match header.
+func etagList(r *http.Request, name string) []string {
+	var tags []string
+	for _, value := range r.Header.Values(name) {
+		for tag := range strings.SplitSeq(value, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+// noneMatch reports whether If-None-Match rules out sending etag again,
+// comparing weakly as RFC 9110 requires.
+func noneMatch(r *http.Request, etag string) bool {
+	for _, tag := range etagList(r, "If-None-Match") {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchTags returns the profile tags If-Match allows. It returns nil if
+// the header is absent or "*", and reports false if it lists only tags
+// that cannot match any profile.
+func ifMatchTags(r *http.Request) ([]user.ProfileTag, bool) {
+	tags := etagList(r, "If-Match")
+	var profiles []user.ProfileTag
+	for _, tag := range tags {
+		if tag == "*" {
+			return nil, true
+		}
+		if p, ok := parseProfileETag(tag); ok {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles, len(tags) == 0 || len(profiles) > 0
+}
+
+// parseProfileETag undoes profileETag. Weak tags are refused: they never
+// match under the strong comparison If-Match uses.
+func parseProfileETag(tag string) (user.ProfileTag, bool) {
+	opaque, ok := strings.CutPrefix(tag, `"`)
+	if !ok {
+		return user.ProfileTag{}, false
+	}
+	opaque, ok = strings.CutSuffix(opaque, `"`)
+	if !ok {
+		return user.ProfileTag{}, false
+	}
+	version, week, ok := strings.Cut(opaque, "-")
+	if !ok {
+		return user.ProfileTag{}, false
+	}
+	v, err := strconv.ParseInt(version, 10, 64)
+	if err != nil {
+		return user.ProfileTag{}, false
+	}
+	w, err := strconv.Atoi(week)
+	if err != nil {
+		return user.ProfileTag{}, false
+	}
+	p := user.ProfileTag{Version: v, Week: w}
+	return p, profileETag(&user.ProfileResponse{Version: v, CurrentWeek: w}) == tag
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/health"
+)
+
+type HealthHandler struct {
+	registry *health.Registry
+}
+
+func NewHealthHandler(registry *health.Registry) *HealthHandler {
+	return &HealthHandler{
+		registry: registry,
+	}
+}
+
+func (h *HealthHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/healthz", h.handleLiveness)
+	r.Get("/readyz", h.handleReadiness)
+}
+
+// handleLiveness only reports that the process is serving requests. It
+// deliberately ignores dependencies, so that an outage of, say, the mail
+// server does not get the process restarted.
+func (h *HealthHandler) handleLiveness(w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, http.StatusOK, health.Report{Status: health.StatusOK})
+}
+
+func (h *HealthHandler) handleReadiness(w http.ResponseWriter, r *http.Request) {
+	report := h.registry.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	writeHealth(w, status, report)
+}
+
+func writeHealth(w http.ResponseWriter, status int, report health.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
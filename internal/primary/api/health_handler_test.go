@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler(t *testing.T) {
+	storageDown := func(context.Context) error { return errors.New("database is closed") }
+	up := func(context.Context) error { return nil }
+
+	tests := []struct {
+		name         string
+		path         string
+		storage      health.CheckFunc
+		shuttingDown bool
+		wantStatus   int
+		wantBody     health.Report
+	}{
+		{
+			name:       "liveness ignores failing dependencies",
+			path:       "/healthz",
+			storage:    storageDown,
+			wantStatus: http.StatusOK,
+			wantBody:   health.Report{Status: health.StatusOK},
+		},
+		{
+			name:       "ready",
+			path:       "/readyz",
+			storage:    up,
+			wantStatus: http.StatusOK,
+			wantBody: health.Report{Status: health.StatusOK, Checks: map[string]health.Result{
+				"storage": {Status: health.StatusOK},
+			}},
+		},
+		{
+			name:       "not ready when a dependency fails",
+			path:       "/readyz",
+			storage:    storageDown,
+			wantStatus: http.StatusServiceUnavailable,
+			wantBody: health.Report{Status: health.StatusFail, Checks: map[string]health.Result{
+				"storage": {Status: health.StatusFail, Error: "database is closed"},
+			}},
+		},
+		{
+			name:         "not ready while shutting down",
+			path:         "/readyz",
+			storage:      up,
+			shuttingDown: true,
+			wantStatus:   http.StatusServiceUnavailable,
+			wantBody: health.Report{Status: health.StatusFail, Checks: map[string]health.Result{
+				"storage":  {Status: health.StatusOK},
+				"shutdown": {Status: health.StatusFail, Error: health.ErrShuttingDown.Error()},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := health.NewRegistry(time.Second)
+			registry.Register(health.Check{Name: "storage", Run: tt.storage})
+			if tt.shuttingDown {
+				registry.SetShuttingDown()
+			}
+			r := chi.NewRouter()
+			NewHealthHandler(registry).RegisterRoutes(r)
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+
+			var got health.Report
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+			for name, result := range got.Checks {
+				result.Duration = 0
+				got.Checks[name] = result
+			}
+			assert.Equal(t, tt.wantBody, got)
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/mgwinsor/weekbyweek/internal/idempotency"
+)
+
+const (
+	IdempotencyKeyHeader      = "Idempotency-Key"
+	IdempotentReplayedHeader  = "Idempotent-Replayed"
+	maxIdempotencyKey         = 255
+	maxIdempotentRequestBody  = maxImportSize + 1<<20
+	maxIdempotentResponseBody = 1 << 20
+	idempotencyPollInterval   = 50 * time.Millisecond
+)
+
+// replayedHeaders are the response headers that describe the response
+// itself rather than this particular exchange, and so are stored.
+var replayedHeaders = []string{"Content-Type", "Content-Location", "Location", "ETag", "Last-Modified", "Cache-Control"}
+
+type Idempotency struct {
+	store  idempotency.Store
+	policy idempotency.Policy
+	now    func() time.Time
+}
+
+func NewIdempotency(store idempotency.Store, policy idempotency.Policy) *Idempotency {
+	return &Idempotency{store: store, policy: policy, now: time.Now}
+}
+
+// Middleware makes POST and PATCH requests that carry an Idempotency-Key
+// safe to retry. The first response to a key is stored and replayed to
+// retries with the same body; a different body gets 422. A retry of a
+// request that is still in flight waits for it, then gets 409. Server
+// errors are not stored, so that they can be retried. A failing store lets
+// requests through unprotected.
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || (r.Method != http.MethodPost && r.Method != http.MethodPatch) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeProblem(w, http.StatusBadRequest, "Idempotency-Key must not exceed 255 characters", "")
+			return
+		}
+
+		body, ok := readIdempotentBody(r)
+		if !ok {
+			// Too large for the handler to accept anyway.
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := r.Context()
+		storeKey := idempotencyStoreKey(r, key)
+		fingerprint := idempotency.Fingerprint(body)
+
+		deadline := i.now().Add(i.policy.Wait)
+		for {
+			now := i.now()
+			rec, claim, err := i.store.Claim(ctx, storeKey, fingerprint, now, now.Add(i.policy.Lease))
+			if err != nil {
+				slog.ErrorContext(ctx, "idempotency store failed", "error", err)
+				next.ServeHTTP(w, r)
+				return
+			}
+			switch {
+			case claim != "":
+				i.serve(w, r, next, storeKey, claim)
+				return
+			case rec.Fingerprint != fingerprint:
+				writeProblem(w, http.StatusUnprocessableEntity,
+					"Idempotency-Key was already used for a different request", "")
+				return
+			case rec.Response != nil:
+				replay(w, *rec.Response)
+				return
+			case !now.Before(deadline):
+				w.Header().Set("Retry-After", "1")
+				writeProblem(w, http.StatusConflict,
+					"a request with this Idempotency-Key is still being processed", "")
+				return
+			}
+
+			timer := time.NewTimer(min(idempotencyPollInterval, deadline.Sub(now)))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	})
+}
+
+// serve runs the claimed request and stores its response. The claim is
+// released if the handler fails, panics or writes more than can be stored.
+func (i *Idempotency) serve(w http.ResponseWriter, r *http.Request, next http.Handler, storeKey, claim string) {
+	ctx := context.WithoutCancel(r.Context())
+	completed := false
+	defer func() {
+		if !completed {
+			if err := i.store.Release(ctx, storeKey, claim); err != nil {
+				slog.ErrorContext(ctx, "releasing idempotency key", "error", err)
+			}
+		}
+	}()
+
+	var body bytes.Buffer
+	ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+	ww.Tee(&body)
+	next.ServeHTTP(ww, r)
+
+	status := ww.Status()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status >= http.StatusInternalServerError || body.Len() > maxIdempotentResponseBody {
+		return
+	}
+
+	resp := idempotency.Response{Status: status, Header: http.Header{}, Body: body.Bytes()}
+	for _, name := range replayedHeaders {
+		if values := w.Header().Values(name); len(values) > 0 {
+			resp.Header[name] = values
+		}
+	}
+	if err := i.store.Complete(ctx, storeKey, claim, resp, i.now().Add(i.policy.TTL)); err != nil {
+		slog.ErrorContext(ctx, "storing idempotent response", "error", err)
+		return
+	}
+	completed = true
+}
+
+func replay(w http.ResponseWriter, resp idempotency.Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+// readIdempotentBody reads the body for fingerprinting and restores it for
+// the handler. It reports false if the body is too large to fingerprint.
+func readIdempotentBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil {
+		return nil, true
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentRequestBody+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	return data, err == nil && len(data) <= maxIdempotentRequestBody
+}
+
+// idempotencyStoreKey scopes keys to the request target, query included,
+// so that clients cannot collide across endpoints or, say, a dry run with
+// the request it previews.
+func idempotencyStoreKey(r *http.Request, key string) string {
+	sum := sha256.Sum256([]byte(r.Method + " " + r.URL.RequestURI() + "\n" + key))
+	return "idempotency:" + hex.EncodeToString(sum[:16])
+}
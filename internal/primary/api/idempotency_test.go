@@ -0,0 +1,294 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/idempotency"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingIdempotencyStore struct{}
+
+func (failingIdempotencyStore) Claim(ctx context.Context, key, fingerprint string, now, until time.Time) (idempotency.Record, string, error) {
+	return idempotency.Record{}, "", errors.New("store down")
+}
+
+func (failingIdempotencyStore) Complete(ctx context.Context, key, claim string, resp idempotency.Response, expires time.Time) error {
+	return errors.New("store down")
+}
+
+func (failingIdempotencyStore) Release(ctx context.Context, key, claim string) error {
+	return errors.New("store down")
+}
+
+var testIdempotencyPolicy = idempotency.Policy{TTL: time.Hour, Wait: 0, Lease: time.Minute}
+
+// newIdempotentRouter serves POST /users, answering 201 with a sequence
+// number so that replays can be told from fresh responses, or with the
+// status in the X-Status request header.
+func newIdempotentRouter(store idempotency.Store, policy idempotency.Policy, handler http.HandlerFunc) (chi.Router, *atomic.Int32) {
+	var calls atomic.Int32
+	if handler == nil {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			n := calls.Add(1)
+			status := http.StatusCreated
+			fmt.Sscan(r.Header.Get("X-Status"), &status)
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Location", fmt.Sprintf("/users/%d", n))
+			w.Header().Set("X-Per-Response", "yes")
+			w.WriteHeader(status)
+			fmt.Fprintf(w, `{"n":%d}`, n)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Use(NewIdempotency(store, policy).Middleware)
+	r.Post("/users", handler)
+	r.Get("/users", handler)
+	return r, &calls
+}
+
+func post(r http.Handler, key, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestIdempotency(t *testing.T) {
+	t.Run("replays the first response to retries", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		first := post(r, "key-1", `{"email":"ada@example.com"}`)
+		retry := post(r, "key-1", `{"email":"ada@example.com"}`)
+
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "application/json", retry.Header().Get("Content-Type"))
+		assert.Equal(t, "/users/1", retry.Header().Get("Location"))
+		assert.Empty(t, retry.Header().Get("X-Per-Response"))
+		assert.Equal(t, "true", retry.Header().Get(IdempotentReplayedHeader))
+		assert.Empty(t, first.Header().Get(IdempotentReplayedHeader))
+	})
+
+	t.Run("rejects a reused key with a different body", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		post(r, "key-1", `{"email":"ada@example.com"}`)
+		rec := post(r, "key-1", `{"email":"grace@example.com"}`)
+
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+		assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+	})
+
+	t.Run("keys are independent and optional", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		post(r, "key-1", `{}`)
+		post(r, "key-2", `{}`)
+		post(r, "", `{}`)
+		post(r, "", `{}`)
+
+		assert.Equal(t, int32(4), calls.Load())
+	})
+
+	t.Run("keys are scoped to the query too", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		for _, target := range []string{"/users?dryRun=true", "/users"} {
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(`{}`))
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			assert.Empty(t, rec.Header().Get(IdempotentReplayedHeader), target)
+		}
+
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("only applies to POST and PATCH", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		for range 2 {
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Header.Set(IdempotencyKeyHeader, "key-1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+		}
+
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("client errors are replayed", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		post(r, "key-1", `{}`, "X-Status", "409")
+		rec := post(r, "key-1", `{}`)
+
+		assert.Equal(t, int32(1), calls.Load())
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+
+	t.Run("server errors are not stored", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		post(r, "key-1", `{}`, "X-Status", "503")
+		rec := post(r, "key-1", `{}`)
+
+		assert.Equal(t, int32(2), calls.Load())
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	})
+
+	t.Run("a panicking handler releases its key", func(t *testing.T) {
+		var calls atomic.Int32
+		r, _ := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, func(w http.ResponseWriter, r *http.Request) {
+			if calls.Add(1) == 1 {
+				panic("boom")
+			}
+			w.WriteHeader(http.StatusCreated)
+		})
+
+		assert.Panics(t, func() { post(r, "key-1", `{}`) })
+		rec := post(r, "key-1", `{}`)
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("keys expire", func(t *testing.T) {
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		store := memory.NewIdempotencyStore()
+		idem := NewIdempotency(store, testIdempotencyPolicy)
+		idem.now = func() time.Time { return now }
+		var calls atomic.Int32
+		r := chi.NewRouter()
+		r.Use(idem.Middleware)
+		r.Post("/users", func(w http.ResponseWriter, r *http.Request) { calls.Add(1) })
+
+		post(r, "key-1", `{}`)
+		now = now.Add(testIdempotencyPolicy.TTL - time.Second)
+		post(r, "key-1", `{}`)
+		assert.Equal(t, int32(1), calls.Load())
+
+		now = now.Add(time.Second)
+		post(r, "key-1", `{"changed":true}`)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("rejects overlong keys", func(t *testing.T) {
+		r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), testIdempotencyPolicy, nil)
+
+		rec := post(r, strings.Repeat("k", 256), `{}`)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, int32(0), calls.Load())
+	})
+
+	t.Run("a failing store lets requests through", func(t *testing.T) {
+		r, calls := newIdempotentRouter(failingIdempotencyStore{}, testIdempotencyPolicy, nil)
+
+		assert.Equal(t, http.StatusCreated, post(r, "key-1", `{}`).Code)
+		assert.Equal(t, http.StatusCreated, post(r, "key-1", `{}`).Code)
+		assert.Equal(t, int32(2), calls.Load())
+	})
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	tests := []struct {
+		name       string
+		wait       time.Duration
+		wantStatus int
+		wantCalls  int32
+	}{
+		{
+			name:       "retry gets 409 without waiting",
+			wait:       0,
+			wantStatus: http.StatusConflict,
+			wantCalls:  1,
+		},
+		{
+			name:       "retry waits for the first request and replays it",
+			wait:       5 * time.Second,
+			wantStatus: http.StatusCreated,
+			wantCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			started, finish := make(chan struct{}), make(chan struct{})
+			var calls atomic.Int32
+			policy := testIdempotencyPolicy
+			policy.Wait = tt.wait
+			r, _ := newIdempotentRouter(memory.NewIdempotencyStore(), policy, func(w http.ResponseWriter, r *http.Request) {
+				calls.Add(1)
+				close(started)
+				<-finish
+				w.WriteHeader(http.StatusCreated)
+			})
+
+			var wg sync.WaitGroup
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				post(r, "key-1", `{}`)
+			}()
+			<-started
+
+			if tt.wait > 0 {
+				time.AfterFunc(100*time.Millisecond, func() { close(finish) })
+			}
+			rec := post(r, "key-1", `{}`)
+			if tt.wait == 0 {
+				close(finish)
+				assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+			}
+			wg.Wait()
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, tt.wantCalls, calls.Load())
+		})
+	}
+}
+
+func TestIdempotency_ConcurrentRetries(t *testing.T) {
+	policy := testIdempotencyPolicy
+	policy.Wait = 5 * time.Second
+	r, calls := newIdempotentRouter(memory.NewIdempotencyStore(), policy, nil)
+
+	var wg sync.WaitGroup
+	bodies := make([]string, 20)
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := post(r, "key-1", `{"email":"ada@example.com"}`)
+			assert.Equal(t, http.StatusCreated, rec.Code)
+			bodies[i] = rec.Body.String()
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), calls.Load())
+	for _, body := range bodies {
+		assert.Equal(t, `{"n":1}`, body)
+	}
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// captureLogs routes the default logger into a buffer for the duration of
+// the test.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(logging.New(&buf, slog.LevelDebug, "json"))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func logRecords(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for line := range strings.SplitSeq(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func newMiddlewareRouter() chi.Router {
+	r := chi.NewRouter()
+	r.Use(RequestID)
+	r.Use(RequestLogger)
+	r.Use(Recoverer)
+	r.Get("/calendar/{userID}/{token}.ics", func(w http.ResponseWriter, r *http.Request) {
+		slog.InfoContext(r.Context(), "handling")
+		w.Write([]byte("ok"))
+	})
+	r.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	return r
+}
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "generated when missing", incoming: "", keep: false},
+		{name: "kept when well formed", incoming: "trace-123", keep: true},
+		{name: "replaced when malformed", incoming: "bad id\x7f", keep: false},
+		{name: "replaced when too long", incoming: strings.Repeat("a", 200), keep: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.incoming != "" {
+				req.Header.Set(RequestIDHeader, tt.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			assert.NotEmpty(t, seen)
+			assert.Equal(t, seen, rr.Header().Get(RequestIDHeader))
+			assert.Equal(t, tt.keep, seen == tt.incoming)
+		})
+	}
+}
+
+func TestRequestLogger(t *testing.T) {
+	logs := captureLogs(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/calendar/123/secret-token.ics", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	rr := httptest.NewRecorder()
+	newMiddlewareRouter().ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.NotContains(t, logs.String(), "secret-token")
+
+	records := logRecords(t, logs)
+	require.Len(t, records, 2, "each request is logged exactly once")
+
+	assert.Equal(t, "handling", records[0]["msg"])
+	assert.Equal(t, "req-42", records[0]["request_id"])
+
+	access := records[1]
+	assert.Equal(t, "request", access["msg"])
+	assert.Equal(t, "req-42", access["request_id"])
+	assert.Equal(t, "GET", access["method"])
+	assert.Equal(t, "/calendar/{userID}/{token}.ics", access["route"])
+	assert.Equal(t, float64(http.StatusOK), access["status"])
+}
+
+func TestRecoverer(t *testing.T) {
+	logs := captureLogs(t)
+
+	rr := httptest.NewRecorder()
+	newMiddlewareRouter().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/panic", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+
+	records := logRecords(t, logs)
+	require.Len(t, records, 2)
+	assert.Equal(t, "handler panicked", records[0]["msg"])
+	assert.Equal(t, "boom", records[0]["panic"])
+	assert.Equal(t, "ERROR", records[1]["level"])
+	assert.Equal(t, float64(http.StatusInternalServerError), records[1]["status"])
+}
@@ -0,0 +1,429 @@
+package api
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var (
+	//go:embed openapi.json
+	openAPIDocument []byte
+	//go:embed openapi.html
+	openAPIViewer []byte
+)
+
+const openAPIURL = "openapi.json"
+
+var errUndocumented = errors.New("not described by the OpenAPI document")
+
+type OpenAPIHandler struct{}
+
+func NewOpenAPIHandler() *OpenAPIHandler {
+	return &OpenAPIHandler{}
+}
+
+// RegisterRoutes serves the OpenAPI document and a viewer for it.
+func (h *OpenAPIHandler) RegisterRoutes(r chi.Router) {
+	r.Get("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(openAPIDocument)
+	})
+	r.Get("/docs", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(openAPIViewer)
+	})
+}
+
+// OpenAPIValidator checks requests, and in tests responses, against the
+// OpenAPI document.
+type OpenAPIValidator struct {
+	operations map[string]*operation
+}
+
+type operation struct {
+	parameters []parameter
+	body       *requestBody
+	// responses maps status codes, or "default", to the schema of each
+	// media type. Schemas are nil for media types that are not JSON.
+	responses map[string]map[string]*jsonschema.Schema
+}
+
+type parameter struct {
+	name     string
+	in       string
+	required bool
+	typ      string
+	schema   *jsonschema.Schema
+}
+
+type requestBody struct {
+	required bool
+	content  map[string]*jsonschema.Schema
+}
+
+// NewOpenAPIValidator compiles the schemas of every operation in the
+// embedded document.
+func NewOpenAPIValidator() (*OpenAPIValidator, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(openAPIDocument))
+	if err != nil {
+		return nil, fmt.Errorf("parsing OpenAPI document: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.AssertFormat()
+	if err := c.AddResource(openAPIURL, doc); err != nil {
+		return nil, err
+	}
+
+	l := &openAPILoader{doc: doc, compiler: c}
+	v := &OpenAPIValidator{operations: make(map[string]*operation)}
+	paths, _ := doc.(map[string]any)["paths"].(map[string]any)
+	for path, item := range paths {
+		itemPtr := "/paths/" + escapePointer(path)
+		item, _ := item.(map[string]any)
+		for method, op := range item {
+			if method == "parameters" {
+				continue
+			}
+			compiled, err := l.operation(itemPtr+"/"+method, op.(map[string]any), item["parameters"])
+			if err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(method), path, err)
+			}
+			v.operations[normaliseRoute(strings.ToUpper(method)+" "+path)] = compiled
+		}
+	}
+	return v, nil
+}
+
+// Operations lists the documented routes as "METHOD /path".
+func (v *OpenAPIValidator) Operations() []string {
+	routes := make([]string, 0, len(v.operations))
+	for route := range v.operations {
+		routes = append(routes, route)
+	}
+	slices.Sort(routes)
+	return routes
+}
+
+// Middleware rejects requests whose parameters or JSON body do not match
+// the operation they are routed to. Bodies that are not valid JSON at all,
+// or too large, are left for the handler to report.
+func (v *OpenAPIValidator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, rctx := matchRoute(r)
+		op := v.operations[route]
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		for _, p := range op.parameters {
+			var (
+				value   string
+				present bool
+			)
+			switch p.in {
+			case "path":
+				value = rctx.URLParam(p.name)
+				present = true
+			case "query":
+				present = r.URL.Query().Has(p.name)
+				value = r.URL.Query().Get(p.name)
+			case "header":
+				value = r.Header.Get(p.name)
+				present = value != ""
+			}
+			if !present {
+				if p.required {
+					writeProblem(w, http.StatusBadRequest, fmt.Sprintf("missing %s parameter %q", p.in, p.name), "")
+					return
+				}
+				continue
+			}
+			if err := p.validate(value); err != nil {
+				writeProblem(w, http.StatusBadRequest, fmt.Sprintf("%s parameter %q: %s", p.in, p.name, err), "")
+				return
+			}
+		}
+
+		if op.body != nil && !v.validateBody(w, r, op.body) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (v *OpenAPIValidator) validateBody(w http.ResponseWriter, r *http.Request, body *requestBody) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	schema, ok := body.content[mediaType]
+	if !ok {
+		if r.ContentLength == 0 && !body.required {
+			return true
+		}
+		writeProblem(w, http.StatusUnsupportedMediaType,
+			"Content-Type must be one of "+strings.Join(sortedKeys(body.content), ", "), "")
+		return false
+	}
+	if schema == nil || r.Body == nil {
+		return true
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxJSONBody+1))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+	if err != nil || len(data) > maxJSONBody {
+		return true
+	}
+
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return true
+	}
+	if err := schema.Validate(instance); err != nil {
+		detail, pointer := describeSchemaError(err)
+		writeProblem(w, http.StatusBadRequest, detail, pointer)
+		return false
+	}
+	return true
+}
+
+// ValidateResponse checks a response to the given route, written as
+// "METHOD /pattern", against the document. It is meant for tests, so that
+// handlers and DTOs cannot drift from the contract unnoticed.
+func (v *OpenAPIValidator) ValidateResponse(route string, status int, header http.Header, body []byte) error {
+	op := v.operations[normaliseRoute(route)]
+	if op == nil {
+		return fmt.Errorf("%s: %w", route, errUndocumented)
+	}
+	content, ok := op.responses[strconv.Itoa(status)]
+	if !ok {
+		content, ok = op.responses["default"]
+	}
+	if !ok {
+		return fmt.Errorf("%s: status %d: %w", route, status, errUndocumented)
+	}
+	if len(content) == 0 {
+		if len(body) > 0 {
+			return fmt.Errorf("%s: status %d: documented without a body, got %d bytes", route, status, len(body))
+		}
+		return nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	schema, ok := content[mediaType]
+	if !ok {
+		return fmt.Errorf("%s: status %d: media type %q: %w", route, status, mediaType, errUndocumented)
+	}
+	if schema == nil {
+		return nil
+	}
+	instance, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%s: status %d: %w", route, status, err)
+	}
+	if err := schema.Validate(instance); err != nil {
+		detail, pointer := describeSchemaError(err)
+		return fmt.Errorf("%s: status %d: body at %q %s", route, status, pointer, detail)
+	}
+	return nil
+}
+
+func (p parameter) validate(value string) error {
+	var instance any = value
+	switch p.typ {
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("must be a boolean")
+		}
+		instance = b
+	case "integer":
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return errors.New("must be an integer")
+		}
+		instance = json.Number(value)
+	}
+	if err := p.schema.Validate(instance); err != nil {
+		detail, _ := describeSchemaError(err)
+		return errors.New(detail)
+	}
+	return nil
+}
+
+// openAPILoader compiles the parts of the document an operation refers to.
+// Schemas are compiled in place, by JSON Pointer into the document, so
+// that their $refs resolve against it.
+type openAPILoader struct {
+	doc      any
+	compiler *jsonschema.Compiler
+}
+
+// located is a node of the document together with its JSON Pointer.
+type located struct {
+	ptr  string
+	node any
+}
+
+func (l *openAPILoader) operation(ptr string, op map[string]any, shared any) (*operation, error) {
+	compiled := &operation{responses: make(map[string]map[string]*jsonschema.Schema)}
+
+	// Parameters shared by the path item come before the operation's own.
+	var params []located
+	if shared, ok := shared.([]any); ok {
+		itemPtr := ptr[:strings.LastIndex(ptr, "/")]
+		for i, p := range shared {
+			params = append(params, located{itemPtr + "/parameters/" + strconv.Itoa(i), p})
+		}
+	}
+	if own, ok := op["parameters"].([]any); ok {
+		for i, p := range own {
+			params = append(params, located{ptr + "/parameters/" + strconv.Itoa(i), p})
+		}
+	}
+	for _, p := range params {
+		node, nodePtr := l.resolve(p.node, p.ptr)
+		param := parameter{}
+		param.name, _ = node["name"].(string)
+		param.in, _ = node["in"].(string)
+		param.required, _ = node["required"].(bool)
+		if schema, ok := node["schema"].(map[string]any); ok {
+			param.typ, _ = schema["type"].(string)
+		}
+		var err error
+		if param.schema, err = l.compile(nodePtr + "/schema"); err != nil {
+			return nil, err
+		}
+		compiled.parameters = append(compiled.parameters, param)
+	}
+
+	if body, ok := op["requestBody"]; ok {
+		node, nodePtr := l.resolve(body, ptr+"/requestBody")
+		required, _ := node["required"].(bool)
+		content, err := l.content(node, nodePtr)
+		if err != nil {
+			return nil, err
+		}
+		compiled.body = &requestBody{required: required, content: content}
+	}
+
+	responses, _ := op["responses"].(map[string]any)
+	for status, response := range responses {
+		node, nodePtr := l.resolve(response, ptr+"/responses/"+status)
+		content, err := l.content(node, nodePtr)
+		if err != nil {
+			return nil, err
+		}
+		compiled.responses[status] = content
+	}
+	return compiled, nil
+}
+
+func (l *openAPILoader) content(node map[string]any, ptr string) (map[string]*jsonschema.Schema, error) {
+	content := make(map[string]*jsonschema.Schema)
+	media, _ := node["content"].(map[string]any)
+	for mediaType := range media {
+		if !isJSONMediaType(mediaType) {
+			content[mediaType] = nil
+			continue
+		}
+		schema, err := l.compile(ptr + "/content/" + escapePointer(mediaType) + "/schema")
+		if err != nil {
+			return nil, err
+		}
+		content[mediaType] = schema
+	}
+	return content, nil
+}
+
+func (l *openAPILoader) compile(ptr string) (*jsonschema.Schema, error) {
+	return l.compiler.Compile(openAPIURL + "#" + ptr)
+}
+
+// resolve follows a local $ref, returning the node it points to and its
+// location.
+func (l *openAPILoader) resolve(node any, ptr string) (map[string]any, string) {
+	m, _ := node.(map[string]any)
+	ref, ok := m["$ref"].(string)
+	if !ok || !strings.HasPrefix(ref, "#/") {
+		return m, ptr
+	}
+	target := l.doc
+	for _, token := range strings.Split(ref[2:], "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		target = target.(map[string]any)[token]
+	}
+	return l.resolve(target, ref[1:])
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// matchRoute returns the method and pattern of the route r will be served
+// by, with the route context holding its URL parameters. The router has not
+// matched it yet when middleware runs, so it is looked up here.
+func matchRoute(r *http.Request) (string, *chi.Context) {
+	routes := chi.RouteContext(r.Context())
+	if routes == nil || routes.Routes == nil {
+		return "", nil
+	}
+	rctx := chi.NewRouteContext()
+	pattern := routes.Routes.Find(rctx, r.Method, r.URL.Path)
+	if pattern == "" {
+		return "", nil
+	}
+	return normaliseRoute(r.Method + " " + pattern), rctx
+}
+
+func normaliseRoute(route string) string {
+	if trimmed := strings.TrimSuffix(route, "/"); !strings.HasSuffix(trimmed, " ") {
+		return trimmed
+	}
+	return route
+}
+
+var schemaErrorPrinter = message.NewPrinter(language.English)
+
+// describeSchemaError reports the first leaf of a validation error and the
+// JSON Pointer to the value it is about.
+func describeSchemaError(err error) (detail string, pointer string) {
+	ve, ok := errors.AsType[*jsonschema.ValidationError](err)
+	if !ok {
+		return err.Error(), ""
+	}
+	for len(ve.Causes) > 0 {
+		ve = ve.Causes[0]
+	}
+	for _, token := range ve.InstanceLocation {
+		pointer += "/" + escapePointer(token)
+	}
+	return ve.ErrorKind.LocalizedString(schemaErrorPrinter), pointer
+}
+
+func escapePointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
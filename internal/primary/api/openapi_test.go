@@ -0,0 +1,275 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newOpenAPIValidator(t *testing.T) *OpenAPIValidator {
+	t.Helper()
+	v, err := NewOpenAPIValidator()
+	require.NoError(t, err)
+	return v
+}
+
+func TestOpenAPIValidator_Middleware(t *testing.T) {
+	v := newOpenAPIValidator(t)
+	r := chi.NewRouter()
+	r.Use(v.Middleware)
+	reached := func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte("reached"))
+	}
+	r.Post("/v1/users", reached)
+	r.Patch("/v1/users/{id}", reached)
+	r.Post("/v1/calendar/{userID}/imports", reached)
+	r.Get("/undocumented", reached)
+
+	valid := `{"email":"ada@example.com","username":"ada","password":"correct horse","dob":"1990-12-10T00:00:00Z"}`
+	id := uuid.NewString()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantDetail  string
+		wantPointer string
+	}{
+		{
+			name:        "valid sign-up",
+			method:      http.MethodPost,
+			path:        "/v1/users",
+			contentType: "application/json",
+			body:        valid,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "missing required field",
+			method:      http.MethodPost,
+			path:        "/v1/users",
+			contentType: "application/json",
+			body:        `{"email":"ada@example.com","username":"ada","dob":"1990-12-10T00:00:00Z"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "missing property 'password'",
+		},
+		{
+			name:        "invalid email",
+			method:      http.MethodPost,
+			path:        "/v1/users",
+			contentType: "application/json",
+			body:        strings.Replace(valid, "ada@example.com", "ada", 1),
+			wantStatus:  http.StatusBadRequest,
+			wantPointer: "/email",
+		},
+		{
+			name:        "short password",
+			method:      http.MethodPost,
+			path:        "/v1/users",
+			contentType: "application/json",
+			body:        strings.Replace(valid, "correct horse", "short", 1),
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "minLength: got 5, want 8",
+			wantPointer: "/password",
+		},
+		{
+			name:        "nested unknown field",
+			method:      http.MethodPatch,
+			path:        "/v1/users/" + id,
+			contentType: "application/json",
+			body:        `{"weekAnchor":{"mode":"calendar","day":"monday"}}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  "additional properties 'day' not allowed",
+			wantPointer: "/weekAnchor",
+		},
+		{
+			name:        "malformed JSON is left to the handler",
+			method:      http.MethodPost,
+			path:        "/v1/users",
+			contentType: "application/json",
+			body:        `{"email":`,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "unsupported media type",
+			method:      http.MethodPost,
+			path:        "/v1/users",
+			contentType: "text/plain",
+			body:        valid,
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantDetail:  "Content-Type must be one of application/json",
+		},
+		{
+			name:        "malformed path parameter",
+			method:      http.MethodPatch,
+			path:        "/v1/users/42",
+			contentType: "application/json",
+			body:        `{"timeZone":"UTC"}`,
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  `path parameter "id": '42' is not valid uuid: must have 5 elements`,
+		},
+		{
+			name:        "malformed query parameter",
+			method:      http.MethodPost,
+			path:        "/v1/calendar/" + id + "/imports?dryRun=perhaps",
+			contentType: "multipart/form-data; boundary=x",
+			body:        "--x--",
+			wantStatus:  http.StatusBadRequest,
+			wantDetail:  `query parameter "dryRun": must be a boolean`,
+		},
+		{
+			name:        "multipart bodies are not inspected",
+			method:      http.MethodPost,
+			path:        "/v1/calendar/" + id + "/imports?dryRun=true",
+			contentType: "multipart/form-data; boundary=x",
+			body:        "--x--",
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:       "undocumented routes pass through",
+			method:     http.MethodGet,
+			path:       "/undocumented",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "reached", rec.Body.String())
+				return
+			}
+			assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+			var problem Problem
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+			if tt.wantDetail != "" {
+				assert.Equal(t, tt.wantDetail, problem.Detail)
+			}
+			assert.Equal(t, tt.wantPointer, problem.Pointer)
+		})
+	}
+}
+
+func TestOpenAPIValidator_ValidateResponse(t *testing.T) {
+	v := newOpenAPIValidator(t)
+	jsonHeader := http.Header{"Content-Type": {"application/json"}}
+	profile := `{"id":"4762e4fb-b6bd-487d-834d-7a8c20c78be9","email":"john@example.com","username":"johndoe","dob":"1992-11-21T00:00:00Z","weekAnchor":{"mode":"continuous"},"timeZone":"UTC"}`
+
+	assert.NoError(t, v.ValidateResponse("GET /v1/users/{id}", http.StatusOK, jsonHeader, []byte(profile)))
+	assert.NoError(t, v.ValidateResponse("GET /v1/users/{id}", http.StatusNotFound,
+		http.Header{"Content-Type": {"text/plain; charset=utf-8"}}, []byte("user not found")))
+	assert.NoError(t, v.ValidateResponse("DELETE /v1/calendar/{userID}/imports/{importID}", http.StatusNoContent, http.Header{}, nil))
+
+	assert.ErrorContains(t, v.ValidateResponse("GET /v1/users/{id}", http.StatusOK, jsonHeader,
+		[]byte(strings.Replace(profile, `"timeZone":"UTC"`, `"timeZone":"UTC","password":"x"`, 1))),
+		"additional properties 'password' not allowed")
+	assert.ErrorContains(t, v.ValidateResponse("GET /v1/users/{id}", http.StatusOK, jsonHeader,
+		[]byte(strings.Replace(profile, `,"timeZone":"UTC"`, "", 1))),
+		"missing property 'timeZone'")
+	assert.ErrorIs(t, v.ValidateResponse("GET /v1/users/{id}", http.StatusTeapot, jsonHeader, nil), errUndocumented)
+	assert.ErrorIs(t, v.ValidateResponse("GET /v1/users/{id}", http.StatusOK,
+		http.Header{"Content-Type": {"text/html"}}, nil), errUndocumented)
+	assert.ErrorIs(t, v.ValidateResponse("PUT /v1/users/{id}", http.StatusOK, jsonHeader, nil), errUndocumented)
+}
+
+// TestUserHandler_MatchesOpenAPI keeps the user DTOs and the document in
+// step.
+func TestUserHandler_MatchesOpenAPI(t *testing.T) {
+	v := newOpenAPIValidator(t)
+	id := uuid.New()
+	dob := time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC)
+
+	service := new(MockUserService)
+	service.On("CreateUser", mock.Anything, mock.Anything).Return(&user.CreateUserResponse{
+		ID:            id,
+		Email:         "john@example.com",
+		Username:      "johndoe",
+		DateOfBirth:   dob,
+		WeekAnchor:    user.WeekAnchor{Mode: "continuous"},
+		TimeZone:      "UTC",
+		CalendarToken: "token",
+	}, nil).Once()
+	service.On("CreateUser", mock.Anything, mock.Anything).Return(nil, user.ErrEmailExists).Once()
+	service.On("GetProfile", mock.Anything, id).Return(&user.ProfileResponse{
+		ID:          id,
+		Email:       "john@example.com",
+		Username:    "johndoe",
+		DateOfBirth: dob,
+		WeekAnchor:  user.WeekAnchor{Mode: "calendar", FirstWeekday: "sunday"},
+		TimeZone:    "Pacific/Auckland",
+		CurrentWeek: 1707,
+	}, nil).Once()
+	service.On("GetProfile", mock.Anything, id).Return(nil, user.ErrUserNotFound).Once()
+	service.On("Authenticate", mock.Anything, "john@example.com", "correct horse").Return(id, nil).Times(2)
+
+	r := chi.NewRouter()
+	r.Use(v.Middleware)
+	Version{Name: "v1"}.Route(r, NewUserHandler(service).RegisterV1)
+
+	signup := `{"email":"john@example.com","username":"johndoe","password":"correct horse","dob":"1992-11-21T00:00:00Z"}`
+	getProfile := func(authenticated bool) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/v1/users/"+id.String(), nil)
+		if authenticated {
+			req.SetBasicAuth("john@example.com", "correct horse")
+		}
+		return req
+	}
+	requests := []struct {
+		route string
+		req   *http.Request
+	}{
+		{"POST /v1/users", httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(signup))},
+		{"POST /v1/users", httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(signup))},
+		{"POST /v1/users", httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"email":1}`))},
+		{"GET /v1/users/{id}", getProfile(true)},
+		{"GET /v1/users/{id}", getProfile(true)},
+		{"GET /v1/users/{id}", getProfile(false)},
+	}
+	for _, tt := range requests {
+		tt.req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, tt.req)
+		assert.NoError(t, v.ValidateResponse(tt.route, rec.Code, rec.Header(), rec.Body.Bytes()))
+	}
+	service.AssertExpectations(t)
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	r := chi.NewRouter()
+	NewOpenAPIHandler().RegisterRoutes(r)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+	assert.Equal(t, "3.1.0", doc["openapi"])
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `fetch("openapi.json")`)
+}
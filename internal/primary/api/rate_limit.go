@@ -0,0 +1,181 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+)
+
+// maxKeyBody bounds how much of a request body a key function reads.
+const maxKeyBody = 64 << 10
+
+// RateLimitKey extracts what a rule counts requests by. Requests for which
+// it returns false are not limited by that rule.
+type RateLimitKey func(r *http.Request) (string, bool)
+
+// RateLimitRule limits requests to one route, written as the method and
+// chi pattern, e.g. "POST /users".
+type RateLimitRule struct {
+	Route string
+	// Name tells rules on the same route apart, e.g. "ip" and "email".
+	Name string
+	// Bucket, if set, is counted against instead of Route, so that rules
+	// for routes that serve the same thing share one limit.
+	Bucket string
+	Limit  ratelimit.Limit
+	Key    RateLimitKey
+}
+
+type RateLimiter struct {
+	store ratelimit.Store
+	rules map[string][]RateLimitRule
+	now   func() time.Time
+}
+
+// NewRateLimiter returns a limiter enforcing rules. Unlimited rules are
+// dropped.
+func NewRateLimiter(store ratelimit.Store, rules ...RateLimitRule) *RateLimiter {
+	l := &RateLimiter{
+		store: store,
+		rules: make(map[string][]RateLimitRule),
+		now:   time.Now,
+	}
+	for _, rule := range rules {
+		if rule.Limit.Unlimited() {
+			continue
+		}
+		route := normaliseRoute(rule.Route)
+		l.rules[route] = append(l.rules[route], rule)
+	}
+	return l
+}
+
+// Middleware applies the rules for the route a request is about to hit.
+// Every matching rule spends a token; the request is rejected with 429 if
+// any bucket is empty, and the RateLimit-* headers describe the tightest
+// one. A failing store lets requests through rather than take the API down
+// with it.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, _ := matchRoute(r)
+		rules := l.rules[route]
+		if len(rules) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := l.now()
+		var (
+			binding ratelimit.Decision
+			limited bool
+		)
+		for _, rule := range rules {
+			value, ok := rule.Key(r)
+			if !ok {
+				continue
+			}
+			d, err := l.store.Take(r.Context(), storeKey(rule, value), rule.Limit, now)
+			if err != nil {
+				slog.ErrorContext(r.Context(), "rate limit store failed", "rule", rule.Name, "error", err)
+				continue
+			}
+			if !limited || tighter(d, binding) {
+				binding = d
+				limited = true
+			}
+		}
+		if !limited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Set("RateLimit-Limit", strconv.Itoa(binding.Limit))
+		h.Set("RateLimit-Remaining", strconv.Itoa(binding.Remaining))
+		h.Set("RateLimit-Reset", seconds(binding.Reset))
+		if !binding.Allowed {
+			h.Set("Retry-After", seconds(binding.RetryAfter))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ClientIP keys requests by the connecting address. Behind a reverse proxy
+// that is the proxy's, so the proxy must rewrite RemoteAddr first.
+func ClientIP(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr, r.RemoteAddr != ""
+	}
+	return host, true
+}
+
+// JSONField keys requests by a top-level string field of a JSON body,
+// compared case-insensitively, such as the email a sign-up targets. The body
+// is restored for the handler.
+func JSONField(name string) RateLimitKey {
+	return func(r *http.Request) (string, bool) {
+		if r.Body == nil {
+			return "", false
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxKeyBody))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(data), r.Body), r.Body}
+		if err != nil {
+			return "", false
+		}
+
+		var fields map[string]json.RawMessage
+		if json.Unmarshal(data, &fields) != nil {
+			return "", false
+		}
+		var value string
+		if json.Unmarshal(fields[name], &value) != nil {
+			return "", false
+		}
+		value = strings.ToLower(strings.TrimSpace(value))
+		return value, value != ""
+	}
+}
+
+// storeKey hashes the key value so that the store never holds email
+// addresses.
+func storeKey(rule RateLimitRule, value string) string {
+	bucket := rule.Bucket
+	if bucket == "" {
+		bucket = normaliseRoute(rule.Route)
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "ratelimit:" + bucket + ":" + rule.Name + ":" + hex.EncodeToString(sum[:16])
+}
+
+// tighter reports whether a is more restrictive than b.
+func tighter(a, b ratelimit.Decision) bool {
+	if a.Allowed != b.Allowed {
+		return !a.Allowed
+	}
+	if !a.Allowed {
+		return a.RetryAfter > b.RetryAfter
+	}
+	return a.Remaining < b.Remaining
+}
+
+// seconds rounds d up to whole seconds, as the headers require.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
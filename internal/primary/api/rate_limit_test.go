@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Decision, error) {
+	return ratelimit.Decision{}, errors.New("store down")
+}
+
+func newRateLimitedRouter(store ratelimit.Store, rules ...RateLimitRule) chi.Router {
+	limiter := NewRateLimiter(store, rules...)
+	limiter.now = func() time.Time { return time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }
+
+	r := chi.NewRouter()
+	r.Use(limiter.Middleware)
+	r.Route("/users", func(r chi.Router) {
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			w.Write(body)
+		})
+		r.Get("/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	return r
+}
+
+func signup(r http.Handler, ip, email string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"email":"`+email+`"}`))
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimiter(t *testing.T) {
+	perIP := RateLimitRule{Route: "POST /users", Name: "ip", Limit: ratelimit.Limit{Requests: 3, Per: time.Minute}, Key: ClientIP}
+	perEmail := RateLimitRule{Route: "POST /users", Name: "email", Limit: ratelimit.Limit{Requests: 2, Per: time.Hour}, Key: JSONField("email")}
+
+	t.Run("rejects once the bucket is empty", func(t *testing.T) {
+		r := newRateLimitedRouter(memory.NewRateLimitStore(), perIP)
+
+		for i, remaining := range []string{"2", "1", "0"} {
+			rec := signup(r, "192.0.2.1", "user"+string(rune('a'+i))+"@example.com")
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "3", rec.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, remaining, rec.Header().Get("RateLimit-Remaining"))
+		}
+
+		rec := signup(r, "192.0.2.1", "userd@example.com")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "20", rec.Header().Get("Retry-After"))
+		assert.Equal(t, "0", rec.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "60", rec.Header().Get("RateLimit-Reset"))
+
+		assert.Equal(t, http.StatusOK, signup(r, "192.0.2.2", "userd@example.com").Code, "other clients are unaffected")
+	})
+
+	t.Run("limits an email across clients", func(t *testing.T) {
+		r := newRateLimitedRouter(memory.NewRateLimitStore(), perIP, perEmail)
+
+		assert.Equal(t, http.StatusOK, signup(r, "192.0.2.1", "ada@example.com").Code)
+		assert.Equal(t, http.StatusOK, signup(r, "192.0.2.2", " ADA@example.com").Code)
+		rec := signup(r, "192.0.2.3", "ada@example.com")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "1800", rec.Header().Get("Retry-After"))
+	})
+
+	t.Run("restores the body for the handler", func(t *testing.T) {
+		r := newRateLimitedRouter(memory.NewRateLimitStore(), perEmail)
+
+		rec := signup(r, "192.0.2.1", "ada@example.com")
+		assert.Equal(t, `{"email":"ada@example.com"}`, rec.Body.String())
+	})
+
+	t.Run("leaves other routes alone", func(t *testing.T) {
+		r := newRateLimitedRouter(memory.NewRateLimitStore(), perIP)
+
+		for range 5 {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+		}
+	})
+
+	t.Run("matches parameterised routes", func(t *testing.T) {
+		rule := RateLimitRule{Route: "GET /users/{id}", Name: "ip", Limit: ratelimit.Limit{Requests: 1, Per: time.Minute}, Key: ClientIP}
+		r := newRateLimitedRouter(memory.NewRateLimitStore(), rule)
+
+		codes := []int{}
+		for _, id := range []string{"1", "2"} {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/"+id, nil))
+			codes = append(codes, rec.Code)
+		}
+		assert.Equal(t, []int{http.StatusOK, http.StatusTooManyRequests}, codes)
+	})
+
+	t.Run("rules with the same bucket share one limit", func(t *testing.T) {
+		limit := ratelimit.Limit{Requests: 2, Per: time.Minute}
+		r := newRateLimitedRouter(memory.NewRateLimitStore(),
+			RateLimitRule{Route: "POST /users", Name: "ip", Limit: limit, Key: ClientIP},
+			RateLimitRule{Route: "GET /users/{id}", Name: "ip", Bucket: "POST /users", Limit: limit, Key: ClientIP},
+		)
+
+		assert.Equal(t, http.StatusOK, signup(r, "192.0.2.1", "ada@example.com").Code)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, http.StatusTooManyRequests, signup(r, "192.0.2.1", "ada@example.com").Code)
+	})
+
+	t.Run("lets requests through when the store fails", func(t *testing.T) {
+		captureLogs(t)
+		r := newRateLimitedRouter(failingRateLimitStore{}, perIP)
+
+		rec := signup(r, "192.0.2.1", "ada@example.com")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get("RateLimit-Limit"))
+	})
+}
@@ -3,10 +3,11 @@ package api
 import (
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
 	"github.com/mgwinsor/weekbyweek/internal/app/user"
 )
 
@@ -20,29 +21,31 @@ func NewUserHandler(service user.Service) *UserHandler {
 	}
 }
 
-func (h *UserHandler) RegisterRoutes(r chi.Router) http.Handler {
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-
+// RegisterV1 registers the user routes of API version 1 on r. Profiles
+// are read and changed only by their own user.
+func (h *UserHandler) RegisterV1(r chi.Router) {
 	r.Route("/users", func(r chi.Router) {
 		r.Post("/", h.handleCreateUser)
+		r.With(requireUser(h.userService, "id")).Get("/{id}", h.handleGetProfile)
+		r.With(requireUser(h.userService, "id")).Patch("/{id}", h.handleUpdateProfile)
 	})
-
-	return r
 }
 
 func (h *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
-	var req user.CreateUserRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	var req createUserRequestV1
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	createUserResponse, err := h.userService.CreateUser(r.Context(), req)
+	createUserResponse, err := h.userService.CreateUser(r.Context(), req.toApp())
 	if err != nil {
-		if errors.Is(err, user.ErrEmailExists) {
+		switch {
+		case errors.Is(err, user.ErrEmailExists):
 			http.Error(w, err.Error(), http.StatusConflict)
-		} else {
+		case errors.Is(err, user.ErrInvalidUser):
+			writeProblem(w, http.StatusBadRequest, err.Error(), "")
+		default:
+			slog.ErrorContext(r.Context(), "creating user", "error", err)
 			http.Error(w, "Failed to create user", http.StatusInternalServerError)
 		}
 		return
@@ -50,5 +53,78 @@ func (h *UserHandler) handleCreateUser(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createUserResponse)
+	json.NewEncoder(w).Encode(newCreateUserResponseV1(createUserResponse))
+}
+
+func (h *UserHandler) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	profileResponse, err := h.userService.GetProfile(r.Context(), id)
+	if err != nil {
+		writeProfileError(w, r, err)
+		return
+	}
+
+	etag := profileETag(profileResponse)
+	w.Header().Set("ETag", etag)
+	if noneMatch(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newProfileResponseV1(profileResponse))
+}
+
+func (h *UserHandler) handleUpdateProfile(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	var req updateProfileRequestV1
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	ifMatch, ok := ifMatchTags(r)
+	if !ok {
+		writeProblem(w, http.StatusPreconditionFailed, user.ErrVersionMismatch.Error(), "")
+		return
+	}
+
+	update := req.toApp()
+	update.IfMatch = ifMatch
+	profileResponse, err := h.userService.UpdateProfile(r.Context(), id, update)
+	if err != nil {
+		writeProfileError(w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", profileETag(profileResponse))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(newProfileResponseV1(profileResponse))
+}
+
+func writeProfileError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, user.ErrUserNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, user.ErrInvalidProfile):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, user.ErrVersionMismatch):
+		writeProblem(w, http.StatusPreconditionFailed, user.ErrVersionMismatch.Error(), "")
+	case errors.Is(err, user.ErrConflict):
+		w.Header().Set("Retry-After", "1")
+		writeProblem(w, http.StatusConflict, user.ErrConflict.Error(), "")
+	default:
+		slog.ErrorContext(r.Context(), "handling profile request", "error", err)
+		http.Error(w, "Failed to load profile", http.StatusInternalServerError)
+	}
 }
@@ -98,13 +98,15 @@ func TestUserAPIIntegration(t *testing.T) {
 			tt.mockSetup(mockService)
 
 			server := NewUserHandler(mockService)
-			router := server.RegisterRoutes(chi.NewRouter())
+			router := chi.NewRouter()
+			server.RegisterV1(router)
 
 			ts := httptest.NewServer(router)
 			defer ts.Close()
 
 			req, err := http.NewRequest(tt.method, ts.URL+tt.path, bytes.NewBuffer(tt.body))
 			require.NoError(t, err)
+			req.Header.Set("Content-Type", "application/json")
 
 			resp, err := ts.Client().Do(req)
 			require.NoError(t, err)
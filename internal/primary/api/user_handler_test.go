@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"maps"
 	"net/http"
 	"net/http/httptest"
@@ -12,6 +13,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/mgwinsor/weekbyweek/internal/app/user"
 	"github.com/stretchr/testify/assert"
@@ -33,6 +35,33 @@ func (m *MockUserService) CreateUser(ctx context.Context, req user.CreateUserReq
 	return resp, args.Error(1)
 }
 
+func (m *MockUserService) Authenticate(ctx context.Context, email, password string) (uuid.UUID, error) {
+	args := m.Called(ctx, email, password)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockUserService) GetProfile(ctx context.Context, id uuid.UUID) (*user.ProfileResponse, error) {
+	args := m.Called(ctx, id)
+
+	var resp *user.ProfileResponse
+	if args.Get(0) != nil {
+		resp = args.Get(0).(*user.ProfileResponse)
+	}
+
+	return resp, args.Error(1)
+}
+
+func (m *MockUserService) UpdateProfile(ctx context.Context, id uuid.UUID, req user.UpdateProfileRequest) (*user.ProfileResponse, error) {
+	args := m.Called(ctx, id, req)
+
+	var resp *user.ProfileResponse
+	if args.Get(0) != nil {
+		resp = args.Get(0).(*user.ProfileResponse)
+	}
+
+	return resp, args.Error(1)
+}
+
 func TestHandleCreateUser(t *testing.T) {
 	validDOB := time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC)
 	id, _ := uuid.Parse("4762e4fb-b6bd-487d-834d-7a8c20c78be9")
@@ -87,14 +116,38 @@ func TestHandleCreateUser(t *testing.T) {
 			inputBody:          newCreateUserPayload(map[string]any{"email": 1234}),
 			mockSetup:          func(m *MockUserService) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       "Invalid request body",
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"must be a string","pointer":"/email"}`,
 		},
 		{
 			name:               "invalid date of birth format",
 			inputBody:          newCreateUserPayload(map[string]any{"dob": "21-11-1992"}),
 			mockSetup:          func(m *MockUserService) {},
 			expectedStatusCode: http.StatusBadRequest,
-			expectedBody:       "Invalid request body",
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"must be an RFC 3339 timestamp","pointer":"/dob"}`,
+		},
+		{
+			name:      "unknown time zone",
+			inputBody: newCreateUserPayload(map[string]any{"timeZone": "Mars/Olympus_Mons"}),
+			mockSetup: func(m *MockUserService) {
+				m.On("CreateUser", mock.Anything, mock.MatchedBy(func(req user.CreateUserRequest) bool {
+					return req.TimeZone == "Mars/Olympus_Mons"
+				})).
+					Return(nil, fmt.Errorf("%w: unknown time zone", user.ErrInvalidUser)).
+					Once()
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid user: unknown time zone"}`,
+		},
+		{
+			name:      "unknown week anchor mode",
+			inputBody: newCreateUserPayload(map[string]any{"weekAnchor": map[string]any{"mode": "lunar"}}),
+			mockSetup: func(m *MockUserService) {
+				m.On("CreateUser", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: invalid week anchoring mode", user.ErrInvalidUser)).
+					Once()
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"invalid user: invalid week anchoring mode"}`,
 		},
 		{
 			name:      "unexpected error",
@@ -117,6 +170,7 @@ func TestHandleCreateUser(t *testing.T) {
 			server := NewUserHandler(mockService)
 			rr := httptest.NewRecorder()
 			req := httptest.NewRequest("POST", "/users", bytes.NewBufferString(tt.inputBody))
+			req.Header.Set("Content-Type", "application/json")
 
 			server.handleCreateUser(rr, req)
 
@@ -140,3 +194,243 @@ func newCreateUserPayload(overrides map[string]any) string {
 	body, _ := json.Marshal(payload)
 	return string(body)
 }
+
+func TestHandleProfile(t *testing.T) {
+	id, _ := uuid.Parse("4762e4fb-b6bd-487d-834d-7a8c20c78be9")
+	auckland := "Pacific/Auckland"
+
+	profile := user.ProfileResponse{
+		ID:          id,
+		Email:       "john@example.com",
+		Username:    "johndoe",
+		DateOfBirth: time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+		WeekAnchor:  user.WeekAnchor{Mode: "continuous"},
+		TimeZone:    auckland,
+		CurrentWeek: 1707,
+		Version:     3,
+	}
+	profileBody, _ := json.Marshal(profile)
+	etag := `"3-1707"`
+
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		header             http.Header
+		anonymous          bool
+		body               string
+		mockSetup          func(m *MockUserService)
+		expectedStatusCode int
+		expectedETag       string
+		expectedBody       string
+	}{
+		{
+			name:   "get profile",
+			method: http.MethodGet,
+			path:   "/users/" + id.String(),
+			mockSetup: func(m *MockUserService) {
+				m.On("GetProfile", mock.Anything, id).Return(&profile, nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       etag,
+			expectedBody:       string(profileBody),
+		},
+		{
+			name:               "get profile without credentials",
+			method:             http.MethodGet,
+			path:               "/users/" + id.String(),
+			anonymous:          true,
+			mockSetup:          func(m *MockUserService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "credentials required",
+		},
+		{
+			name:               "get another user's profile",
+			method:             http.MethodGet,
+			path:               "/users/" + uuid.NewString(),
+			mockSetup:          func(m *MockUserService) {},
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       "Forbidden",
+		},
+		{
+			name:   "get profile not modified",
+			method: http.MethodGet,
+			path:   "/users/" + id.String(),
+			header: http.Header{"If-None-Match": {`"2-1707", W/` + etag}},
+			mockSetup: func(m *MockUserService) {
+				m.On("GetProfile", mock.Anything, id).Return(&profile, nil).Once()
+			},
+			expectedStatusCode: http.StatusNotModified,
+			expectedETag:       etag,
+		},
+		{
+			name:   "get profile modified",
+			method: http.MethodGet,
+			path:   "/users/" + id.String(),
+			header: http.Header{"If-None-Match": {`"2-1707"`}},
+			mockSetup: func(m *MockUserService) {
+				m.On("GetProfile", mock.Anything, id).Return(&profile, nil).Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       etag,
+			expectedBody:       string(profileBody),
+		},
+		{
+			name:   "get unknown profile",
+			method: http.MethodGet,
+			path:   "/users/" + id.String(),
+			mockSetup: func(m *MockUserService) {
+				m.On("GetProfile", mock.Anything, id).Return(nil, user.ErrUserNotFound).Once()
+			},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       user.ErrUserNotFound.Error(),
+		},
+		{
+			name:               "get malformed id",
+			method:             http.MethodGet,
+			path:               "/users/not-a-uuid",
+			mockSetup:          func(m *MockUserService) {},
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       "404 page not found",
+		},
+		{
+			name:   "update time zone",
+			method: http.MethodPatch,
+			path:   "/users/" + id.String(),
+			body:   `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup: func(m *MockUserService) {
+				m.On("UpdateProfile", mock.Anything, id, user.UpdateProfileRequest{TimeZone: &auckland}).
+					Return(&profile, nil).
+					Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       etag,
+			expectedBody:       string(profileBody),
+		},
+		{
+			name:               "update without credentials",
+			method:             http.MethodPatch,
+			path:               "/users/" + id.String(),
+			anonymous:          true,
+			body:               `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup:          func(m *MockUserService) {},
+			expectedStatusCode: http.StatusUnauthorized,
+			expectedBody:       "credentials required",
+		},
+		{
+			name:   "update if match",
+			method: http.MethodPatch,
+			path:   "/users/" + id.String(),
+			header: http.Header{"If-Match": {`"2-1707", "3-1706"`}},
+			body:   `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup: func(m *MockUserService) {
+				m.On("UpdateProfile", mock.Anything, id, user.UpdateProfileRequest{TimeZone: &auckland, IfMatch: []user.ProfileTag{{Version: 2, Week: 1707}, {Version: 3, Week: 1706}}}).
+					Return(&profile, nil).
+					Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       etag,
+			expectedBody:       string(profileBody),
+		},
+		{
+			name:   "update if match any",
+			method: http.MethodPatch,
+			path:   "/users/" + id.String(),
+			header: http.Header{"If-Match": {"*"}},
+			body:   `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup: func(m *MockUserService) {
+				m.On("UpdateProfile", mock.Anything, id, user.UpdateProfileRequest{TimeZone: &auckland}).
+					Return(&profile, nil).
+					Once()
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedETag:       etag,
+			expectedBody:       string(profileBody),
+		},
+		{
+			name:   "update stale profile",
+			method: http.MethodPatch,
+			path:   "/users/" + id.String(),
+			header: http.Header{"If-Match": {`"2-1707"`}},
+			body:   `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup: func(m *MockUserService) {
+				m.On("UpdateProfile", mock.Anything, id, mock.Anything).
+					Return(nil, user.ErrVersionMismatch).
+					Once()
+			},
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBody:       `{"type":"about:blank","title":"Precondition Failed","status":412,"detail":"profile has changed"}`,
+		},
+		{
+			name:               "update if match weak or malformed",
+			method:             http.MethodPatch,
+			path:               "/users/" + id.String(),
+			header:             http.Header{"If-Match": {`W/"3-1707", 3, "3", "3-1707x"`}},
+			body:               `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup:          func(m *MockUserService) {},
+			expectedStatusCode: http.StatusPreconditionFailed,
+			expectedBody:       `{"type":"about:blank","title":"Precondition Failed","status":412,"detail":"profile has changed"}`,
+		},
+		{
+			name:   "update keeps conflicting",
+			method: http.MethodPatch,
+			path:   "/users/" + id.String(),
+			body:   `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup: func(m *MockUserService) {
+				m.On("UpdateProfile", mock.Anything, id, mock.Anything).
+					Return(nil, user.ErrConflict).
+					Once()
+			},
+			expectedStatusCode: http.StatusConflict,
+			expectedBody:       `{"type":"about:blank","title":"Conflict","status":409,"detail":"profile was modified concurrently"}`,
+		},
+		{
+			name:   "update with invalid time zone",
+			method: http.MethodPatch,
+			path:   "/users/" + id.String(),
+			body:   `{"timeZone":"Pacific/Auckland"}`,
+			mockSetup: func(m *MockUserService) {
+				m.On("UpdateProfile", mock.Anything, id, mock.Anything).
+					Return(nil, fmt.Errorf("%w: unknown time zone", user.ErrInvalidProfile)).
+					Once()
+			},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       "invalid profile: unknown time zone",
+		},
+		{
+			name:               "update with malformed body",
+			method:             http.MethodPatch,
+			path:               "/users/" + id.String(),
+			body:               `{"timeZone":`,
+			mockSetup:          func(m *MockUserService) {},
+			expectedStatusCode: http.StatusBadRequest,
+			expectedBody:       `{"type":"about:blank","title":"Bad Request","status":400,"detail":"unexpected end of JSON input","pointer":"/timeZone"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockUserService)
+			mockService.On("Authenticate", mock.Anything, "john@example.com", "correct horse").Return(id, nil).Maybe()
+			tt.mockSetup(mockService)
+
+			router := chi.NewRouter()
+			NewUserHandler(mockService).RegisterV1(router)
+			rr := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if !tt.anonymous {
+				req.SetBasicAuth("john@example.com", "correct horse")
+			}
+			maps.Copy(req.Header, tt.header)
+
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.expectedStatusCode, rr.Code, "status code should match expected")
+			assert.Equal(t, tt.expectedETag, rr.Header().Get("ETag"), "ETag should match expected")
+			assert.Equal(t, tt.expectedBody, strings.TrimSpace(rr.Body.String()), "response body should match expected")
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Version is a major version of the API, served under /{Name}, or at the
+// root if Name is empty. Its routes and DTOs are frozen once published:
+// breaking changes, such as a new dob format, go into the next version
+// while clients migrate.
+type Version struct {
+	Name string
+	// Deprecation and Sunset, when set, announce on every response of the
+	// version when it was deprecated (RFC 9745) and when it will be
+	// removed (RFC 8594).
+	Deprecation time.Time
+	Sunset      time.Time
+}
+
+// Route mounts the version on r and gives each register function a group
+// of its own, so that middleware one handler adds stays with its routes.
+func (v Version) Route(r chi.Router, register ...func(chi.Router)) {
+	mount := func(r chi.Router) {
+		if !v.Deprecation.IsZero() || !v.Sunset.IsZero() {
+			r.Use(v.announce)
+		}
+		for _, register := range register {
+			r.Group(register)
+		}
+	}
+	if v.Name == "" {
+		r.Group(mount)
+		return
+	}
+	r.Route("/"+v.Name, mount)
+}
+
+func (v Version) announce(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !v.Deprecation.IsZero() {
+			w.Header().Set("Deprecation", "@"+strconv.FormatInt(v.Deprecation.Unix(), 10))
+		}
+		if !v.Sunset.IsZero() {
+			w.Header().Set("Sunset", v.Sunset.UTC().Format(http.TimeFormat))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
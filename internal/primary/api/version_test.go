@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVersion_Route(t *testing.T) {
+	tests := []struct {
+		name            string
+		version         Version
+		wantDeprecation string
+		wantSunset      string
+	}{
+		{
+			name:    "current",
+			version: Version{Name: "v1"},
+		},
+		{
+			name: "deprecated",
+			version: Version{
+				Name:        "v1",
+				Deprecation: time.Date(2026, time.June, 30, 23, 59, 59, 0, time.UTC),
+			},
+			wantDeprecation: "@1782863999",
+		},
+		{
+			name: "sunset scheduled",
+			version: Version{
+				Name:        "v1",
+				Deprecation: time.Date(2026, time.June, 30, 23, 59, 59, 0, time.UTC),
+				Sunset:      time.Date(2027, time.January, 1, 1, 0, 0, 0, time.FixedZone("CET", 3600)),
+			},
+			wantDeprecation: "@1782863999",
+			wantSunset:      "Fri, 01 Jan 2027 00:00:00 GMT",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := chi.NewRouter()
+			tt.version.Route(r,
+				func(r chi.Router) {
+					r.Use(func(next http.Handler) http.Handler {
+						return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+							w.Header().Set("X-Users", "yes")
+							next.ServeHTTP(w, r)
+						})
+					})
+					r.Get("/users", func(w http.ResponseWriter, r *http.Request) {})
+				},
+				func(r chi.Router) {
+					r.Get("/calendar", func(w http.ResponseWriter, r *http.Request) {})
+				},
+			)
+
+			for _, path := range []string{"/v1/users", "/v1/calendar"} {
+				rec := httptest.NewRecorder()
+				r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+				assert.Equal(t, http.StatusOK, rec.Code, path)
+				assert.Equal(t, tt.wantDeprecation, rec.Header().Get("Deprecation"), path)
+				assert.Equal(t, tt.wantSunset, rec.Header().Get("Sunset"), path)
+			}
+
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/calendar", nil))
+			assert.Empty(t, rec.Header().Get("X-Users"), "middleware stays with its handler's routes")
+
+			rec = httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users", nil))
+			assert.Equal(t, http.StatusNotFound, rec.Code, "routes only exist under the version")
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package ical
+
+import "time"
+
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT. All-day events use the calendar dates of Start and
+// End, with End exclusive as required by RFC 5545. Recurrence and ExDates
+// are populated by Decode and are not written by Encode.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+	Stamp       time.Time
+	Recurrence  *Recurrence
+	ExDates     []time.Time
+}
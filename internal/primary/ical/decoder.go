@@ -0,0 +1,258 @@
+package ical
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ErrMalformedCalendar = errors.New("malformed iCalendar data")
+
+const localDateTimeFormat = "20060102T150405"
+
+// Decode parses an RFC 5545 iCalendar stream and returns its VEVENTs.
+// Instances overriding a recurring event (those with a RECURRENCE-ID) and
+// cancelled events are dropped. A TZID that is not a known IANA zone is
+// treated as floating time.
+func Decode(r io.Reader) (*Calendar, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &Calendar{}
+	var (
+		stack   []string
+		current *Event
+		skip    bool
+	)
+
+	for i, raw := range lines {
+		lineNo := i + 1
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+
+		cl, err := parseContentLine(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformedCalendar, lineNo, err)
+		}
+
+		switch cl.name {
+		case "BEGIN":
+			component := strings.ToUpper(cl.value)
+			stack = append(stack, component)
+			if component == "VEVENT" && len(stack) == 2 {
+				current = &Event{}
+				skip = false
+			}
+			continue
+		case "END":
+			component := strings.ToUpper(cl.value)
+			if len(stack) == 0 || stack[len(stack)-1] != component {
+				return nil, fmt.Errorf("%w: line %d: unexpected END:%s", ErrMalformedCalendar, lineNo, cl.value)
+			}
+			stack = stack[:len(stack)-1]
+			if component == "VEVENT" && current != nil {
+				if !skip {
+					if current.Start.IsZero() {
+						return nil, fmt.Errorf("%w: line %d: event %q has no DTSTART", ErrMalformedCalendar, lineNo, current.UID)
+					}
+					cal.Events = append(cal.Events, *current)
+				}
+				current = nil
+			}
+			continue
+		}
+
+		if len(stack) == 1 && stack[0] == "VCALENDAR" {
+			switch cl.name {
+			case "PRODID":
+				cal.ProdID = cl.value
+			case "X-WR-CALNAME":
+				cal.Name = unescapeText(cl.value)
+			}
+			continue
+		}
+
+		if current == nil || len(stack) != 2 {
+			continue
+		}
+
+		if err := applyProperty(current, cl, &skip); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrMalformedCalendar, lineNo, err)
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("%w: unterminated %s", ErrMalformedCalendar, stack[len(stack)-1])
+	}
+
+	return cal, nil
+}
+
+func applyProperty(e *Event, cl contentLine, skip *bool) error {
+	switch cl.name {
+	case "UID":
+		e.UID = cl.value
+	case "SUMMARY":
+		e.Summary = unescapeText(cl.value)
+	case "DESCRIPTION":
+		e.Description = unescapeText(cl.value)
+	case "DTSTART":
+		t, allDay, err := parseTime(cl)
+		if err != nil {
+			return err
+		}
+		e.Start, e.AllDay = t, allDay
+	case "DTEND":
+		t, _, err := parseTime(cl)
+		if err != nil {
+			return err
+		}
+		e.End = t
+	case "DTSTAMP":
+		t, _, err := parseTime(cl)
+		if err != nil {
+			return err
+		}
+		e.Stamp = t
+	case "RRULE":
+		rec, err := parseRecurrence(cl.value)
+		if err != nil {
+			return err
+		}
+		e.Recurrence = rec
+	case "EXDATE":
+		for v := range strings.SplitSeq(cl.value, ",") {
+			t, _, err := parseTime(contentLine{name: cl.name, params: cl.params, value: v})
+			if err != nil {
+				return err
+			}
+			e.ExDates = append(e.ExDates, t)
+		}
+	case "RECURRENCE-ID":
+		*skip = true
+	case "STATUS":
+		if strings.EqualFold(cl.value, "CANCELLED") {
+			*skip = true
+		}
+	}
+	return nil
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+func parseContentLine(line string) (contentLine, error) {
+	cl := contentLine{params: make(map[string]string)}
+
+	nameEnd := strings.IndexAny(line, ";:")
+	if nameEnd <= 0 {
+		return cl, fmt.Errorf("missing property name in %q", line)
+	}
+	cl.name = strings.ToUpper(line[:nameEnd])
+
+	rest := line[nameEnd:]
+	for strings.HasPrefix(rest, ";") {
+		rest = rest[1:]
+		eq := strings.IndexByte(rest, '=')
+		if eq <= 0 {
+			return cl, fmt.Errorf("malformed parameter in %q", line)
+		}
+		key := strings.ToUpper(rest[:eq])
+		rest = rest[eq+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				return cl, fmt.Errorf("unterminated quoted parameter in %q", line)
+			}
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.IndexAny(rest, ";:")
+			if end < 0 {
+				return cl, fmt.Errorf("missing value in %q", line)
+			}
+			value = rest[:end]
+			rest = rest[end:]
+		}
+		cl.params[key] = value
+	}
+
+	if !strings.HasPrefix(rest, ":") {
+		return cl, fmt.Errorf("missing value in %q", line)
+	}
+	cl.value = rest[1:]
+	return cl, nil
+}
+
+func parseTime(cl contentLine) (time.Time, bool, error) {
+	value := strings.TrimSpace(cl.value)
+
+	if strings.EqualFold(cl.params["VALUE"], "DATE") || len(value) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s date %q", cl.name, value)
+		}
+		return t, true, nil
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s date-time %q", cl.name, value)
+		}
+		return t, false, nil
+	}
+
+	loc := time.UTC
+	if tzid := cl.params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(strings.TrimPrefix(tzid, "/")); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localDateTimeFormat, value, loc)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid %s date-time %q", cl.name, value)
+	}
+	return t, false, nil
+}
+
+var textUnescaper = strings.NewReplacer(
+	`\\`, `\`,
+	`\;`, ";",
+	`\,`, ",",
+	`\n`, "\n",
+	`\N`, "\n",
+)
+
+func unescapeText(s string) string {
+	return textUnescaper.Replace(s)
+}
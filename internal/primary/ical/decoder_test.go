@@ -0,0 +1,149 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	input := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Example//EN",
+		"X-WR-CALNAME:Work\\, mostly",
+		"BEGIN:VTIMEZONE",
+		"TZID:Europe/London",
+		"BEGIN:STANDARD",
+		"DTSTART:19701025T020000",
+		"END:STANDARD",
+		"END:VTIMEZONE",
+		"BEGIN:VEVENT",
+		"UID:all-day@example",
+		"DTSTART;VALUE=DATE:20140612",
+		"DTEND;VALUE=DATE:20140613",
+		`SUMMARY:Graduation\; finally`,
+		"DESCRIPTION:A long description that has been folded",
+		"  across two lines",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup@example",
+		`DTSTART;TZID="Europe/London":20200106T093000`,
+		"RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+		"EXDATE;TZID=Europe/London:20200108T093000",
+		"SUMMARY:Standup",
+		"BEGIN:VALARM",
+		"SUMMARY:Alarm summary is ignored",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:standup@example",
+		"RECURRENCE-ID;TZID=Europe/London:20200113T093000",
+		"DTSTART;TZID=Europe/London:20200113T100000",
+		"SUMMARY:Moved standup",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:cancelled@example",
+		"DTSTART:20200101T120000Z",
+		"STATUS:CANCELLED",
+		"SUMMARY:Cancelled",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+
+	cal, err := Decode(strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, "-//Example//EN", cal.ProdID)
+	assert.Equal(t, "Work, mostly", cal.Name)
+	require.Len(t, cal.Events, 2)
+
+	allDay := cal.Events[0]
+	assert.Equal(t, "all-day@example", allDay.UID)
+	assert.Equal(t, "Graduation; finally", allDay.Summary)
+	assert.Equal(t, "A long description that has been folded across two lines", allDay.Description)
+	assert.True(t, allDay.AllDay)
+	assert.Equal(t, time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC), allDay.Start)
+	assert.Equal(t, time.Date(2014, time.June, 13, 0, 0, 0, 0, time.UTC), allDay.End)
+
+	london, err := time.LoadLocation("Europe/London")
+	require.NoError(t, err)
+
+	standup := cal.Events[1]
+	assert.Equal(t, "Standup", standup.Summary)
+	assert.False(t, standup.AllDay)
+	assert.Equal(t, time.Date(2020, time.January, 6, 9, 30, 0, 0, london), standup.Start)
+	require.NotNil(t, standup.Recurrence)
+	assert.Equal(t, Weekly, standup.Recurrence.Frequency)
+	assert.Equal(t, []time.Weekday{time.Monday, time.Wednesday}, standup.Recurrence.ByDay)
+	assert.Equal(t, 4, standup.Recurrence.Count)
+	assert.Len(t, standup.ExDates, 1)
+}
+
+func TestDecode_Malformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{
+			name:  "unterminated calendar",
+			input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:20200101T000000Z\r\nEND:VEVENT\r\n",
+		},
+		{
+			name:  "mismatched end",
+			input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n",
+		},
+		{
+			name:  "event without start",
+			input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:x\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		},
+		{
+			name:  "invalid date",
+			input: "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nDTSTART:2020-01-01\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n",
+		},
+		{
+			name:  "line without value",
+			input: "BEGIN:VCALENDAR\r\nGARBAGE\r\nEND:VCALENDAR\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cal, err := Decode(strings.NewReader(tt.input))
+
+			require.ErrorIs(t, err, ErrMalformedCalendar)
+			assert.Nil(t, cal)
+		})
+	}
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	original := Calendar{
+		ProdID: "-//weekbyweek//EN",
+		Name:   "Life, week by week",
+		Events: []Event{
+			{
+				UID:         "week-1@weekbyweek",
+				Summary:     "Week 1",
+				Description: strings.Repeat("long, text; ", 20),
+				Start:       time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(1992, time.November, 28, 0, 0, 0, 0, time.UTC),
+				AllDay:      true,
+				Stamp:       time.Date(2025, time.August, 1, 9, 30, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, Encode(&buf, original))
+
+	decoded, err := Decode(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, original, *decoded)
+}
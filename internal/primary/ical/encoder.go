@@ -0,0 +1,99 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405Z"
+	maxLineOctets  = 75
+)
+
+// Encode writes cal to w as an RFC 5545 iCalendar stream.
+func Encode(w io.Writer, cal Calendar) error {
+	e := &encoder{w: bufio.NewWriter(w)}
+
+	e.line("BEGIN", "VCALENDAR")
+	e.line("VERSION", "2.0")
+	e.line("PRODID", cal.ProdID)
+	e.line("CALSCALE", "GREGORIAN")
+	e.line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		e.line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+
+	for _, ev := range cal.Events {
+		e.line("BEGIN", "VEVENT")
+		e.line("UID", ev.UID)
+		e.line("DTSTAMP", ev.Stamp.UTC().Format(dateTimeFormat))
+		if ev.AllDay {
+			e.line("DTSTART;VALUE=DATE", ev.Start.Format(dateFormat))
+			e.line("DTEND;VALUE=DATE", ev.End.Format(dateFormat))
+		} else {
+			e.line("DTSTART", ev.Start.UTC().Format(dateTimeFormat))
+			e.line("DTEND", ev.End.UTC().Format(dateTimeFormat))
+		}
+		e.line("SUMMARY", escapeText(ev.Summary))
+		if ev.Description != "" {
+			e.line("DESCRIPTION", escapeText(ev.Description))
+		}
+		e.line("TRANSP", "TRANSPARENT")
+		e.line("END", "VEVENT")
+	}
+
+	e.line("END", "VCALENDAR")
+
+	if e.err != nil {
+		return e.err
+	}
+	return e.w.Flush()
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+// line writes a content line, folding it so that no physical line exceeds
+// 75 octets and no UTF-8 sequence is split.
+func (e *encoder) line(name, value string) {
+	if e.err != nil {
+		return
+	}
+
+	s := name + ":" + value
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		e.write(s[:cut] + "\r\n ")
+		s = s[cut:]
+		limit = maxLineOctets - 1
+	}
+	e.write(s + "\r\n")
+}
+
+func (e *encoder) write(s string) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = e.w.WriteString(s)
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,95 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	stamp := time.Date(2025, time.August, 1, 9, 30, 0, 0, time.UTC)
+
+	cal := Calendar{
+		ProdID: "-//weekbyweek//EN",
+		Name:   "Life, week by week",
+		Events: []Event{
+			{
+				UID:         "week-1@weekbyweek",
+				Summary:     "Week 1",
+				Description: "Hello, world; again\nand again",
+				Start:       time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(1992, time.November, 28, 0, 0, 0, 0, time.UTC),
+				AllDay:      true,
+				Stamp:       stamp,
+			},
+			{
+				UID:     "timed@weekbyweek",
+				Summary: "Timed",
+				Start:   time.Date(2025, time.August, 1, 10, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+				End:     time.Date(2025, time.August, 1, 11, 0, 0, 0, time.FixedZone("CEST", 2*3600)),
+				Stamp:   stamp,
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	err := Encode(&buf, cal)
+	require.NoError(t, err)
+
+	expected := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//weekbyweek//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		`X-WR-CALNAME:Life\, week by week`,
+		"BEGIN:VEVENT",
+		"UID:week-1@weekbyweek",
+		"DTSTAMP:20250801T093000Z",
+		"DTSTART;VALUE=DATE:19921121",
+		"DTEND;VALUE=DATE:19921128",
+		"SUMMARY:Week 1",
+		`DESCRIPTION:Hello\, world\; again\nand again`,
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:timed@weekbyweek",
+		"DTSTAMP:20250801T093000Z",
+		"DTSTART:20250801T080000Z",
+		"DTEND:20250801T090000Z",
+		"SUMMARY:Timed",
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+		"",
+	}, "\r\n")
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestEncode_FoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("é", 60)
+
+	var buf bytes.Buffer
+	err := Encode(&buf, Calendar{
+		ProdID: "-//weekbyweek//EN",
+		Events: []Event{{UID: "long", Summary: summary, AllDay: true}},
+	})
+	require.NoError(t, err)
+
+	var unfolded strings.Builder
+	for i, line := range strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, "line %d exceeds 75 octets", i)
+		assert.True(t, strings.ToValidUTF8(line, "") == line, "line %d splits a UTF-8 sequence", i)
+
+		if strings.HasPrefix(line, " ") {
+			unfolded.WriteString(line[1:])
+		} else {
+			unfolded.WriteString("\n" + line)
+		}
+	}
+	assert.Contains(t, unfolded.String(), "\nSUMMARY:"+summary+"\n")
+}
@@ -0,0 +1,206 @@
+package ical
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrUnsupportedRecurrence = errors.New("unsupported recurrence rule")
+	ErrTooManyOccurrences    = errors.New("recurrence expands to too many occurrences")
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+// Recurrence is the subset of an RRULE that can be expanded: FREQ of DAILY
+// through YEARLY with INTERVAL, COUNT, UNTIL and, for weekly rules, BYDAY.
+// Any other rule part is recorded in Unsupported.
+type Recurrence struct {
+	Frequency   Frequency
+	Interval    int
+	Count       int
+	Until       time.Time
+	ByDay       []time.Weekday
+	Unsupported []string
+}
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+func parseRecurrence(value string) (*Recurrence, error) {
+	rec := &Recurrence{Interval: 1}
+
+	for part := range strings.SplitSeq(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed RRULE part %q", part)
+		}
+
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rec.Frequency = Frequency(strings.ToUpper(val))
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE INTERVAL %q", val)
+			}
+			rec.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("invalid RRULE COUNT %q", val)
+			}
+			rec.Count = n
+		case "UNTIL":
+			t, _, err := parseTime(contentLine{name: "UNTIL", value: val})
+			if err != nil {
+				return nil, err
+			}
+			rec.Until = t
+		case "BYDAY":
+			for day := range strings.SplitSeq(val, ",") {
+				wd, ok := weekdays[strings.ToUpper(day)]
+				if !ok {
+					rec.Unsupported = append(rec.Unsupported, part)
+					break
+				}
+				rec.ByDay = append(rec.ByDay, wd)
+			}
+		case "WKST":
+		default:
+			rec.Unsupported = append(rec.Unsupported, part)
+		}
+	}
+
+	switch rec.Frequency {
+	case Daily, Weekly, Monthly, Yearly:
+	case "":
+		return nil, errors.New("RRULE has no FREQ")
+	default:
+		rec.Unsupported = append(rec.Unsupported, "FREQ="+string(rec.Frequency))
+	}
+	if len(rec.ByDay) > 0 && rec.Frequency != Weekly {
+		rec.Unsupported = append(rec.Unsupported, "BYDAY with FREQ="+string(rec.Frequency))
+	}
+
+	return rec, nil
+}
+
+// Occurrences returns the start times of e up to and including until, at
+// most limit of them. Dates listed in EXDATE are left out.
+func (e Event) Occurrences(until time.Time, limit int) ([]time.Time, error) {
+	rec := e.Recurrence
+	if rec == nil {
+		if e.Start.After(until) {
+			return nil, nil
+		}
+		return []time.Time{e.Start}, nil
+	}
+	if len(rec.Unsupported) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedRecurrence, strings.Join(rec.Unsupported, ", "))
+	}
+
+	end := until
+	if !rec.Until.IsZero() && rec.Until.Before(end) {
+		end = rec.Until
+	}
+
+	var (
+		occurrences []time.Time
+		generated   int
+	)
+	for period := 0; ; period++ {
+		candidates := rec.period(e.Start, period)
+		if len(candidates) == 0 {
+			continue
+		}
+		if candidates[0].After(end) {
+			return occurrences, nil
+		}
+
+		for _, t := range candidates {
+			if t.Before(e.Start) || t.After(end) {
+				continue
+			}
+			if rec.Count > 0 && generated >= rec.Count {
+				return occurrences, nil
+			}
+			generated++
+
+			if e.excluded(t) {
+				continue
+			}
+			if len(occurrences) >= limit {
+				return nil, ErrTooManyOccurrences
+			}
+			occurrences = append(occurrences, t)
+		}
+	}
+}
+
+// period returns the candidate occurrences in the nth period after start.
+// Monthly and yearly periods that would fall on a day that does not exist,
+// such as 31 April, yield no candidates.
+func (r *Recurrence) period(start time.Time, n int) []time.Time {
+	step := n * r.Interval
+
+	switch r.Frequency {
+	case Daily:
+		return []time.Time{start.AddDate(0, 0, step)}
+	case Weekly:
+		if len(r.ByDay) == 0 {
+			return []time.Time{start.AddDate(0, 0, 7*step)}
+		}
+		monday := start.AddDate(0, 0, 7*step-(int(start.Weekday())+6)%7)
+		days := make([]time.Time, 0, len(r.ByDay))
+		for _, wd := range r.ByDay {
+			days = append(days, monday.AddDate(0, 0, (int(wd)+6)%7))
+		}
+		slices.SortFunc(days, func(a, b time.Time) int { return a.Compare(b) })
+		return days
+	case Monthly:
+		t := start.AddDate(0, step, 0)
+		if t.Day() != start.Day() {
+			return nil
+		}
+		return []time.Time{t}
+	case Yearly:
+		t := start.AddDate(step, 0, 0)
+		if t.Day() != start.Day() {
+			return nil
+		}
+		return []time.Time{t}
+	}
+	return nil
+}
+
+func (e Event) excluded(t time.Time) bool {
+	for _, ex := range e.ExDates {
+		if e.AllDay {
+			if ex.Format(dateFormat) == t.Format(dateFormat) {
+				return true
+			}
+		} else if ex.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
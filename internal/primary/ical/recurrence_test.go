@@ -0,0 +1,168 @@
+package ical
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOccurrences(t *testing.T) {
+	start := time.Date(2020, time.January, 6, 9, 30, 0, 0, time.UTC)
+	until := time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	date := func(month time.Month, day int) time.Time {
+		return time.Date(2020, month, day, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name        string
+		event       Event
+		limit       int
+		expected    []time.Time
+		expectedErr error
+	}{
+		{
+			name:     "single event",
+			event:    Event{Start: start},
+			limit:    10,
+			expected: []time.Time{start},
+		},
+		{
+			name:     "single event after until",
+			event:    Event{Start: until.AddDate(0, 0, 1)},
+			limit:    10,
+			expected: nil,
+		},
+		{
+			name:     "daily with count",
+			event:    Event{Start: start, Recurrence: &Recurrence{Frequency: Daily, Interval: 1, Count: 3}},
+			limit:    10,
+			expected: []time.Time{date(time.January, 6), date(time.January, 7), date(time.January, 8)},
+		},
+		{
+			name: "weekly by day with exdate",
+			event: Event{
+				Start:      start,
+				Recurrence: &Recurrence{Frequency: Weekly, Interval: 1, Count: 4, ByDay: []time.Weekday{time.Wednesday, time.Monday}},
+				ExDates:    []time.Time{date(time.January, 8)},
+			},
+			limit:    10,
+			expected: []time.Time{date(time.January, 6), date(time.January, 13), date(time.January, 15)},
+		},
+		{
+			name: "fortnightly until",
+			event: Event{
+				Start:      start,
+				Recurrence: &Recurrence{Frequency: Weekly, Interval: 2, Until: date(time.February, 3)},
+			},
+			limit:    10,
+			expected: []time.Time{date(time.January, 6), date(time.January, 20), date(time.February, 3)},
+		},
+		{
+			name: "monthly skips months without the day",
+			event: Event{
+				Start:      date(time.January, 31),
+				Recurrence: &Recurrence{Frequency: Monthly, Interval: 1, Count: 3},
+			},
+			limit:    10,
+			expected: []time.Time{date(time.January, 31), date(time.March, 31), date(time.May, 31)},
+		},
+		{
+			name: "yearly stops at until",
+			event: Event{
+				Start:      time.Date(2018, time.June, 12, 0, 0, 0, 0, time.UTC),
+				AllDay:     true,
+				Recurrence: &Recurrence{Frequency: Yearly, Interval: 1},
+			},
+			limit: 10,
+			expected: []time.Time{
+				time.Date(2018, time.June, 12, 0, 0, 0, 0, time.UTC),
+				time.Date(2019, time.June, 12, 0, 0, 0, 0, time.UTC),
+				time.Date(2020, time.June, 12, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:        "too many occurrences",
+			event:       Event{Start: start, Recurrence: &Recurrence{Frequency: Daily, Interval: 1}},
+			limit:       10,
+			expectedErr: ErrTooManyOccurrences,
+		},
+		{
+			name:        "unsupported rule",
+			event:       Event{Start: start, Recurrence: &Recurrence{Frequency: Monthly, Interval: 1, Unsupported: []string{"BYSETPOS=-1"}}},
+			limit:       10,
+			expectedErr: ErrUnsupportedRecurrence,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			occurrences, err := tt.event.Occurrences(until, tt.limit)
+
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, occurrences)
+		})
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		expected    *Recurrence
+		expectError bool
+	}{
+		{
+			name:     "weekly by day",
+			value:    "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;WKST=SU",
+			expected: &Recurrence{Frequency: Weekly, Interval: 2, ByDay: []time.Weekday{time.Monday, time.Friday}},
+		},
+		{
+			name:  "until in utc",
+			value: "FREQ=DAILY;UNTIL=20200110T000000Z",
+			expected: &Recurrence{
+				Frequency: Daily,
+				Interval:  1,
+				Until:     time.Date(2020, time.January, 10, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			name:  "unsupported parts are recorded",
+			value: "FREQ=MONTHLY;BYDAY=-1FR",
+			expected: &Recurrence{
+				Frequency:   Monthly,
+				Interval:    1,
+				Unsupported: []string{"BYDAY=-1FR"},
+			},
+		},
+		{
+			name:        "missing frequency",
+			value:       "COUNT=3",
+			expectError: true,
+		},
+		{
+			name:        "invalid interval",
+			value:       "FREQ=DAILY;INTERVAL=0",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := parseRecurrence(tt.value)
+
+			if tt.expectError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, rec)
+		})
+	}
+}
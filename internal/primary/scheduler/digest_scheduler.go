@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/app/digest"
+)
+
+type DigestScheduler struct {
+	digestService digest.Service
+	interval      time.Duration
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewDigestScheduler(service digest.Service, interval time.Duration) *DigestScheduler {
+	return &DigestScheduler{
+		digestService: service,
+		interval:      interval,
+	}
+}
+
+// Run sends due digests straight away and then every interval until ctx is
+// cancelled. Weeks roll over at each user's local midnight, so the interval
+// bounds how late after midnight a digest can arrive.
+func (s *DigestScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start runs the scheduler in the background until Stop is called.
+func (s *DigestScheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	s.done = make(chan struct{})
+
+	go func() {
+		defer close(s.done)
+		s.Run(ctx)
+	}()
+}
+
+// Stop cancels the scheduler and waits for an in-progress run to return.
+func (s *DigestScheduler) Stop(ctx context.Context) error {
+	if s.cancel == nil {
+		return nil
+	}
+
+	s.cancel()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *DigestScheduler) runOnce(ctx context.Context) {
+	sent, err := s.digestService.SendDueDigests(ctx)
+	if err != nil && ctx.Err() == nil {
+		slog.ErrorContext(ctx, "sending weekly digests", "error", err)
+	}
+	if sent > 0 {
+		slog.InfoContext(ctx, "sent weekly digests", "count", sent)
+	}
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDigestService struct {
+	calls atomic.Int32
+	err   error
+}
+
+func (f *fakeDigestService) SendDueDigests(ctx context.Context) (int, error) {
+	f.calls.Add(1)
+	return 0, f.err
+}
+
+func TestDigestScheduler_Run(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "runs until cancelled", err: nil},
+		{name: "keeps running after errors", err: errors.New("mail server unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := &fakeDigestService{err: tt.err}
+			scheduler := NewDigestScheduler(service, 5*time.Millisecond)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				scheduler.Run(ctx)
+				close(done)
+			}()
+
+			assert.Eventually(t, func() bool { return service.calls.Load() >= 3 }, time.Second, time.Millisecond)
+
+			cancel()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("scheduler did not stop after cancellation")
+			}
+		})
+	}
+}
+
+func TestDigestScheduler_StartStop(t *testing.T) {
+	service := &fakeDigestService{}
+	scheduler := NewDigestScheduler(service, 5*time.Millisecond)
+
+	scheduler.Start(context.Background())
+	assert.Eventually(t, func() bool { return service.calls.Load() >= 2 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, scheduler.Stop(ctx))
+
+	calls := service.calls.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, calls, service.calls.Load(), "scheduler kept running after Stop")
+}
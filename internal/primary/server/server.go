@@ -0,0 +1,160 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+type Config struct {
+	Addr              string
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	ShutdownTimeout   time.Duration
+	// DrainDelay keeps serving for a while after shutdown begins, giving
+	// load balancers time to notice failing readiness checks.
+	DrainDelay time.Duration
+}
+
+// DefaultConfig keeps slow or idle clients from holding connections open
+// indefinitely. The write timeout covers the calendar import, the slowest
+// handler, which parses uploads of up to 10MB.
+var DefaultConfig = Config{
+	Addr:              ":8080",
+	ReadTimeout:       15 * time.Second,
+	ReadHeaderTimeout: 5 * time.Second,
+	WriteTimeout:      30 * time.Second,
+	IdleTimeout:       60 * time.Second,
+	MaxHeaderBytes:    64 << 10,
+	ShutdownTimeout:   20 * time.Second,
+}
+
+// StopFunc stops a background component, returning once it has finished or
+// ctx has expired.
+type StopFunc func(ctx context.Context) error
+
+type Server struct {
+	httpServer      *http.Server
+	shutdownTimeout time.Duration
+	drainDelay      time.Duration
+	listener        net.Listener
+	draining        []func()
+	stops           []StopFunc
+}
+
+func New(handler http.Handler, config Config) *Server {
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = DefaultConfig.ShutdownTimeout
+	}
+
+	return &Server{
+		httpServer: &http.Server{
+			Addr:              config.Addr,
+			Handler:           handler,
+			ReadTimeout:       config.ReadTimeout,
+			ReadHeaderTimeout: config.ReadHeaderTimeout,
+			WriteTimeout:      config.WriteTimeout,
+			IdleTimeout:       config.IdleTimeout,
+			MaxHeaderBytes:    config.MaxHeaderBytes,
+			ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		},
+		shutdownTimeout: config.ShutdownTimeout,
+		drainDelay:      config.DrainDelay,
+	}
+}
+
+// OnDrain registers fn to run as soon as shutdown begins, while requests are
+// still being served.
+func (s *Server) OnDrain(fn func()) {
+	s.draining = append(s.draining, fn)
+}
+
+// OnShutdown registers stop to run after the HTTP server has drained.
+// Components are stopped in registration order, so register producers of
+// background work before the workers that consume it.
+func (s *Server) OnShutdown(stop StopFunc) {
+	s.stops = append(s.stops, stop)
+}
+
+// Listen binds the server's address. Calling it before Run surfaces
+// startup failures such as a port already in use, and lets callers that
+// bind ":0" find the chosen port through Addr.
+func (s *Server) Listen() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", s.httpServer.Addr, err)
+	}
+	s.listener = listener
+	return nil
+}
+
+func (s *Server) Addr() net.Addr {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Run serves requests until ctx is cancelled and then shuts down: it runs
+// the drain hooks and waits out the drain delay, stops accepting
+// connections, waits for in-flight requests and then stops the registered
+// components, the last two within the shutdown timeout. It returns an error
+// if the server could not start or did not shut down cleanly.
+func (s *Server) Run(ctx context.Context) error {
+	if s.listener == nil {
+		if err := s.Listen(); err != nil {
+			return err
+		}
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.httpServer.Serve(s.listener)
+	}()
+
+	slog.Info("server listening", "addr", s.listener.Addr().String())
+
+	select {
+	case err := <-serveErr:
+		return errors.Join(fmt.Errorf("serving: %w", err), s.shutdown())
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down")
+	for _, fn := range s.draining {
+		fn()
+	}
+	if s.drainDelay > 0 {
+		time.Sleep(s.drainDelay)
+	}
+
+	err := s.shutdown()
+	if serveErr := <-serveErr; !errors.Is(serveErr, http.ErrServerClosed) {
+		err = errors.Join(fmt.Errorf("serving: %w", serveErr), err)
+	}
+	return err
+}
+
+func (s *Server) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := s.httpServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("draining requests: %w", err))
+		s.httpServer.Close()
+	}
+	for _, stop := range s.stops {
+		if err := stop(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
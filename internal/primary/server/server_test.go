@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() Config {
+	config := DefaultConfig
+	config.Addr = "127.0.0.1:0"
+	config.ShutdownTimeout = time.Second
+	return config
+}
+
+func startServer(t *testing.T, srv *Server) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+	require.NoError(t, srv.Listen())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Run(ctx) }()
+
+	return "http://" + srv.Addr().String(), cancel, done
+}
+
+func waitForExit(t *testing.T, done <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down")
+		return nil
+	}
+}
+
+func TestServer_ServesUntilCancelled(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello")
+	})
+	srv := New(handler, testConfig())
+
+	var stopped []string
+	srv.OnShutdown(func(ctx context.Context) error {
+		stopped = append(stopped, "scheduler")
+		return nil
+	})
+	srv.OnShutdown(func(ctx context.Context) error {
+		stopped = append(stopped, "workers")
+		return nil
+	})
+
+	url, cancel, done := startServer(t, srv)
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "hello", string(body))
+
+	cancel()
+	require.NoError(t, waitForExit(t, done))
+	assert.Equal(t, []string{"scheduler", "workers"}, stopped)
+
+	_, err = http.Get(url)
+	assert.Error(t, err, "server still accepting connections after shutdown")
+}
+
+func TestServer_DrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		io.WriteString(w, "finished")
+	})
+	srv := New(handler, testConfig())
+
+	var workersStoppedAfterDrain bool
+	requestDone := make(chan struct{})
+	srv.OnShutdown(func(ctx context.Context) error {
+		select {
+		case <-requestDone:
+			workersStoppedAfterDrain = true
+		default:
+		}
+		return nil
+	})
+
+	url, cancel, done := startServer(t, srv)
+
+	var body []byte
+	go func() {
+		defer close(requestDone)
+		resp, err := http.Get(url)
+		if err != nil {
+			return
+		}
+		defer resp.Body.Close()
+		body, _ = io.ReadAll(resp.Body)
+	}()
+
+	<-started
+	cancel()
+
+	require.NoError(t, waitForExit(t, done))
+	<-requestDone
+	assert.Equal(t, "finished", string(body))
+	assert.True(t, workersStoppedAfterDrain)
+}
+
+func TestServer_BoundedDrain(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	config := testConfig()
+	config.ShutdownTimeout = 50 * time.Millisecond
+	srv := New(handler, config)
+
+	stopErr := errors.New("workers did not stop")
+	srv.OnShutdown(func(ctx context.Context) error {
+		return stopErr
+	})
+
+	url, cancel, done := startServer(t, srv)
+	go http.Get(url)
+
+	<-started
+	cancel()
+
+	err := waitForExit(t, done)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, stopErr)
+}
+
+func TestServer_DrainDelay(t *testing.T) {
+	config := testConfig()
+	config.DrainDelay = 100 * time.Millisecond
+	srv := New(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "still serving")
+	}), config)
+
+	draining := make(chan struct{})
+	srv.OnDrain(func() { close(draining) })
+
+	url, cancel, done := startServer(t, srv)
+	cancel()
+
+	select {
+	case <-draining:
+	case <-time.After(time.Second):
+		t.Fatal("drain hook was not called")
+	}
+
+	resp, err := http.Get(url)
+	require.NoError(t, err, "server stopped accepting requests during the drain delay")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "still serving", string(body))
+
+	require.NoError(t, waitForExit(t, done))
+}
+
+func TestServer_StartupFailure(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer taken.Close()
+
+	config := testConfig()
+	config.Addr = taken.Addr().String()
+	srv := New(http.NotFoundHandler(), config)
+
+	err = srv.Run(context.Background())
+	assert.ErrorContains(t, err, "listening on "+config.Addr)
+}
+
+func TestServer_HeaderLimits(t *testing.T) {
+	config := testConfig()
+	config.MaxHeaderBytes = 1 << 10
+	srv := New(http.NotFoundHandler(), config)
+	url, cancel, done := startServer(t, srv)
+	defer func() {
+		cancel()
+		waitForExit(t, done)
+	}()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header.Set("X-Padding", strings.Repeat("a", 8<<10))
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusRequestHeaderFieldsTooLarge, resp.StatusCode)
+}
@@ -0,0 +1,248 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+)
+
+type Config struct {
+	Concurrency  int
+	PollInterval time.Duration
+	Lease        time.Duration
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+var DefaultConfig = Config{
+	Concurrency:  4,
+	PollInterval: time.Second,
+	Lease:        5 * time.Minute,
+	BaseBackoff:  time.Second,
+	MaxBackoff:   time.Hour,
+}
+
+type handlerFunc func(ctx context.Context, payload []byte) error
+
+// errStopped cancels jobs still running when Stop gives up waiting.
+var errStopped = errors.New("worker pool stopped")
+
+// Pool runs a bounded number of workers that claim jobs from a store and
+// dispatch them to handlers by job type.
+type Pool struct {
+	store    job.Store
+	config   Config
+	handlers map[string]handlerFunc
+	buried   map[string]handlerFunc
+	now      func() time.Time
+
+	cancel  context.CancelFunc
+	abort   context.CancelCauseFunc
+	wg      sync.WaitGroup
+	running atomic.Bool
+}
+
+func NewPool(store job.Store, config Config) *Pool {
+	if config.Concurrency < 1 {
+		config.Concurrency = DefaultConfig.Concurrency
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultConfig.PollInterval
+	}
+	if config.Lease <= 0 {
+		config.Lease = DefaultConfig.Lease
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = DefaultConfig.BaseBackoff
+	}
+	if config.MaxBackoff < config.BaseBackoff {
+		config.MaxBackoff = max(DefaultConfig.MaxBackoff, config.BaseBackoff)
+	}
+
+	return &Pool{
+		store:    store,
+		config:   config,
+		handlers: make(map[string]handlerFunc),
+		buried:   make(map[string]handlerFunc),
+		now:      time.Now,
+	}
+}
+
+// Register installs the handler for payloads of type T. It must be called
+// before Start.
+func Register[T job.Payload](p *Pool, handle func(ctx context.Context, payload T) error) {
+	var zero T
+	p.handlers[zero.JobType()] = decode(handle)
+}
+
+// OnBury installs a function that is called with the payload of a job of
+// type T once it has been dead-lettered. It must be called before Start.
+func OnBury[T job.Payload](p *Pool, handle func(ctx context.Context, payload T) error) {
+	var zero T
+	p.buried[zero.JobType()] = decode(handle)
+}
+
+func decode[T job.Payload](handle func(ctx context.Context, payload T) error) handlerFunc {
+	return func(ctx context.Context, data []byte) error {
+		var payload T
+		if err := json.Unmarshal(data, &payload); err != nil {
+			return fmt.Errorf("decoding payload: %w", err)
+		}
+		return handle(ctx, payload)
+	}
+}
+
+// Start launches the workers. They keep running until Stop is called.
+// Jobs run in a context of their own, which only Stop cancels.
+func (p *Pool) Start(ctx context.Context) {
+	var jobs context.Context
+	jobs, p.abort = context.WithCancelCause(context.WithoutCancel(ctx))
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.running.Store(true)
+
+	for range p.config.Concurrency {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx, jobs)
+		}()
+	}
+}
+
+// Stop stops claiming new jobs and waits for running ones to finish. If ctx
+// expires first the in-flight jobs are cancelled, and Stop still waits for
+// their handlers to return, so that nothing uses the store once it has
+// returned. Their leases lapse and they are picked up again on the next
+// start.
+func (p *Pool) Stop(ctx context.Context) error {
+	if p.cancel == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	p.running.Store(false)
+	p.cancel()
+	defer p.abort(errStopped)
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.abort(errStopped)
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Check reports whether the workers are running. The store behind them is
+// checked with the rest of the storage.
+func (p *Pool) Check(ctx context.Context) error {
+	if !p.running.Load() {
+		return errors.New("worker pool is not running")
+	}
+	return nil
+}
+
+func (p *Pool) work(ctx, jobs context.Context) {
+	for {
+		j, err := p.store.Claim(ctx, p.now().UTC(), p.config.Lease)
+		if err == nil {
+			p.process(jobs, j)
+			continue
+		}
+		if !errors.Is(err, job.ErrNoJobAvailable) && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "claiming job", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(p.config.PollInterval):
+		}
+	}
+}
+
+// process runs a claimed job to completion. ctx is not the pool's, so that
+// Stop drains in-flight jobs rather than abandoning them halfway. The
+// outcome is recorded even if Stop cancels the job meanwhile, except that a
+// job cut short that way is left for its lease to lapse rather than counted
+// as a failed attempt.
+func (p *Pool) process(ctx context.Context, j *job.Job) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.Lease)
+	defer cancel()
+
+	handle, exists := p.handlers[j.Type]
+	if !exists {
+		p.bury(ctx, j, fmt.Errorf("no handler registered for job type %q", j.Type))
+		return
+	}
+
+	err := p.run(ctx, handle, j.Payload)
+	store := context.WithoutCancel(ctx)
+	switch {
+	case err != nil && errors.Is(context.Cause(ctx), errStopped):
+		slog.WarnContext(ctx, "job interrupted by shutdown", "job_id", j.ID, "job_type", j.Type, "error", err)
+	case err == nil:
+		if err := p.store.Complete(store, j.ID, j.Attempts); err != nil {
+			slog.ErrorContext(ctx, "completing job", "job_id", j.ID, "error", err)
+		}
+	case j.Attempts >= j.MaxAttempts:
+		p.bury(ctx, j, err)
+	default:
+		runAt := p.now().UTC().Add(p.backoff(j.Attempts))
+		slog.WarnContext(ctx, "job failed, retrying",
+			"job_id", j.ID, "job_type", j.Type, "attempt", j.Attempts, "run_at", runAt, "error", err)
+		if err := p.store.Retry(store, j.ID, j.Attempts, runAt, err.Error()); err != nil {
+			slog.ErrorContext(ctx, "rescheduling job", "job_id", j.ID, "error", err)
+		}
+	}
+}
+
+func (p *Pool) run(ctx context.Context, handle handlerFunc, payload []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return handle(ctx, payload)
+}
+
+func (p *Pool) bury(ctx context.Context, j *job.Job, cause error) {
+	slog.ErrorContext(ctx, "job failed permanently",
+		"job_id", j.ID, "job_type", j.Type, "attempts", j.Attempts, "error", cause)
+	if err := p.store.Bury(context.WithoutCancel(ctx), j.ID, j.Attempts, cause.Error()); err != nil {
+		slog.ErrorContext(ctx, "burying job", "job_id", j.ID, "error", err)
+		return
+	}
+
+	if handle, exists := p.buried[j.Type]; exists {
+		if err := p.run(ctx, handle, j.Payload); err != nil {
+			slog.ErrorContext(ctx, "handling buried job", "job_id", j.ID, "job_type", j.Type, "error", err)
+		}
+	}
+}
+
+// backoff doubles the delay with every attempt, capped at MaxBackoff, and
+// picks a random point in the upper half so retries of jobs that failed
+// together spread out.
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.config.BaseBackoff
+	for i := 1; i < attempt && delay < p.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.config.MaxBackoff)
+	half := delay / 2
+	return half + rand.N(half+1)
+}
@@ -0,0 +1,232 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type greet struct {
+	Name string `json:"name"`
+}
+
+func (greet) JobType() string { return "greet" }
+
+type unhandled struct{}
+
+func (unhandled) JobType() string { return "unhandled" }
+
+var testConfig = Config{
+	Concurrency:  2,
+	PollInterval: time.Millisecond,
+	Lease:        time.Second,
+	BaseBackoff:  time.Millisecond,
+	MaxBackoff:   2 * time.Millisecond,
+}
+
+func enqueue(t *testing.T, store job.Store, payload job.Payload, maxAttempts int) *job.Job {
+	t.Helper()
+	j, err := job.New(payload, maxAttempts, time.Time{})
+	require.NoError(t, err)
+	require.NoError(t, store.Enqueue(context.Background(), j))
+	return j
+}
+
+func deadLetters(t *testing.T, store job.Store) []*job.Job {
+	t.Helper()
+	dead, err := store.DeadLetters(context.Background())
+	require.NoError(t, err)
+	return dead
+}
+
+func TestPool(t *testing.T) {
+	t.Run("processes jobs with typed payloads", func(t *testing.T) {
+		store := memory.NewJobStore()
+		enqueue(t, store, greet{Name: "Ada"}, 1)
+
+		got := make(chan string, 1)
+		pool := NewPool(store, testConfig)
+		Register(pool, func(ctx context.Context, g greet) error {
+			got <- g.Name
+			return nil
+		})
+		pool.Start(context.Background())
+		defer pool.Stop(context.Background())
+
+		select {
+		case name := <-got:
+			assert.Equal(t, "Ada", name)
+		case <-time.After(time.Second):
+			t.Fatal("job was not processed")
+		}
+	})
+
+	t.Run("retries failures until they succeed", func(t *testing.T) {
+		store := memory.NewJobStore()
+		enqueue(t, store, greet{}, 5)
+
+		var calls atomic.Int32
+		done := make(chan struct{})
+		pool := NewPool(store, testConfig)
+		Register(pool, func(ctx context.Context, g greet) error {
+			if calls.Add(1) < 3 {
+				return errors.New("temporary failure")
+			}
+			close(done)
+			return nil
+		})
+		pool.Start(context.Background())
+		defer pool.Stop(context.Background())
+
+		select {
+		case <-done:
+			assert.Equal(t, int32(3), calls.Load())
+		case <-time.After(time.Second):
+			t.Fatal("job was not retried")
+		}
+	})
+
+	t.Run("dead letters exhausted, panicking and unknown jobs", func(t *testing.T) {
+		store := memory.NewJobStore()
+		enqueue(t, store, greet{Name: "fail"}, 2)
+		enqueue(t, store, greet{Name: "panic"}, 1)
+		enqueue(t, store, unhandled{}, 3)
+
+		pool := NewPool(store, testConfig)
+		Register(pool, func(ctx context.Context, g greet) error {
+			if g.Name == "panic" {
+				panic("handler bug")
+			}
+			return errors.New("permanent failure")
+		})
+		pool.Start(context.Background())
+		defer pool.Stop(context.Background())
+
+		require.Eventually(t, func() bool {
+			return len(deadLetters(t, store)) == 3
+		}, time.Second, time.Millisecond)
+
+		errs := map[string]string{}
+		for _, j := range deadLetters(t, store) {
+			errs[j.Type+":"+string(j.Payload)] = j.LastError
+		}
+		assert.Equal(t, "permanent failure", errs[`greet:{"name":"fail"}`])
+		assert.Equal(t, "panic: handler bug", errs[`greet:{"name":"panic"}`])
+		assert.Contains(t, errs["unhandled:{}"], "no handler registered")
+	})
+
+	t.Run("buried jobs are handed to their bury handler", func(t *testing.T) {
+		store := memory.NewJobStore()
+		enqueue(t, store, greet{Name: "fail"}, 1)
+
+		buried := make(chan string, 1)
+		pool := NewPool(store, testConfig)
+		Register(pool, func(ctx context.Context, g greet) error {
+			return errors.New("permanent failure")
+		})
+		OnBury(pool, func(ctx context.Context, g greet) error {
+			buried <- g.Name
+			return nil
+		})
+		pool.Start(context.Background())
+		defer pool.Stop(context.Background())
+
+		select {
+		case name := <-buried:
+			assert.Equal(t, "fail", name)
+			assert.Len(t, deadLetters(t, store), 1)
+		case <-time.After(time.Second):
+			t.Fatal("bury handler was not called")
+		}
+	})
+
+	t.Run("stop drains in-flight jobs", func(t *testing.T) {
+		store := memory.NewJobStore()
+		enqueue(t, store, greet{}, 1)
+
+		started := make(chan struct{})
+		var finished atomic.Bool
+		pool := NewPool(store, testConfig)
+		Register(pool, func(ctx context.Context, g greet) error {
+			close(started)
+			time.Sleep(50 * time.Millisecond)
+			finished.Store(true)
+			return ctx.Err()
+		})
+		pool.Start(context.Background())
+		<-started
+
+		require.NoError(t, pool.Stop(context.Background()))
+		assert.True(t, finished.Load())
+		assert.Empty(t, deadLetters(t, store))
+	})
+
+	t.Run("stop cancels in-flight jobs when its context expires", func(t *testing.T) {
+		store := memory.NewJobStore()
+		j := enqueue(t, store, greet{}, 1)
+
+		started := make(chan struct{})
+		var returned atomic.Bool
+		pool := NewPool(store, testConfig)
+		Register(pool, func(ctx context.Context, g greet) error {
+			close(started)
+			<-ctx.Done()
+			time.Sleep(10 * time.Millisecond)
+			returned.Store(true)
+			return ctx.Err()
+		})
+		pool.Start(context.Background())
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, pool.Stop(ctx), context.DeadlineExceeded)
+		assert.True(t, returned.Load(), "stop waits for the cancelled job")
+
+		assert.Empty(t, deadLetters(t, store), "the interrupted attempt is not counted")
+		again, err := store.Claim(context.Background(), time.Now().Add(testConfig.Lease), testConfig.Lease)
+		require.NoError(t, err, "the job is picked up again once its lease lapses")
+		assert.Equal(t, j.ID, again.ID)
+	})
+}
+
+func TestPool_Backoff(t *testing.T) {
+	pool := NewPool(memory.NewJobStore(), Config{BaseBackoff: time.Second, MaxBackoff: time.Minute})
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{attempt: 1, min: 500 * time.Millisecond, max: time.Second},
+		{attempt: 2, min: time.Second, max: 2 * time.Second},
+		{attempt: 4, min: 4 * time.Second, max: 8 * time.Second},
+		{attempt: 10, min: 30 * time.Second, max: time.Minute},
+		{attempt: 200, min: 30 * time.Second, max: time.Minute},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			delay := pool.backoff(tt.attempt)
+			assert.GreaterOrEqual(t, delay, tt.min, "attempt %d", tt.attempt)
+			assert.LessOrEqual(t, delay, tt.max, "attempt %d", tt.attempt)
+		}
+	}
+}
+
+func TestPool_Check(t *testing.T) {
+	pool := NewPool(memory.NewJobStore(), testConfig)
+	assert.Error(t, pool.Check(context.Background()), "before start")
+
+	pool.Start(context.Background())
+	assert.NoError(t, pool.Check(context.Background()))
+
+	require.NoError(t, pool.Stop(context.Background()))
+	assert.Error(t, pool.Check(context.Background()), "after stop")
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+)
+
+// LockoutPolicy locks an account after Threshold consecutive failed
+// password checks: for Base at first, doubling with every further failure
+// up to Max. Failures are forgotten after a quiet period of Max. A zero
+// Threshold disables lockout.
+type LockoutPolicy struct {
+	Threshold int
+	Base      time.Duration
+	Max       time.Duration
+}
+
+func (p LockoutPolicy) LockedUntil(f Failures) time.Time {
+	if p.Threshold <= 0 || f.Count < p.Threshold {
+		return time.Time{}
+	}
+	d := p.Base
+	for i := p.Threshold; i < f.Count && d < p.Max; i++ {
+		d *= 2
+	}
+	return f.Last.Add(min(d, p.Max))
+}
+
+// Failures counts consecutive failures for one key.
+type Failures struct {
+	Count int
+	Last  time.Time
+}
+
+// FailureStore keeps failure counts. AddFailure must be atomic per key.
+type FailureStore interface {
+	Failures(ctx context.Context, key string) (Failures, error)
+	// AddFailure records a failure at now, first forgetting the count if
+	// the last failure happened before since.
+	AddFailure(ctx context.Context, key string, now, since time.Time) (Failures, error)
+	ResetFailures(ctx context.Context, key string) error
+}
+
+// LockedError reports a lockout. It matches user.ErrAccountLocked.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s until %s", user.ErrAccountLocked, e.Until.Format(time.RFC3339))
+}
+
+func (e *LockedError) Is(target error) bool {
+	return target == user.ErrAccountLocked
+}
+
+type Lockout struct {
+	store  FailureStore
+	policy LockoutPolicy
+	now    func() time.Time
+}
+
+func NewLockout(store FailureStore, policy LockoutPolicy) *Lockout {
+	return &Lockout{store: store, policy: policy, now: time.Now}
+}
+
+type lockoutHasher struct {
+	user.PasswordHasher
+	lockout *Lockout
+}
+
+// PasswordHasher guards Compare with the lockout policy. Accounts are told
+// apart by their stored hash, which is unique per account thanks to the
+// salt, so any caller of Compare is protected without passing an account
+// ID. A password change starts a fresh count.
+func (l *Lockout) PasswordHasher(next user.PasswordHasher) user.PasswordHasher {
+	return &lockoutHasher{PasswordHasher: next, lockout: l}
+}
+
+// Compare fails closed: if the failure store cannot be read, the password
+// is not checked.
+func (h *lockoutHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	l := h.lockout
+	key := lockoutKey(hashedPassword)
+
+	failures, err := l.store.Failures(ctx, key)
+	if err != nil {
+		return fmt.Errorf("reading failed attempts: %w", err)
+	}
+	now := l.now()
+	if until := l.policy.LockedUntil(failures); now.Before(until) {
+		return &LockedError{Until: until}
+	}
+
+	err = h.PasswordHasher.Compare(ctx, hashedPassword, password)
+	if err == nil {
+		if failures.Count > 0 {
+			return l.store.ResetFailures(ctx, key)
+		}
+		return nil
+	}
+	if ctx.Err() != nil {
+		return err
+	}
+	if _, serr := l.store.AddFailure(ctx, key, now, now.Add(-l.policy.Max)); serr != nil {
+		return fmt.Errorf("%w (recording failed attempt: %w)", err, serr)
+	}
+	return err
+}
+
+func lockoutKey(hashedPassword string) string {
+	sum := sha256.Sum256([]byte(hashedPassword))
+	return "lockout:" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMismatch = errors.New("mismatch")
+
+type plainHasher struct{}
+
+func (plainHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hash:" + password, nil
+}
+
+func (plainHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	if hashedPassword != "hash:"+password {
+		return errMismatch
+	}
+	return nil
+}
+
+type mockFailureStore struct {
+	mu       sync.Mutex
+	failures map[string]Failures
+	err      error
+}
+
+func newMockFailureStore() *mockFailureStore {
+	return &mockFailureStore{failures: map[string]Failures{}}
+}
+
+func (s *mockFailureStore) Failures(ctx context.Context, key string) (Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[key], s.err
+}
+
+func (s *mockFailureStore) AddFailure(ctx context.Context, key string, now, since time.Time) (Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f := s.failures[key]
+	if f.Last.Before(since) {
+		f.Count = 0
+	}
+	f.Count++
+	f.Last = now
+	s.failures[key] = f
+	return f, s.err
+}
+
+func (s *mockFailureStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	return s.err
+}
+
+func TestLockoutPolicy_LockedUntil(t *testing.T) {
+	policy := LockoutPolicy{Threshold: 3, Base: time.Minute, Max: 10 * time.Minute}
+	last := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		count int
+		want  time.Duration
+	}{
+		{count: 0},
+		{count: 2},
+		{count: 3, want: time.Minute},
+		{count: 4, want: 2 * time.Minute},
+		{count: 5, want: 4 * time.Minute},
+		{count: 6, want: 8 * time.Minute},
+		{count: 7, want: 10 * time.Minute},
+		{count: 100, want: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		got := policy.LockedUntil(Failures{Count: tt.count, Last: last})
+		if tt.want == 0 {
+			assert.True(t, got.IsZero(), "count %d", tt.count)
+			continue
+		}
+		assert.Equal(t, last.Add(tt.want), got, "count %d", tt.count)
+	}
+
+	assert.True(t, LockoutPolicy{}.LockedUntil(Failures{Count: 100, Last: last}).IsZero(), "disabled")
+}
+
+func TestLockout_PasswordHasher(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newHasher := func(store FailureStore) (user.PasswordHasher, *time.Time) {
+		lockout := NewLockout(store, LockoutPolicy{Threshold: 2, Base: time.Minute, Max: time.Hour})
+		clock := now
+		lockout.now = func() time.Time { return clock }
+		return lockout.PasswordHasher(plainHasher{}), &clock
+	}
+
+	t.Run("locks after repeated failures", func(t *testing.T) {
+		hasher, clock := newHasher(newMockFailureStore())
+		hash, err := hasher.Hash(ctx, "correct")
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, hasher.Compare(ctx, hash, "wrong"), errMismatch)
+		assert.ErrorIs(t, hasher.Compare(ctx, hash, "wrong"), errMismatch)
+
+		err = hasher.Compare(ctx, hash, "correct")
+		assert.ErrorIs(t, err, user.ErrAccountLocked, "even the right password is refused while locked")
+		var locked *LockedError
+		require.ErrorAs(t, err, &locked)
+		assert.Equal(t, now.Add(time.Minute), locked.Until)
+
+		*clock = now.Add(time.Minute)
+		assert.ErrorIs(t, hasher.Compare(ctx, hash, "wrong"), errMismatch)
+		*clock = now.Add(2 * time.Minute)
+		assert.ErrorIs(t, hasher.Compare(ctx, hash, "correct"), user.ErrAccountLocked, "lockout doubles")
+		*clock = now.Add(3 * time.Minute)
+		assert.NoError(t, hasher.Compare(ctx, hash, "correct"))
+
+		assert.ErrorIs(t, hasher.Compare(ctx, hash, "wrong"), errMismatch)
+		assert.NoError(t, hasher.Compare(ctx, hash, "correct"), "success resets the count")
+	})
+
+	t.Run("accounts are independent", func(t *testing.T) {
+		hasher, _ := newHasher(newMockFailureStore())
+		ada, _ := hasher.Hash(ctx, "ada's password")
+		bob, _ := hasher.Hash(ctx, "bob's password")
+
+		hasher.Compare(ctx, ada, "wrong")
+		hasher.Compare(ctx, ada, "wrong")
+
+		assert.ErrorIs(t, hasher.Compare(ctx, ada, "ada's password"), user.ErrAccountLocked)
+		assert.NoError(t, hasher.Compare(ctx, bob, "bob's password"))
+	})
+
+	t.Run("fails closed when the store is down", func(t *testing.T) {
+		store := newMockFailureStore()
+		store.err = errors.New("store down")
+		hasher, _ := newHasher(store)
+
+		assert.ErrorIs(t, hasher.Compare(ctx, "hash:correct", "correct"), store.err)
+	})
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidLimit = errors.New("limit must look like 20/1m")
+
+// Limit allows Requests requests per period Per, refilled continuously, with
+// bursts of up to Requests. The zero Limit is unlimited.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+// interval is how long one token takes to refill.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "0"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText parses limits such as "20/1m" or "5/1h". "0" and the empty
+// string mean unlimited.
+func (l *Limit) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" || s == "0" {
+		*l = Limit{}
+		return nil
+	}
+
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return ErrInvalidLimit
+	}
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 0 {
+		return ErrInvalidLimit
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return ErrInvalidLimit
+	}
+	*l = Limit{Requests: n, Per: d}
+	return nil
+}
+
+// Decision is the outcome of taking a token.
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until a request would be allowed; zero when
+	// this one was.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps one token bucket per key. Take must be atomic per key, so that
+// concurrent requests, possibly from several instances sharing the store,
+// never spend the same token twice.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Decision, error)
+}
+
+// Bucket is the state a Store keeps per key. A missing bucket is a full one.
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+func NewBucket(limit Limit, now time.Time) Bucket {
+	return Bucket{Tokens: float64(limit.Requests), Updated: now}
+}
+
+// Take refills b for the time elapsed since it was last updated and spends
+// a token if there is one. A clock that went backwards refills nothing.
+func (b *Bucket) Take(limit Limit, now time.Time) Decision {
+	if elapsed := now.Sub(b.Updated); elapsed > 0 {
+		b.Tokens = math.Min(float64(limit.Requests), b.Tokens+float64(elapsed)/float64(limit.interval()))
+		b.Updated = now
+	}
+
+	d := Decision{Limit: limit.Requests}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = tokenTime(1-b.Tokens, limit)
+	}
+	d.Remaining = int(b.Tokens)
+	d.Reset = tokenTime(float64(limit.Requests)-b.Tokens, limit)
+	return d
+}
+
+// Full reports whether b has refilled completely by now, at which point it
+// is indistinguishable from a missing bucket and can be dropped.
+func (b Bucket) Full(limit Limit, now time.Time) bool {
+	return b.Tokens+float64(now.Sub(b.Updated))/float64(limit.interval()) >= float64(limit.Requests)
+}
+
+func tokenTime(tokens float64, limit Limit) time.Duration {
+	return time.Duration(math.Ceil(tokens * float64(limit.interval())))
+}
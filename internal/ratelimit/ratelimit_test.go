@@ -0,0 +1,81 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimit_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Limit
+		wantErr bool
+	}{
+		{in: "20/1m", want: Limit{Requests: 20, Per: time.Minute}},
+		{in: " 5/1h ", want: Limit{Requests: 5, Per: time.Hour}},
+		{in: "0", want: Limit{}},
+		{in: "", want: Limit{}},
+		{in: "20", wantErr: true},
+		{in: "x/1m", wantErr: true},
+		{in: "-1/1m", wantErr: true},
+		{in: "20/0s", wantErr: true},
+		{in: "20/soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got Limit
+			err := got.UnmarshalText([]byte(tt.in))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidLimit)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			text, err := got.MarshalText()
+			require.NoError(t, err)
+			var roundTrip Limit
+			require.NoError(t, roundTrip.UnmarshalText(text))
+			assert.Equal(t, got, roundTrip)
+		})
+	}
+}
+
+func TestBucket_Take(t *testing.T) {
+	limit := Limit{Requests: 3, Per: 3 * time.Second}
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := NewBucket(limit, now)
+
+	for remaining := 2; remaining >= 0; remaining-- {
+		d := b.Take(limit, now)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 3, d.Limit)
+		assert.Equal(t, remaining, d.Remaining)
+		assert.Zero(t, d.RetryAfter)
+	}
+
+	d := b.Take(limit, now)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, time.Second, d.RetryAfter)
+	assert.Equal(t, 3*time.Second, d.Reset)
+
+	d = b.Take(limit, now.Add(500*time.Millisecond))
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	d = b.Take(limit, now.Add(time.Second))
+	assert.True(t, d.Allowed, "one token refills per second")
+
+	d = b.Take(limit, now)
+	assert.False(t, d.Allowed, "a clock going backwards refills nothing")
+
+	assert.False(t, b.Full(limit, now.Add(2*time.Second)))
+	assert.True(t, b.Full(limit, now.Add(4*time.Second)))
+	d = b.Take(limit, now.Add(time.Hour))
+	assert.Equal(t, 2, d.Remaining, "refills no further than the limit")
+}
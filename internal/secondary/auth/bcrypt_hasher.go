@@ -1,20 +1,35 @@
 package auth
 
 import (
+	"context"
+	"errors"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
 	"golang.org/x/crypto/bcrypt"
 )
 
-type BcryptHasher struct{}
+type BcryptHasher struct {
+	cost int
+}
 
-func NewBcryptHasher() *BcryptHasher {
-	return &BcryptHasher{}
+// NewBcryptHasher returns a hasher using the given bcrypt cost, or
+// bcrypt.DefaultCost when cost is zero.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{cost: cost}
 }
 
-func (h *BcryptHasher) Hash(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (h *BcryptHasher) Hash(ctx context.Context, password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost)
 	return string(bytes), err
 }
 
-func (h *BcryptHasher) Compare(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+func (h *BcryptHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return user.ErrPasswordMismatch
+	}
+	return err
 }
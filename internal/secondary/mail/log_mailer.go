@@ -0,0 +1,21 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+)
+
+// LogMailer writes messages to the log instead of sending them. It is meant
+// for local development without a mail server.
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg mail.Message) error {
+	slog.InfoContext(ctx, "mail", "to", msg.To, "subject", msg.Subject, "body", msg.TextBody)
+	return nil
+}
@@ -0,0 +1,197 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts every message and keeps
+// the envelope and data of each one.
+type smtpStandIn struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	from     []string
+	to       []string
+	messages []string
+}
+
+func newSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := &smtpStandIn{listener: l}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		l.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+func (s *smtpStandIn) addr() string { return s.listener.Addr().String() }
+
+func (s *smtpStandIn) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *smtpStandIn) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stand-in")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.mu.Lock()
+			s.from = append(s.from, strings.TrimSpace(line)[len("MAIL FROM:"):])
+			s.mu.Unlock()
+			reply("250 OK")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.to = append(s.to, strings.TrimSpace(line)[len("RCPT TO:"):])
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, data.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer_Send(t *testing.T) {
+	server := newSMTPStandIn(t)
+
+	mailer := NewSMTPMailer(SMTPConfig{
+		Addr: server.addr(),
+		From: "weekbyweek@example.com",
+	})
+
+	err := mailer.Send(context.Background(), mail.Message{
+		To:       "john@example.com",
+		Subject:  "Week 1706 in review ✓",
+		TextBody: "Plain body with a line that is long enough to need soft line breaks when quoted-printable encoded.",
+		HTMLBody: "<p>HTML body</p>",
+	})
+	require.NoError(t, err)
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	assert.Equal(t, []string{"<weekbyweek@example.com>"}, server.from)
+	assert.Equal(t, []string{"<john@example.com>"}, server.to)
+	require.Len(t, server.messages, 1)
+
+	msg, err := netmail.ReadMessage(strings.NewReader(server.messages[0]))
+	require.NoError(t, err)
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "Week 1706 in review ✓", subject)
+	assert.Equal(t, "john@example.com", msg.Header.Get("To"))
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	bodies := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		bodies[part.Header.Get("Content-Type")] = string(body)
+	}
+
+	assert.Equal(t, map[string]string{
+		"text/plain; charset=utf-8": "Plain body with a line that is long enough to need soft line breaks when quoted-printable encoded.",
+		"text/html; charset=utf-8":  "<p>HTML body</p>",
+	}, bodies)
+}
+
+func TestSMTPMailer_SendUnreachable(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	mailer := NewSMTPMailer(SMTPConfig{Addr: addr, From: "weekbyweek@example.com"})
+
+	err = mailer.Send(context.Background(), mail.Message{To: "john@example.com", Subject: "x", TextBody: "x"})
+	assert.Error(t, err)
+}
+
+func TestSMTPMailer_Check(t *testing.T) {
+	server := newSMTPStandIn(t)
+	mailer := NewSMTPMailer(SMTPConfig{Addr: server.addr(), From: "weekbyweek@example.com"})
+	assert.NoError(t, mailer.Check(context.Background()))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	mailer = NewSMTPMailer(SMTPConfig{Addr: addr, From: "weekbyweek@example.com"})
+	assert.Error(t, mailer.Check(context.Background()))
+}
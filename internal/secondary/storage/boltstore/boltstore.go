@@ -0,0 +1,74 @@
+package boltstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets hold records by ID, and indexes that map a derived key to the
+// ID of the record it belongs to.
+var (
+	usersBucket            = []byte("users")
+	usersByEmailBucket     = []byte("users_by_email")
+	usersByUsernameBucket  = []byte("users_by_username")
+	milestonesBucket       = []byte("milestones")
+	milestonesByUserBucket = []byte("milestones_by_user")
+	deliveriesBucket       = []byte("digest_deliveries")
+	jobsBucket             = []byte("jobs")
+	jobsByRunAtBucket      = []byte("jobs_by_run_at")
+
+	buckets = [][]byte{
+		usersBucket, usersByEmailBucket, usersByUsernameBucket,
+		milestonesBucket, milestonesByUserBucket,
+		deliveriesBucket,
+		jobsBucket, jobsByRunAtBucket,
+	}
+)
+
+// ErrUnknownRecord is returned for a record written in a format this build
+// cannot read, for example by a newer one.
+var ErrUnknownRecord = errors.New("unknown record format")
+
+// Open opens the database file at path, creating it and any buckets that
+// do not exist yet. It waits up to a second for another process holding
+// the file to let go of it.
+func Open(path string) (*bolt.DB, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range buckets {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// Records are stored as a format version byte followed by JSON, so that
+// their layout can change while records written before stay readable.
+func encode(version byte, v any) ([]byte, error) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{version}, payload...), nil
+}
+
+func decode(data []byte) (byte, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, fmt.Errorf("empty record: %w", ErrUnknownRecord)
+	}
+	return data[0], data[1:], nil
+}
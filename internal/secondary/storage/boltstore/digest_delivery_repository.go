@@ -0,0 +1,50 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	bolt "go.etcd.io/bbolt"
+)
+
+const deliveryRecordV1 = 1
+
+type deliveryRecord struct {
+	SentAt time.Time `json:"sent_at"`
+}
+
+type boltDeliveryRepository struct {
+	db *bolt.DB
+}
+
+func NewDeliveryRepository(db *bolt.DB) digest.DeliveryRepository {
+	return &boltDeliveryRepository{db: db}
+}
+
+func (r *boltDeliveryRepository) Record(ctx context.Context, d digest.Delivery) error {
+	return update(ctx, r.db, func(tx *bolt.Tx) error {
+		key := deliveryKey(d.UserID, d.Week)
+		if tx.Bucket(deliveriesBucket).Get(key) != nil {
+			return digest.ErrAlreadyDelivered
+		}
+		data, err := encode(deliveryRecordV1, deliveryRecord{SentAt: d.SentAt})
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(deliveriesBucket).Put(key, data)
+	})
+}
+
+func (r *boltDeliveryRepository) Delete(ctx context.Context, userID uuid.UUID, week int) error {
+	return update(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket(deliveriesBucket).Delete(deliveryKey(userID, week))
+	})
+}
+
+// deliveryKey is the user ID followed by the week.
+func deliveryKey(userID uuid.UUID, week int) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, userID[:]...), uint64(week))
+}
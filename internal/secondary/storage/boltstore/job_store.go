@@ -0,0 +1,221 @@
+package boltstore
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	bolt "go.etcd.io/bbolt"
+)
+
+const jobRecordV1 = 1
+
+type jobRecord struct {
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	State       job.State       `json:"state"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LockedUntil time.Time       `json:"locked_until"`
+	LastError   string          `json:"last_error"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+type boltJobStore struct {
+	db *bolt.DB
+}
+
+// NewJobStore keeps the queue in db. Pending and running jobs are indexed
+// by when they are due, so that Claim does not read the finished ones.
+func NewJobStore(db *bolt.DB) job.Store {
+	return &boltJobStore{db: db}
+}
+
+func (s *boltJobStore) Enqueue(ctx context.Context, j *job.Job) error {
+	return update(ctx, s.db, func(tx *bolt.Tx) error {
+		return putJob(tx, j)
+	})
+}
+
+// Claim walks the index from the job due first. A running job was due when
+// it was claimed, so none past now can be claimable.
+func (s *boltJobStore) Claim(ctx context.Context, now time.Time, lease time.Duration) (*job.Job, error) {
+	var claimed *job.Job
+	err := update(ctx, s.db, func(tx *bolt.Tx) error {
+		c := tx.Bucket(jobsByRunAtBucket).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			j, err := getJob(tx, v)
+			if err != nil {
+				return err
+			}
+			if j.RunAt.After(now) {
+				break
+			}
+			due := j.State == job.StatePending
+			abandoned := j.State == job.StateRunning && !j.LockedUntil.After(now)
+			if !due && !abandoned {
+				continue
+			}
+
+			j.State = job.StateRunning
+			j.Attempts++
+			j.LockedUntil = now.Add(lease)
+			j.UpdatedAt = now
+			claimed = j
+			return putJob(tx, j)
+		}
+		return job.ErrNoJobAvailable
+	})
+	return claimed, err
+}
+
+func (s *boltJobStore) Complete(ctx context.Context, id uuid.UUID, attempt int) error {
+	return s.finish(ctx, id, attempt, func(j *job.Job) {
+		j.State = job.StateDone
+	})
+}
+
+func (s *boltJobStore) Retry(ctx context.Context, id uuid.UUID, attempt int, runAt time.Time, lastErr string) error {
+	return s.finish(ctx, id, attempt, func(j *job.Job) {
+		j.State = job.StatePending
+		j.RunAt = runAt
+		j.LastError = lastErr
+	})
+}
+
+func (s *boltJobStore) Bury(ctx context.Context, id uuid.UUID, attempt int, lastErr string) error {
+	return s.finish(ctx, id, attempt, func(j *job.Job) {
+		j.State = job.StateDead
+		j.LastError = lastErr
+	})
+}
+
+func (s *boltJobStore) DeadLetters(ctx context.Context) ([]*job.Job, error) {
+	var dead []*job.Job
+	err := view(ctx, s.db, func(tx *bolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEach(func(k, v []byte) error {
+			j, err := decodeJob(k, v)
+			if err != nil {
+				return err
+			}
+			if j.State == job.StateDead {
+				dead = append(dead, j)
+			}
+			return nil
+		})
+	})
+	sort.Slice(dead, func(i, k int) bool {
+		return dead[i].CreatedAt.Before(dead[k].CreatedAt)
+	})
+	return dead, err
+}
+
+// finish applies an outcome to the job only while it is still running
+// under attempt.
+func (s *boltJobStore) finish(ctx context.Context, id uuid.UUID, attempt int, apply func(j *job.Job)) error {
+	return update(ctx, s.db, func(tx *bolt.Tx) error {
+		j, err := getJob(tx, idKey(id))
+		if err != nil {
+			return err
+		}
+		if j.State != job.StateRunning || j.Attempts != attempt {
+			return job.ErrLeaseLost
+		}
+		apply(j)
+		j.LockedUntil = time.Time{}
+		j.UpdatedAt = time.Now().UTC()
+		return putJob(tx, j)
+	})
+}
+
+// putJob writes j and moves its index entry, which only pending and
+// running jobs have.
+func putJob(tx *bolt.Tx, j *job.Job) error {
+	if data := tx.Bucket(jobsBucket).Get(idKey(j.ID)); data != nil {
+		stored, err := decodeJob(idKey(j.ID), data)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(jobsByRunAtBucket).Delete(runAtKey(stored)); err != nil {
+			return err
+		}
+	}
+
+	data, err := encode(jobRecordV1, jobRecord{
+		Type:        j.Type,
+		Payload:     j.Payload,
+		State:       j.State,
+		Attempts:    j.Attempts,
+		MaxAttempts: j.MaxAttempts,
+		RunAt:       j.RunAt,
+		LockedUntil: j.LockedUntil,
+		LastError:   j.LastError,
+		CreatedAt:   j.CreatedAt,
+		UpdatedAt:   j.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(jobsBucket).Put(idKey(j.ID), data); err != nil {
+		return err
+	}
+	if j.State != job.StatePending && j.State != job.StateRunning {
+		return nil
+	}
+	return tx.Bucket(jobsByRunAtBucket).Put(runAtKey(j), idKey(j.ID))
+}
+
+func getJob(tx *bolt.Tx, key []byte) (*job.Job, error) {
+	data := tx.Bucket(jobsBucket).Get(key)
+	if data == nil {
+		return nil, job.ErrJobNotFound
+	}
+	return decodeJob(key, data)
+}
+
+func decodeJob(key, data []byte) (*job.Job, error) {
+	id, err := uuid.FromBytes(key)
+	if err != nil {
+		return nil, err
+	}
+	version, payload, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if version != jobRecordV1 {
+		return nil, fmt.Errorf("job %s has version %d: %w", id, version, ErrUnknownRecord)
+	}
+
+	var rec jobRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return nil, fmt.Errorf("job %s: %w", id, err)
+	}
+	return &job.Job{
+		ID:          id,
+		Type:        rec.Type,
+		Payload:     rec.Payload,
+		State:       rec.State,
+		Attempts:    rec.Attempts,
+		MaxAttempts: rec.MaxAttempts,
+		RunAt:       rec.RunAt,
+		LockedUntil: rec.LockedUntil,
+		LastError:   rec.LastError,
+		CreatedAt:   rec.CreatedAt,
+		UpdatedAt:   rec.UpdatedAt,
+	}, nil
+}
+
+// runAtKey is when j is due and its ID, so that keys are unique and sort
+// by due time.
+func runAtKey(j *job.Job) []byte {
+	key := binary.BigEndian.AppendUint64(nil, uint64(j.RunAt.UnixNano()))
+	return append(key, idKey(j.ID)...)
+}
@@ -0,0 +1,178 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	bolt "go.etcd.io/bbolt"
+)
+
+const milestoneRecordV1 = 1
+
+type milestoneRecord struct {
+	UserID      uuid.UUID `json:"user_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Date        time.Time `json:"date"`
+	ImportID    uuid.UUID `json:"import_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type boltMilestoneRepository struct {
+	db *bolt.DB
+}
+
+func NewMilestoneRepository(db *bolt.DB) milestone.MilestoneRepository {
+	return &boltMilestoneRepository{db: db}
+}
+
+func (r *boltMilestoneRepository) Save(ctx context.Context, m *milestone.Milestone) error {
+	return update(ctx, r.db, func(tx *bolt.Tx) error {
+		return putMilestone(tx, m)
+	})
+}
+
+// SaveAll writes the milestones in one transaction, so that an import is
+// stored whole or not at all.
+func (r *boltMilestoneRepository) SaveAll(ctx context.Context, milestones []*milestone.Milestone) error {
+	return update(ctx, r.db, func(tx *bolt.Tx) error {
+		for _, m := range milestones {
+			if err := putMilestone(tx, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *boltMilestoneRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*milestone.Milestone, error) {
+	var found []*milestone.Milestone
+	err := view(ctx, r.db, func(tx *bolt.Tx) error {
+		var err error
+		found, err = userMilestones(tx, userID)
+		return err
+	})
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Date().Before(found[j].Date())
+	})
+	return found, err
+}
+
+func (r *boltMilestoneRepository) DeleteByImportID(ctx context.Context, userID, importID uuid.UUID) error {
+	if importID == uuid.Nil {
+		return milestone.ErrImportNotFound
+	}
+	return update(ctx, r.db, func(tx *bolt.Tx) error {
+		milestones, err := userMilestones(tx, userID)
+		if err != nil {
+			return err
+		}
+
+		deleted := 0
+		for _, m := range milestones {
+			if m.ImportID() != importID {
+				continue
+			}
+			if err := tx.Bucket(milestonesBucket).Delete(idKey(m.ID())); err != nil {
+				return err
+			}
+			if err := tx.Bucket(milestonesByUserBucket).Delete(milestoneUserKey(m.UserID(), m.ID())); err != nil {
+				return err
+			}
+			deleted++
+		}
+		if deleted == 0 {
+			return milestone.ErrImportNotFound
+		}
+		return nil
+	})
+}
+
+func putMilestone(tx *bolt.Tx, m *milestone.Milestone) error {
+	// A milestone saved again under another user moves to that user's
+	// index entries.
+	if data := tx.Bucket(milestonesBucket).Get(idKey(m.ID())); data != nil {
+		stored, err := decodeMilestone(m.ID(), data)
+		if err != nil {
+			return err
+		}
+		if err := tx.Bucket(milestonesByUserBucket).Delete(milestoneUserKey(stored.UserID(), m.ID())); err != nil {
+			return err
+		}
+	}
+
+	data, err := encode(milestoneRecordV1, milestoneRecord{
+		UserID:      m.UserID(),
+		Title:       m.Title(),
+		Description: m.Description(),
+		Date:        m.Date(),
+		ImportID:    m.ImportID(),
+		CreatedAt:   m.CreatedAt(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(milestonesBucket).Put(idKey(m.ID()), data); err != nil {
+		return err
+	}
+	return tx.Bucket(milestonesByUserBucket).Put(milestoneUserKey(m.UserID(), m.ID()), idKey(m.ID()))
+}
+
+// userMilestones scans the user index, whose keys start with the user ID.
+func userMilestones(tx *bolt.Tx, userID uuid.UUID) ([]*milestone.Milestone, error) {
+	var milestones []*milestone.Milestone
+	prefix := idKey(userID)
+	c := tx.Bucket(milestonesByUserBucket).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		id, err := uuid.FromBytes(v)
+		if err != nil {
+			return nil, err
+		}
+		data := tx.Bucket(milestonesBucket).Get(v)
+		if data == nil {
+			return nil, fmt.Errorf("milestone %s is indexed but not stored", id)
+		}
+		m, err := decodeMilestone(id, data)
+		if err != nil {
+			return nil, err
+		}
+		milestones = append(milestones, m)
+	}
+	return milestones, nil
+}
+
+func decodeMilestone(id uuid.UUID, data []byte) (*milestone.Milestone, error) {
+	version, payload, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if version != milestoneRecordV1 {
+		return nil, fmt.Errorf("milestone %s has version %d: %w", id, version, ErrUnknownRecord)
+	}
+
+	var rec milestoneRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return nil, fmt.Errorf("milestone %s: %w", id, err)
+	}
+	return milestone.Restore(milestone.RestoreParams{
+		ID:          id,
+		UserID:      rec.UserID,
+		Title:       rec.Title,
+		Description: rec.Description,
+		Date:        rec.Date,
+		ImportID:    rec.ImportID,
+		CreatedAt:   rec.CreatedAt,
+	}), nil
+}
+
+// milestoneUserKey is the user ID followed by the milestone ID.
+func milestoneUserKey(userID, id uuid.UUID) []byte {
+	return append(append([]byte{}, userID[:]...), id[:]...)
+}
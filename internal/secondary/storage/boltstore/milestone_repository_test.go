@@ -0,0 +1,102 @@
+package boltstore
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMilestoneRepository(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+
+	newMilestone := func(t *testing.T, userID, importID uuid.UUID, title string, date time.Time) *milestone.Milestone {
+		m, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+			UserID:   userID,
+			Title:    title,
+			Date:     date,
+			ImportID: importID,
+		})
+		require.NoError(t, err, "failed to create test milestone")
+		return m
+	}
+	titles := func(milestones []*milestone.Milestone) []string {
+		var titles []string
+		for _, m := range milestones {
+			titles = append(titles, m.Title())
+		}
+		return titles
+	}
+
+	t.Run("find milestones by user ordered by date", func(t *testing.T) {
+		repo := NewMilestoneRepository(newTestDB(t))
+
+		later := newMilestone(t, userID, uuid.Nil, "Moved abroad", time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC))
+		earlier := newMilestone(t, userID, uuid.Nil, "Graduated", time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC))
+		other := newMilestone(t, uuid.New(), uuid.Nil, "Someone else", time.Date(2015, time.May, 5, 0, 0, 0, 0, time.UTC))
+
+		for _, m := range []*milestone.Milestone{later, earlier, other} {
+			require.NoError(t, repo.Save(ctx, m))
+		}
+
+		found, err := repo.FindByUserID(ctx, userID)
+		require.NoError(t, err)
+
+		require.Len(t, found, 2)
+		assert.Equal(t, []string{"Graduated", "Moved abroad"}, titles(found))
+		assert.Equal(t, earlier.ID(), found[0].ID())
+		assert.Equal(t, userID, found[0].UserID())
+		assert.True(t, earlier.Date().Equal(found[0].Date()))
+		assert.True(t, earlier.CreatedAt().Equal(found[0].CreatedAt()))
+	})
+
+	t.Run("save again replaces the milestone", func(t *testing.T) {
+		repo := NewMilestoneRepository(newTestDB(t))
+		m := newMilestone(t, userID, uuid.Nil, "Graduated", time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC))
+
+		require.NoError(t, repo.Save(ctx, m))
+		require.NoError(t, repo.Save(ctx, m))
+
+		found, err := repo.FindByUserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("delete milestones by import", func(t *testing.T) {
+		repo := NewMilestoneRepository(newTestDB(t))
+		importID := uuid.New()
+
+		imported := newMilestone(t, userID, importID, "Imported", time.Date(2016, time.April, 2, 0, 0, 0, 0, time.UTC))
+		manual := newMilestone(t, userID, uuid.Nil, "Manual", time.Date(2017, time.April, 2, 0, 0, 0, 0, time.UTC))
+
+		require.NoError(t, repo.SaveAll(ctx, []*milestone.Milestone{imported, manual}))
+
+		err := repo.DeleteByImportID(ctx, uuid.New(), importID)
+		assert.ErrorIs(t, err, milestone.ErrImportNotFound, "another user's import should not be deleted")
+
+		require.NoError(t, repo.DeleteByImportID(ctx, userID, importID))
+
+		found, err := repo.FindByUserID(ctx, userID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Manual"}, titles(found))
+
+		err = repo.DeleteByImportID(ctx, userID, importID)
+		assert.ErrorIs(t, err, milestone.ErrImportNotFound)
+		err = repo.DeleteByImportID(ctx, userID, uuid.Nil)
+		assert.ErrorIs(t, err, milestone.ErrImportNotFound, "manual milestones have no import")
+	})
+
+	t.Run("no milestones for user", func(t *testing.T) {
+		repo := NewMilestoneRepository(newTestDB(t))
+
+		found, err := repo.FindByUserID(ctx, userID)
+
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+}
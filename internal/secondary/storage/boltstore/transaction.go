@@ -0,0 +1,81 @@
+package boltstore
+
+import (
+	"context"
+	"slices"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/transaction"
+	bolt "go.etcd.io/bbolt"
+)
+
+type txKey struct{}
+
+type boltTx struct {
+	db        *bolt.DB
+	tx        *bolt.Tx
+	rollbacks []func()
+}
+
+// txFrom returns the write transaction ctx carries for db, if any.
+func txFrom(ctx context.Context, db *bolt.DB) *bolt.Tx {
+	if t, ok := ctx.Value(txKey{}).(*boltTx); ok && t.db == db {
+		return t.tx
+	}
+	return nil
+}
+
+// setVersion sets the version of an aggregate written to db under ctx, and
+// puts the old one back if the transaction ctx carries is rolled back.
+// Bolt transactions are not safe for concurrent use, so neither is this.
+func setVersion(ctx context.Context, db *bolt.DB, a aggregate.Versioned, v aggregate.Version) {
+	if t, ok := ctx.Value(txKey{}).(*boltTx); ok && t.db == db {
+		old := a.Version()
+		t.rollbacks = append(t.rollbacks, func() { a.SetVersion(old) })
+	}
+	a.SetVersion(v)
+}
+
+// update and view run fn in the transaction ctx carries, or else in one of
+// their own. Joining matters: bolt allows one write transaction at a time,
+// so starting another inside it would never return.
+func update(ctx context.Context, db *bolt.DB, fn func(*bolt.Tx) error) error {
+	if tx := txFrom(ctx, db); tx != nil {
+		return fn(tx)
+	}
+	return db.Update(fn)
+}
+
+func view(ctx context.Context, db *bolt.DB, fn func(*bolt.Tx) error) error {
+	if tx := txFrom(ctx, db); tx != nil {
+		return fn(tx)
+	}
+	return db.View(fn)
+}
+
+type boltTransactionManager struct {
+	db *bolt.DB
+}
+
+// NewTransactionManager runs use cases in write transactions on db, which
+// are serialised.
+func NewTransactionManager(db *bolt.DB) transaction.Manager {
+	return &boltTransactionManager{db: db}
+}
+
+func (m *boltTransactionManager) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx, m.db) != nil {
+		return fn(ctx)
+	}
+	t := &boltTx{db: m.db}
+	err := m.db.Update(func(tx *bolt.Tx) error {
+		t.tx = tx
+		return fn(context.WithValue(ctx, txKey{}, t))
+	})
+	if err != nil {
+		for _, undo := range slices.Backward(t.rollbacks) {
+			undo()
+		}
+	}
+	return err
+}
@@ -0,0 +1,73 @@
+package boltstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionManager(t *testing.T) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	t.Run("commits every write", func(t *testing.T) {
+		db := newTestDB(t)
+		users := NewUserRepository(db)
+		ada, grace := newTestUser(t, "ada@example.com"), newTestUser(t, "grace@example.com")
+
+		err := NewTransactionManager(db).Run(ctx, func(ctx context.Context) error {
+			if err := users.Create(ctx, ada); err != nil {
+				return err
+			}
+			found, err := users.FindByEmail(ctx, "ada@example.com")
+			require.NoError(t, err, "the transaction sees its own writes")
+			assert.Equal(t, ada.ID(), found.ID())
+			return users.Create(ctx, grace)
+		})
+		require.NoError(t, err)
+
+		all, err := users.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Len(t, all, 2)
+	})
+
+	t.Run("rolls back writes when the use case fails", func(t *testing.T) {
+		db := newTestDB(t)
+		users := NewUserRepository(db)
+		u := newTestUser(t, "ada@example.com")
+
+		err := NewTransactionManager(db).Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Create(ctx, u))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		assert.Zero(t, u.Version(), "the user is not left at a version that was never stored")
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		_, err = users.FindByEmail(ctx, "ada@example.com")
+		assert.ErrorIs(t, err, user.ErrUserNotFound, "index entries are rolled back too")
+	})
+
+	t.Run("nested runs join the transaction", func(t *testing.T) {
+		db := newTestDB(t)
+		users := NewUserRepository(db)
+		transactions := NewTransactionManager(db)
+		u := newTestUser(t, "ada@example.com")
+
+		err := transactions.Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, transactions.Run(ctx, func(ctx context.Context) error {
+				return users.Create(ctx, u)
+			}))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound, "the inner run did not commit on its own")
+	})
+}
@@ -0,0 +1,243 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+	bolt "go.etcd.io/bbolt"
+)
+
+const userRecordV1 = 1
+
+type userRecord struct {
+	Email         string            `json:"email"`
+	Username      string            `json:"username"`
+	PasswordHash  string            `json:"password_hash"`
+	DateOfBirth   time.Time         `json:"date_of_birth"`
+	AnchorMode    string            `json:"anchor_mode"`
+	FirstWeekday  int               `json:"first_weekday"`
+	TimeZone      string            `json:"time_zone"`
+	CalendarToken string            `json:"calendar_token"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Version       aggregate.Version `json:"version"`
+}
+
+type boltUserRepository struct {
+	db *bolt.DB
+}
+
+func NewUserRepository(db *bolt.DB) user.UserRepository {
+	return &boltUserRepository{db: db}
+}
+
+// Create and Save write the record and its index entries in one
+// transaction, so that the indexes never disagree with the records.
+func (r *boltUserRepository) Create(ctx context.Context, u *user.User) error {
+	version := aggregate.Version(1)
+	err := update(ctx, r.db, func(tx *bolt.Tx) error {
+		if tx.Bucket(usersBucket).Get(idKey(u.ID())) != nil {
+			return fmt.Errorf("user %s already exists: %w", u.ID(), aggregate.ErrConflict)
+		}
+		if tx.Bucket(usersByEmailBucket).Get(emailKey(u.Email())) != nil {
+			return user.ErrEmailTaken
+		}
+		return putUser(tx, u, version)
+	})
+	if err != nil {
+		return err
+	}
+
+	setVersion(ctx, r.db, u, version)
+	return nil
+}
+
+func (r *boltUserRepository) Save(ctx context.Context, u *user.User) error {
+	version := u.Version() + 1
+	err := update(ctx, r.db, func(tx *bolt.Tx) error {
+		stored, err := getUser(tx, u.ID())
+		if err != nil {
+			return err
+		}
+		if stored.Version() != u.Version() {
+			return fmt.Errorf("user %s at version %d, saving version %d: %w", u.ID(), stored.Version(), u.Version(), aggregate.ErrConflict)
+		}
+		if id := tx.Bucket(usersByEmailBucket).Get(emailKey(u.Email())); id != nil && !bytes.Equal(id, idKey(u.ID())) {
+			return user.ErrEmailTaken
+		}
+
+		if err := tx.Bucket(usersByEmailBucket).Delete(emailKey(stored.Email())); err != nil {
+			return err
+		}
+		if err := tx.Bucket(usersByUsernameBucket).Delete(usernameKey(stored)); err != nil {
+			return err
+		}
+		return putUser(tx, u, version)
+	})
+	if err != nil {
+		return err
+	}
+
+	setVersion(ctx, r.db, u, version)
+	return nil
+}
+
+func (r *boltUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	var u *user.User
+	err := view(ctx, r.db, func(tx *bolt.Tx) error {
+		var err error
+		u, err = getUser(tx, id)
+		return err
+	})
+	return u, err
+}
+
+func (r *boltUserRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	var u *user.User
+	err := view(ctx, r.db, func(tx *bolt.Tx) error {
+		key := tx.Bucket(usersByEmailBucket).Get(emailKey(email))
+		if key == nil {
+			return user.ErrUserNotFound
+		}
+		id, err := uuid.FromBytes(key)
+		if err != nil {
+			return err
+		}
+		u, err = getUser(tx, id)
+		return err
+	})
+	return u, err
+}
+
+// FindByUsername scans the username index, whose keys order the users
+// sharing a username by creation.
+func (r *boltUserRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	users := []*user.User{}
+	err := view(ctx, r.db, func(tx *bolt.Tx) error {
+		prefix := append([]byte(user.NormaliseUsername(username)), 0)
+		c := tx.Bucket(usersByUsernameBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			id, err := uuid.FromBytes(v)
+			if err != nil {
+				return err
+			}
+			u, err := getUser(tx, id)
+			if err != nil {
+				return err
+			}
+			users = append(users, u)
+		}
+		return nil
+	})
+	return users, err
+}
+
+func (r *boltUserRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	users := []*user.User{}
+	err := view(ctx, r.db, func(tx *bolt.Tx) error {
+		return tx.Bucket(usersBucket).ForEach(func(k, v []byte) error {
+			id, err := uuid.FromBytes(k)
+			if err != nil {
+				return err
+			}
+			u, err := decodeUser(id, v)
+			if err != nil {
+				return err
+			}
+			users = append(users, u)
+			return nil
+		})
+	})
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt().Before(users[j].CreatedAt())
+	})
+	return users, err
+}
+
+func putUser(tx *bolt.Tx, u *user.User, version aggregate.Version) error {
+	data, err := encode(userRecordV1, userRecord{
+		Email:         u.Email(),
+		Username:      u.Username(),
+		PasswordHash:  u.PasswordHash(),
+		DateOfBirth:   u.DateOfBirth(),
+		AnchorMode:    string(u.WeekAnchor().Mode),
+		FirstWeekday:  int(u.WeekAnchor().FirstWeekday),
+		TimeZone:      u.Location().String(),
+		CalendarToken: u.CalendarToken(),
+		CreatedAt:     u.CreatedAt(),
+		UpdatedAt:     u.UpdatedAt(),
+		Version:       version,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Bucket(usersBucket).Put(idKey(u.ID()), data); err != nil {
+		return err
+	}
+	if err := tx.Bucket(usersByEmailBucket).Put(emailKey(u.Email()), idKey(u.ID())); err != nil {
+		return err
+	}
+	return tx.Bucket(usersByUsernameBucket).Put(usernameKey(u), idKey(u.ID()))
+}
+
+func getUser(tx *bolt.Tx, id uuid.UUID) (*user.User, error) {
+	data := tx.Bucket(usersBucket).Get(idKey(id))
+	if data == nil {
+		return nil, user.ErrUserNotFound
+	}
+	return decodeUser(id, data)
+}
+
+func decodeUser(id uuid.UUID, data []byte) (*user.User, error) {
+	version, payload, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if version != userRecordV1 {
+		return nil, fmt.Errorf("user %s has version %d: %w", id, version, ErrUnknownRecord)
+	}
+
+	var rec userRecord
+	if err := json.Unmarshal(payload, &rec); err != nil {
+		return nil, fmt.Errorf("user %s: %w", id, err)
+	}
+	return user.Restore(user.RestoreParams{
+		ID:            id,
+		Email:         rec.Email,
+		Username:      rec.Username,
+		PasswordHash:  rec.PasswordHash,
+		DateOfBirth:   rec.DateOfBirth,
+		WeekAnchor:    week.Anchor{Mode: week.Mode(rec.AnchorMode), FirstWeekday: time.Weekday(rec.FirstWeekday)},
+		TimeZone:      rec.TimeZone,
+		CalendarToken: rec.CalendarToken,
+		CreatedAt:     rec.CreatedAt,
+		UpdatedAt:     rec.UpdatedAt,
+		Version:       rec.Version,
+	})
+}
+
+func emailKey(email string) []byte {
+	return []byte(user.NormaliseEmail(email))
+}
+
+// usernameKey is the normalised username, a zero byte, the creation time
+// and the ID, so that keys are unique and sort by creation per username.
+func usernameKey(u *user.User) []byte {
+	key := append([]byte(user.NormaliseUsername(u.Username())), 0)
+	key = binary.BigEndian.AppendUint64(key, uint64(u.CreatedAt().UnixNano()))
+	return append(key, idKey(u.ID())...)
+}
+
+func idKey(id uuid.UUID) []byte {
+	return id[:]
+}
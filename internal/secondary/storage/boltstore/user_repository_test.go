@@ -0,0 +1,284 @@
+package boltstore
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+
+func (fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error { return nil }
+
+func newTestDB(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := Open(filepath.Join(t.TempDir(), "weekbyweek.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestUser(t *testing.T, email string) *user.User {
+	t.Helper()
+	u, err := user.NewUser(context.Background(), user.NewUserParams{
+		Email:       email,
+		Username:    "ada",
+		Password:    "correct horse",
+		DateOfBirth: time.Date(1960, time.December, 10, 0, 0, 0, 0, time.UTC),
+		WeekAnchor:  week.Anchor{Mode: week.ModeCalendar, FirstWeekday: time.Sunday},
+		TimeZone:    "Europe/London",
+	}, fakeHasher{})
+	require.NoError(t, err)
+	return u
+}
+
+// withEmail copies u with another email, which users cannot change
+// themselves yet.
+func withEmail(t *testing.T, u *user.User, email string) *user.User {
+	t.Helper()
+	changed, err := user.Restore(user.RestoreParams{
+		ID:            u.ID(),
+		Email:         email,
+		Username:      u.Username(),
+		PasswordHash:  u.PasswordHash(),
+		DateOfBirth:   u.DateOfBirth(),
+		WeekAnchor:    u.WeekAnchor(),
+		TimeZone:      u.Location().String(),
+		CalendarToken: u.CalendarToken(),
+		CreatedAt:     u.CreatedAt(),
+		UpdatedAt:     u.UpdatedAt(),
+		Version:       u.Version(),
+	})
+	require.NoError(t, err)
+	return changed
+}
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and find", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+
+		require.NoError(t, repo.Create(ctx, u))
+		assert.Equal(t, aggregate.Version(1), u.Version())
+
+		byID, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		byEmail, err := repo.FindByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		byName, err := repo.FindByUsername(ctx, "ada")
+		require.NoError(t, err)
+		all, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, byName, 1)
+		require.Len(t, all, 1)
+
+		for _, found := range []*user.User{byID, byEmail, byName[0], all[0]} {
+			assert.Equal(t, u.ID(), found.ID())
+			assert.Equal(t, u.Email(), found.Email())
+			assert.Equal(t, u.Username(), found.Username())
+			assert.Equal(t, u.PasswordHash(), found.PasswordHash())
+			assert.True(t, u.DateOfBirth().Equal(found.DateOfBirth()))
+			assert.Equal(t, u.WeekAnchor(), found.WeekAnchor())
+			assert.Equal(t, "Europe/London", found.Location().String())
+			assert.Equal(t, u.CalendarToken(), found.CalendarToken())
+			assert.True(t, u.CreatedAt().Equal(found.CreatedAt()))
+			assert.Equal(t, aggregate.Version(1), found.Version())
+		}
+	})
+
+	t.Run("rejects a registered email", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		require.NoError(t, repo.Create(ctx, newTestUser(t, "ada@example.com")))
+
+		assert.ErrorIs(t, repo.Create(ctx, newTestUser(t, "ADA@example.com")), user.ErrEmailTaken)
+		assert.NoError(t, repo.Create(ctx, newTestUser(t, "grace@example.com")))
+	})
+
+	t.Run("users sharing a username are found oldest first", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		first := newTestUser(t, "ada@example.com")
+		time.Sleep(time.Millisecond)
+		second := newTestUser(t, "lovelace@example.com")
+		require.NoError(t, repo.Create(ctx, second))
+		require.NoError(t, repo.Create(ctx, first))
+
+		found, err := repo.FindByUsername(ctx, "ADA")
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, first.ID(), found[0].ID())
+		assert.Equal(t, second.ID(), found[1].ID())
+
+		found, err = repo.FindByUsername(ctx, "ad")
+		require.NoError(t, err)
+		assert.Empty(t, found, "a prefix of a username does not match")
+	})
+
+	t.Run("rejects creating a user twice", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		assert.ErrorIs(t, repo.Create(ctx, u), aggregate.ErrConflict)
+	})
+
+	t.Run("saves advance the version", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		require.NoError(t, u.ChangeTimeZone("Asia/Tokyo"))
+		require.NoError(t, repo.Save(ctx, u))
+		assert.Equal(t, aggregate.Version(2), u.Version())
+
+		found, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "Asia/Tokyo", found.Location().String())
+		assert.Equal(t, aggregate.Version(2), found.Version())
+	})
+
+	t.Run("rejects saving a stale copy", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+		stale, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Save(ctx, u))
+		require.NoError(t, stale.ChangeTimeZone("Asia/Tokyo"))
+		assert.ErrorIs(t, repo.Save(ctx, stale), aggregate.ErrConflict)
+	})
+
+	t.Run("saving requires creating first", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+
+		assert.ErrorIs(t, repo.Save(ctx, newTestUser(t, "ada@example.com")), user.ErrUserNotFound)
+	})
+
+	t.Run("unknown users are not found", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+
+		_, err := repo.FindByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		_, err = repo.FindByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		byName, err := repo.FindByUsername(ctx, "nobody")
+		require.NoError(t, err)
+		assert.Empty(t, byName)
+		all, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+
+	t.Run("survives reopening", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "weekbyweek.db")
+		db, err := Open(path)
+		require.NoError(t, err)
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, NewUserRepository(db).Create(ctx, u))
+		require.NoError(t, db.Close())
+
+		db, err = Open(path)
+		require.NoError(t, err)
+		defer db.Close()
+		found, err := NewUserRepository(db).FindByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, u.ID(), found.ID())
+	})
+
+	t.Run("rejects records in an unknown format", func(t *testing.T) {
+		db := newTestDB(t)
+		repo := NewUserRepository(db)
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket(usersBucket)
+			data := append([]byte(nil), b.Get(idKey(u.ID()))...)
+			data[0] = userRecordV1 + 1
+			return b.Put(idKey(u.ID()), data)
+		}))
+
+		_, err := repo.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, ErrUnknownRecord)
+	})
+}
+
+// TestUserRepository_Indexes checks that a save moves the user's index
+// entries along with the record, in the same transaction.
+func TestUserRepository_Indexes(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	repo := NewUserRepository(db)
+	ada := newTestUser(t, "ada@example.com")
+	grace := newTestUser(t, "grace@example.com")
+	require.NoError(t, repo.Create(ctx, ada))
+	require.NoError(t, repo.Create(ctx, grace))
+
+	require.NoError(t, repo.Save(ctx, withEmail(t, ada, "countess@example.com")))
+
+	_, err := repo.FindByEmail(ctx, "ada@example.com")
+	assert.ErrorIs(t, err, user.ErrUserNotFound)
+	found, err := repo.FindByEmail(ctx, "Countess@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, ada.ID(), found.ID())
+	byName, err := repo.FindByUsername(ctx, "ada")
+	require.NoError(t, err)
+	assert.Len(t, byName, 2, "the old username entry is replaced, not duplicated")
+
+	assert.ErrorIs(t, repo.Save(ctx, withEmail(t, grace, "countess@example.com")), user.ErrEmailTaken)
+	found, err = repo.FindByEmail(ctx, "grace@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, grace.ID(), found.ID(), "a rejected save leaves the indexes alone")
+
+	require.NoError(t, db.View(func(tx *bolt.Tx) error {
+		assert.Equal(t, 2, tx.Bucket(usersByEmailBucket).Stats().KeyN)
+		assert.Equal(t, 2, tx.Bucket(usersByUsernameBucket).Stats().KeyN)
+		return nil
+	}))
+}
+
+func TestUserRepository_ConcurrentCreate(t *testing.T) {
+	repo := NewUserRepository(newTestDB(t))
+	users := make([]*user.User, 50)
+	for i := range users {
+		users[i] = newTestUser(t, "ada@example.com")
+	}
+
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for _, u := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(context.Background(), u)
+			if err == nil {
+				created.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, user.ErrEmailTaken)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created.Load())
+	all, err := repo.FindAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"golang.org/x/sync/singleflight"
+)
+
+// Config bounds the users cache.
+type Config struct {
+	// Size is how many users are kept; the least recently used go first.
+	Size int
+	// TTL is how long a user is served before being read again.
+	TTL time.Duration
+	// NegativeTTL is how long an ID that was not found is remembered. Zero
+	// remembers nothing.
+	NegativeTTL time.Duration
+}
+
+var DefaultConfig = Config{
+	Size:        10000,
+	TTL:         time.Minute,
+	NegativeTTL: 5 * time.Second,
+}
+
+// Stats counts lookups by ID since the cache was created.
+type Stats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// Users caches lookups of users by ID in front of a repository. Writes
+// through the cache invalidate what it holds, but writes made elsewhere,
+// such as by another instance, are seen only once the entry expires.
+type Users struct {
+	cfg Config
+	now func() time.Time
+
+	mu      sync.Mutex
+	entries map[uuid.UUID]*list.Element
+	recent  *list.List
+	// generation advances on every invalidation, so that a lookup that
+	// started before one does not cache what it read.
+	generation uint64
+
+	loads  singleflight.Group
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type entry struct {
+	id uuid.UUID
+	// user is nil for an ID that was not found.
+	user    *user.User
+	expires time.Time
+}
+
+func NewUsers(cfg Config) *Users {
+	return &Users{
+		cfg:     cfg,
+		now:     time.Now,
+		entries: make(map[uuid.UUID]*list.Element),
+		recent:  list.New(),
+	}
+}
+
+func (c *Users) Stats() Stats {
+	return Stats{Hits: c.hits.Load(), Misses: c.misses.Load()}
+}
+
+// lookup returns the cached entry for id, if it has not expired, and the
+// generation to cache a fresh read under otherwise.
+func (c *Users) lookup(id uuid.UUID) (*entry, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[id]
+	if !ok {
+		return nil, c.generation
+	}
+	e := el.Value.(*entry)
+	if !c.now().Before(e.expires) {
+		c.recent.Remove(el)
+		delete(c.entries, id)
+		return nil, c.generation
+	}
+	c.recent.MoveToFront(el)
+	return e, c.generation
+}
+
+// store caches u, or that id was not found if u is nil, unless the cache
+// was invalidated since generation.
+func (c *Users) store(id uuid.UUID, u *user.User, generation uint64) {
+	ttl := c.cfg.TTL
+	if u == nil {
+		ttl = c.cfg.NegativeTTL
+	}
+	if ttl <= 0 || c.cfg.Size <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return
+	}
+
+	e := &entry{id: id, user: u, expires: c.now().Add(ttl)}
+	if el, ok := c.entries[id]; ok {
+		el.Value = e
+		c.recent.MoveToFront(el)
+		return
+	}
+	c.entries[id] = c.recent.PushFront(e)
+	for c.recent.Len() > c.cfg.Size {
+		oldest := c.recent.Back()
+		c.recent.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).id)
+	}
+}
+
+func (c *Users) invalidate(ids ...uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, id := range ids {
+		if el, ok := c.entries[id]; ok {
+			c.recent.Remove(el)
+			delete(c.entries, id)
+		}
+	}
+}
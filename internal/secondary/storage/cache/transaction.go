@@ -0,0 +1,49 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/transaction"
+)
+
+type txKey struct{}
+
+// written collects the users a transaction wrote, to invalidate again once
+// it has committed or rolled back.
+type written struct {
+	mu  sync.Mutex
+	ids []uuid.UUID
+}
+
+func writtenFrom(ctx context.Context) *written {
+	w, _ := ctx.Value(txKey{}).(*written)
+	return w
+}
+
+type transactions struct {
+	next  transaction.Manager
+	users *Users
+}
+
+// Transactions must wrap the manager that use cases writing users through
+// the cache run in. Lookups within a transaction bypass the cache, since
+// they may see what it has not committed, and what it wrote is invalidated
+// again when it ends, in case a lookup cached the old users meanwhile.
+func (c *Users) Transactions(next transaction.Manager) transaction.Manager {
+	return &transactions{next: next, users: c}
+}
+
+func (m *transactions) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if writtenFrom(ctx) != nil {
+		return m.next.Run(ctx, fn)
+	}
+
+	w := &written{}
+	err := m.next.Run(ctx, func(ctx context.Context) error {
+		return fn(context.WithValue(ctx, txKey{}, w))
+	})
+	m.users.invalidate(w.ids...)
+	return err
+}
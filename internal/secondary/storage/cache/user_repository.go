@@ -0,0 +1,95 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+)
+
+type userRepository struct {
+	next  user.UserRepository
+	users *Users
+}
+
+// UserRepository serves FindByID from the cache, reading through to next
+// on a miss. Concurrent misses for a user share one read. Other lookups
+// are not cached.
+func (c *Users) UserRepository(next user.UserRepository) user.UserRepository {
+	return &userRepository{next: next, users: c}
+}
+
+func (r *userRepository) Create(ctx context.Context, u *user.User) error {
+	defer r.invalidate(ctx, u.ID())
+	return r.next.Create(ctx, u)
+}
+
+// Save invalidates the user even when it fails: a conflict means the
+// cached copy is probably stale.
+func (r *userRepository) Save(ctx context.Context, u *user.User) error {
+	defer r.invalidate(ctx, u.ID())
+	return r.next.Save(ctx, u)
+}
+
+func (r *userRepository) invalidate(ctx context.Context, id uuid.UUID) {
+	if w := writtenFrom(ctx); w != nil {
+		w.mu.Lock()
+		w.ids = append(w.ids, id)
+		w.mu.Unlock()
+	}
+	r.users.invalidate(id)
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	if writtenFrom(ctx) != nil {
+		return r.next.FindByID(ctx, id)
+	}
+
+	e, generation := r.users.lookup(id)
+	if e != nil {
+		r.users.hits.Add(1)
+		if e.user == nil {
+			return nil, user.ErrUserNotFound
+		}
+		return e.user.Clone(), nil
+	}
+	r.users.misses.Add(1)
+
+	// Callers share the read, so it must not be cancelled with the one
+	// that happened to start it.
+	key := id.String() + "/" + strconv.FormatUint(generation, 10)
+	loaded := r.users.loads.DoChan(key, func() (any, error) {
+		u, err := r.next.FindByID(context.WithoutCancel(ctx), id)
+		switch {
+		case err == nil:
+			r.users.store(id, u.Clone(), generation)
+		case errors.Is(err, user.ErrUserNotFound):
+			r.users.store(id, nil, generation)
+		}
+		return u, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-loaded:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*user.User).Clone(), nil
+	}
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	return r.next.FindByEmail(ctx, email)
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	return r.next.FindByUsername(ctx, username)
+}
+
+func (r *userRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	return r.next.FindAll(ctx)
+}
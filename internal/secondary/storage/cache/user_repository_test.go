@@ -0,0 +1,355 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+
+func (fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error { return nil }
+
+// countingRepository counts the lookups by ID that reach it. If gate is
+// set, they wait for it to close before returning what they read.
+type countingRepository struct {
+	user.UserRepository
+	reads atomic.Int32
+	gate  chan struct{}
+	err   error
+}
+
+func (r *countingRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	r.reads.Add(1)
+	u, err := r.UserRepository.FindByID(ctx, id)
+	if r.gate != nil {
+		<-r.gate
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return u, err
+}
+
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+var testConfig = Config{Size: 2, TTL: time.Minute, NegativeTTL: time.Second}
+
+func newTestCache(t *testing.T, cfg Config) (*Users, *countingRepository, *clock) {
+	t.Helper()
+	c := NewUsers(cfg)
+	clk := &clock{now: time.Date(2026, time.October, 19, 12, 0, 0, 0, time.UTC)}
+	c.now = clk.Now
+	return c, &countingRepository{UserRepository: memory.NewUserRepository()}, clk
+}
+
+func newTestUser(t *testing.T, email string) *user.User {
+	t.Helper()
+	u, err := user.NewUser(context.Background(), user.NewUserParams{
+		Email:       email,
+		Username:    "ada",
+		Password:    "correct horse",
+		DateOfBirth: time.Date(1960, time.December, 10, 0, 0, 0, 0, time.UTC),
+		WeekAnchor:  week.Anchor{Mode: week.ModeCalendar, FirstWeekday: time.Sunday},
+		TimeZone:    "Europe/London",
+	}, fakeHasher{})
+	require.NoError(t, err)
+	return u
+}
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("serves repeated lookups from the cache", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		first, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		require.NoError(t, first.ChangeTimeZone("Asia/Tokyo"))
+		second, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(1), next.reads.Load())
+		assert.Equal(t, "Europe/London", second.Location().String(), "callers get copies they can change")
+		assert.Equal(t, Stats{Hits: 1, Misses: 1}, c.Stats())
+	})
+
+	t.Run("reads again once an entry expires", func(t *testing.T) {
+		c, next, clk := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		_, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		clk.Advance(testConfig.TTL)
+		_, err = repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+
+		assert.Equal(t, int32(2), next.reads.Load())
+		assert.Equal(t, Stats{Misses: 2}, c.Stats())
+	})
+
+	t.Run("remembers users that were not found", func(t *testing.T) {
+		c, next, clk := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		id := uuid.New()
+
+		for range 2 {
+			_, err := repo.FindByID(ctx, id)
+			assert.ErrorIs(t, err, user.ErrUserNotFound)
+		}
+		assert.Equal(t, int32(1), next.reads.Load())
+
+		clk.Advance(testConfig.NegativeTTL)
+		_, err := repo.FindByID(ctx, id)
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		assert.Equal(t, int32(2), next.reads.Load(), "for less long than users that were")
+	})
+
+	t.Run("forgets a missing user once it is created", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		u := newTestUser(t, "ada@example.com")
+
+		_, err := repo.FindByID(ctx, u.ID())
+		require.ErrorIs(t, err, user.ErrUserNotFound)
+		require.NoError(t, repo.Create(ctx, u))
+
+		_, err = repo.FindByID(ctx, u.ID())
+		assert.NoError(t, err)
+	})
+
+	t.Run("does not cache other errors", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		next.err = errors.New("disk on fire")
+		repo := c.UserRepository(next)
+		id := uuid.New()
+
+		for range 2 {
+			_, err := repo.FindByID(ctx, id)
+			assert.ErrorIs(t, err, next.err)
+		}
+		assert.Equal(t, int32(2), next.reads.Load())
+	})
+
+	t.Run("saves invalidate the user", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+		_, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+
+		require.NoError(t, u.ChangeTimeZone("Asia/Tokyo"))
+		require.NoError(t, repo.Save(ctx, u))
+
+		found, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "Asia/Tokyo", found.Location().String())
+		assert.Equal(t, u.Version(), found.Version())
+	})
+
+	t.Run("evicts the least recently used", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		users := []*user.User{
+			newTestUser(t, "ada@example.com"),
+			newTestUser(t, "grace@example.com"),
+			newTestUser(t, "hedy@example.com"),
+		}
+		for _, u := range users {
+			require.NoError(t, repo.Create(ctx, u))
+		}
+
+		for _, i := range []int{0, 1, 0, 2} {
+			_, err := repo.FindByID(ctx, users[i].ID())
+			require.NoError(t, err)
+		}
+		require.Equal(t, int32(3), next.reads.Load())
+
+		_, err := repo.FindByID(ctx, users[0].ID())
+		require.NoError(t, err)
+		assert.Equal(t, int32(3), next.reads.Load(), "the first user was used since the second")
+		_, err = repo.FindByID(ctx, users[1].ID())
+		require.NoError(t, err)
+		assert.Equal(t, int32(4), next.reads.Load())
+	})
+
+	t.Run("other lookups pass through", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		require.NoError(t, repo.Create(ctx, newTestUser(t, "ada@example.com")))
+
+		_, err := repo.FindByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		byName, err := repo.FindByUsername(ctx, "ada")
+		require.NoError(t, err)
+		all, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+
+		assert.Len(t, byName, 1)
+		assert.Len(t, all, 1)
+		assert.Equal(t, Stats{}, c.Stats())
+	})
+}
+
+func TestUserRepository_ConcurrentMisses(t *testing.T) {
+	c, next, _ := newTestCache(t, testConfig)
+	repo := c.UserRepository(next)
+	u := newTestUser(t, "ada@example.com")
+	require.NoError(t, repo.Create(context.Background(), u))
+	next.gate = make(chan struct{})
+
+	const callers = 20
+	var wg sync.WaitGroup
+	for range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			found, err := repo.FindByID(context.Background(), u.ID())
+			if assert.NoError(t, err) {
+				assert.Equal(t, u.ID(), found.ID())
+			}
+		}()
+	}
+	require.Eventually(t, func() bool { return c.Stats().Misses == callers }, time.Second, time.Millisecond)
+	// Give the last callers to miss time to join the read.
+	time.Sleep(10 * time.Millisecond)
+	close(next.gate)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), next.reads.Load())
+}
+
+func TestUserRepository_CancelledWhileWaiting(t *testing.T) {
+	c, next, _ := newTestCache(t, testConfig)
+	repo := c.UserRepository(next)
+	u := newTestUser(t, "ada@example.com")
+	require.NoError(t, repo.Create(context.Background(), u))
+	next.gate = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := repo.FindByID(ctx, u.ID())
+		done <- err
+	}()
+	require.Eventually(t, func() bool { return next.reads.Load() == 1 }, time.Second, time.Millisecond)
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	close(next.gate)
+	require.Eventually(t, func() bool {
+		_, err := repo.FindByID(context.Background(), u.ID())
+		return err == nil && c.Stats().Hits > 0
+	}, time.Second, time.Millisecond, "the read carried on and was cached")
+	assert.Equal(t, int32(1), next.reads.Load())
+}
+
+// TestUserRepository_ReadRacingSave checks that a read which started
+// before a save does not cache what it read after the save invalidated it.
+func TestUserRepository_ReadRacingSave(t *testing.T) {
+	ctx := context.Background()
+	c, next, _ := newTestCache(t, testConfig)
+	repo := c.UserRepository(next)
+	u := newTestUser(t, "ada@example.com")
+	require.NoError(t, repo.Create(ctx, u))
+	next.gate = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := repo.FindByID(ctx, u.ID())
+		assert.NoError(t, err)
+	}()
+	require.Eventually(t, func() bool { return next.reads.Load() == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, u.ChangeTimeZone("Asia/Tokyo"))
+	require.NoError(t, repo.Save(ctx, u))
+	close(next.gate)
+	<-done
+
+	found, err := repo.FindByID(ctx, u.ID())
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", found.Location().String())
+}
+
+func TestTransactions(t *testing.T) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	t.Run("lookups in a transaction bypass the cache", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		transactions := c.Transactions(memory.NewTransactionManager())
+		u := newTestUser(t, "ada@example.com")
+
+		_, err := repo.FindByID(ctx, u.ID())
+		require.ErrorIs(t, err, user.ErrUserNotFound)
+
+		err = transactions.Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.Create(ctx, u))
+			_, err := repo.FindByID(ctx, u.ID())
+			require.NoError(t, err, "the transaction sees its own writes")
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+
+		_, err = repo.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound, "nor does it cache them")
+		assert.Equal(t, Stats{Misses: 2}, c.Stats())
+	})
+
+	t.Run("writes are invalidated again when it commits", func(t *testing.T) {
+		c, next, _ := newTestCache(t, testConfig)
+		repo := c.UserRepository(next)
+		transactions := c.Transactions(memory.NewTransactionManager())
+		u := newTestUser(t, "ada@example.com")
+
+		require.NoError(t, transactions.Run(ctx, func(txCtx context.Context) error {
+			require.NoError(t, transactions.Run(txCtx, func(txCtx context.Context) error {
+				return repo.Create(txCtx, u)
+			}))
+			_, err := repo.FindByID(ctx, u.ID())
+			require.ErrorIs(t, err, user.ErrUserNotFound, "others do not see it until it commits")
+			return nil
+		}))
+
+		found, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, u.ID(), found.ID())
+	})
+}
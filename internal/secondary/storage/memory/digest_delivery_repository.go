@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+)
+
+type deliveryKey struct {
+	userID uuid.UUID
+	week   int
+}
+
+func (k deliveryKey) String() string {
+	return fmt.Sprintf("%s/%d", k.userID, k.week)
+}
+
+type inMemoryDeliveryRepository struct {
+	txBase
+	deliveries map[deliveryKey]digest.Delivery
+	mu         sync.Mutex
+
+	// base, in a fork, is the repository deliveries overlays, less the
+	// deliveries in deleted.
+	base    *inMemoryDeliveryRepository
+	deleted map[deliveryKey]struct{}
+
+	// journal, if set, logs every change before it is made.
+	journal *journal
+}
+
+func NewDeliveryRepository() digest.DeliveryRepository {
+	return &inMemoryDeliveryRepository{
+		txBase:     newTxBase(),
+		deliveries: make(map[deliveryKey]digest.Delivery),
+		mu:         sync.Mutex{},
+	}
+}
+
+func (r *inMemoryDeliveryRepository) Record(ctx context.Context, d digest.Delivery) error {
+	return write(ctx, r, func(r *inMemoryDeliveryRepository) error {
+		key := deliveryKey{userID: d.UserID, week: d.Week}
+		if r.exists(key) {
+			return digest.ErrAlreadyDelivered
+		}
+		if err := r.journal.put(key.String(), d); err != nil {
+			return err
+		}
+		r.deliveries[key] = d
+		delete(r.deleted, key)
+		return nil
+	})
+}
+
+func (r *inMemoryDeliveryRepository) Delete(ctx context.Context, userID uuid.UUID, week int) error {
+	return write(ctx, r, func(r *inMemoryDeliveryRepository) error {
+		key := deliveryKey{userID: userID, week: week}
+		if !r.exists(key) {
+			return nil
+		}
+		if err := r.journal.delete(key.String()); err != nil {
+			return err
+		}
+		delete(r.deliveries, key)
+		if r.base != nil {
+			r.deleted[key] = struct{}{}
+		}
+		return nil
+	})
+}
+
+// exists reports whether a delivery is recorded under key, looking through
+// a fork to its base.
+func (r *inMemoryDeliveryRepository) exists(key deliveryKey) bool {
+	if _, ok := r.deliveries[key]; ok {
+		return true
+	}
+	if r.base == nil {
+		return false
+	}
+	_, deleted := r.deleted[key]
+	_, ok := r.base.deliveries[key]
+	return ok && !deleted
+}
+
+func (r *inMemoryDeliveryRepository) lock() {
+	if r.base != nil {
+		r.base.rlock()
+	}
+	r.mu.Lock()
+}
+
+func (r *inMemoryDeliveryRepository) unlock() {
+	r.mu.Unlock()
+	if r.base != nil {
+		r.base.runlock()
+	}
+}
+
+func (r *inMemoryDeliveryRepository) rlock()   { r.lock() }
+func (r *inMemoryDeliveryRepository) runlock() { r.unlock() }
+
+func (r *inMemoryDeliveryRepository) fork() txRepository {
+	return &inMemoryDeliveryRepository{
+		txBase:     r.txBase,
+		deliveries: make(map[deliveryKey]digest.Delivery),
+		base:       r,
+		deleted:    make(map[deliveryKey]struct{}),
+		journal:    r.journal.buffer(),
+	}
+}
+
+func (r *inMemoryDeliveryRepository) replace(fork txRepository) error {
+	f := fork.(*inMemoryDeliveryRepository)
+	if err := r.journal.commit(f.journal); err != nil {
+		return err
+	}
+	for key := range f.deleted {
+		delete(r.deliveries, key)
+	}
+	maps.Copy(r.deliveries, f.deliveries)
+	return nil
+}
+
+// apply replays a logged change.
+func (r *inMemoryDeliveryRepository) apply(c change) error {
+	userID, week, _ := strings.Cut(c.Key, "/")
+	id, idErr := uuid.Parse(userID)
+	n, weekErr := strconv.Atoi(week)
+	if idErr != nil || weekErr != nil {
+		return fmt.Errorf("delivery key %q: %w", c.Key, ErrCorrupt)
+	}
+	key := deliveryKey{userID: id, week: n}
+
+	switch c.Op {
+	case opPut:
+		var d digest.Delivery
+		if err := json.Unmarshal(c.Value, &d); err != nil {
+			return fmt.Errorf("delivery %s: %w: %w", key, ErrCorrupt, err)
+		}
+		r.deliveries[key] = d
+	case opDelete:
+		delete(r.deliveries, key)
+	default:
+		return fmt.Errorf("delivery %s: unknown operation %q: %w", key, c.Op, ErrCorrupt)
+	}
+	return nil
+}
+
+func (r *inMemoryDeliveryRepository) snapshot() error {
+	r.mu.Lock()
+	seq, stale, err := r.journal.rotate()
+	if err != nil || !stale {
+		r.mu.Unlock()
+		return err
+	}
+	deliveries := make([]digest.Delivery, 0, len(r.deliveries))
+	for _, d := range r.deliveries {
+		deliveries = append(deliveries, d)
+	}
+	r.mu.Unlock()
+
+	return r.journal.writeSnapshot(seq, len(deliveries), func(yield func(string, any) bool) {
+		for _, d := range deliveries {
+			key := deliveryKey{userID: d.UserID, week: d.Week}
+			if !yield(key.String(), d) {
+				return
+			}
+		}
+	})
+}
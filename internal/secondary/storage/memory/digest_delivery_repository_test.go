@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryRepository(t *testing.T) {
+	userID := uuid.New()
+	delivery := digest.Delivery{UserID: userID, Week: 1706, SentAt: time.Now().UTC()}
+
+	t.Run("record once per user and week", func(t *testing.T) {
+		repo := NewDeliveryRepository()
+
+		require.NoError(t, repo.Record(context.Background(), delivery))
+
+		err := repo.Record(context.Background(), delivery)
+		assert.ErrorIs(t, err, digest.ErrAlreadyDelivered)
+
+		require.NoError(t, repo.Record(context.Background(), digest.Delivery{UserID: userID, Week: 1707}))
+		require.NoError(t, repo.Record(context.Background(), digest.Delivery{UserID: uuid.New(), Week: 1706}))
+	})
+
+	t.Run("deleted delivery can be recorded again", func(t *testing.T) {
+		repo := NewDeliveryRepository()
+
+		require.NoError(t, repo.Record(context.Background(), delivery))
+		require.NoError(t, repo.Delete(context.Background(), userID, delivery.Week))
+
+		assert.NoError(t, repo.Record(context.Background(), delivery))
+	})
+}
@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/idempotency"
+)
+
+type inMemoryIdempotencyStore struct {
+	records map[string]claimedRecord
+	claims  int
+	mu      sync.Mutex
+}
+
+type claimedRecord struct {
+	idempotency.Record
+	claim string
+}
+
+func NewIdempotencyStore() idempotency.Store {
+	return &inMemoryIdempotencyStore{
+		records: make(map[string]claimedRecord),
+		mu:      sync.Mutex{},
+	}
+}
+
+func (s *inMemoryIdempotencyStore) Claim(ctx context.Context, key, fingerprint string, now, until time.Time) (idempotency.Record, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.claims++
+	if s.claims%sweepEvery == 0 {
+		for k, rec := range s.records {
+			if !now.Before(rec.ExpiresAt) {
+				delete(s.records, k)
+			}
+		}
+	}
+
+	if rec, ok := s.records[key]; ok && now.Before(rec.ExpiresAt) {
+		return rec.Record, "", nil
+	}
+	claim := rand.Text()
+	s.records[key] = claimedRecord{
+		Record: idempotency.Record{Fingerprint: fingerprint, ExpiresAt: until},
+		claim:  claim,
+	}
+	return idempotency.Record{}, claim, nil
+}
+
+func (s *inMemoryIdempotencyStore) Complete(ctx context.Context, key, claim string, resp idempotency.Response, expires time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[key]
+	if !ok || rec.claim != claim || rec.Response != nil {
+		return idempotency.ErrClaimLost
+	}
+	rec.Response = &resp
+	rec.ExpiresAt = expires
+	s.records[key] = rec
+	return nil
+}
+
+func (s *inMemoryIdempotencyStore) Release(ctx context.Context, key, claim string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if rec, ok := s.records[key]; ok && rec.claim == claim && rec.Response == nil {
+		delete(s.records, key)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/idempotency"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("exactly one concurrent claim wins", func(t *testing.T) {
+		store := NewIdempotencyStore()
+
+		var claimed atomic.Int32
+		var wg sync.WaitGroup
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, claim, err := store.Claim(ctx, "key", "fp", now, now.Add(time.Minute))
+				assert.NoError(t, err)
+				if claim != "" {
+					claimed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(1), claimed.Load())
+	})
+
+	t.Run("completed responses are returned until they expire", func(t *testing.T) {
+		store := NewIdempotencyStore()
+		_, claim, err := store.Claim(ctx, "key", "fp", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.NotEmpty(t, claim)
+
+		resp := idempotency.Response{Status: http.StatusCreated, Header: http.Header{"Location": {"/x"}}, Body: []byte("{}")}
+		require.NoError(t, store.Complete(ctx, "key", claim, resp, now.Add(time.Hour)))
+
+		rec, claim, err := store.Claim(ctx, "key", "other", now.Add(30*time.Minute), now.Add(31*time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, claim)
+		assert.Equal(t, "fp", rec.Fingerprint)
+		assert.Equal(t, &resp, rec.Response)
+
+		_, claim, err = store.Claim(ctx, "key", "other", now.Add(time.Hour), now.Add(61*time.Minute))
+		require.NoError(t, err)
+		assert.NotEmpty(t, claim, "expired")
+	})
+
+	t.Run("released keys can be claimed again", func(t *testing.T) {
+		store := NewIdempotencyStore()
+		_, claim, err := store.Claim(ctx, "key", "fp", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		require.NotEmpty(t, claim)
+
+		rec, other, err := store.Claim(ctx, "key", "fp", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, other)
+		assert.Nil(t, rec.Response, "in flight")
+
+		require.NoError(t, store.Release(ctx, "key", claim))
+		_, claim, err = store.Claim(ctx, "key", "fp", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		assert.NotEmpty(t, claim)
+	})
+
+	t.Run("expired claim cannot complete or release the next one", func(t *testing.T) {
+		store := NewIdempotencyStore()
+		_, expired, err := store.Claim(ctx, "key", "fp", now, now.Add(time.Minute))
+		require.NoError(t, err)
+		_, current, err := store.Claim(ctx, "key", "fp", now.Add(time.Minute), now.Add(2*time.Minute))
+		require.NoError(t, err)
+		require.NotEmpty(t, current)
+
+		resp := idempotency.Response{Status: http.StatusCreated}
+		assert.ErrorIs(t, store.Complete(ctx, "key", expired, resp, now.Add(time.Hour)), idempotency.ErrClaimLost)
+		require.NoError(t, store.Release(ctx, "key", expired))
+
+		rec, claim, err := store.Claim(ctx, "key", "fp", now.Add(time.Minute), now.Add(2*time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, claim, "current claim is still held")
+		assert.Nil(t, rec.Response)
+
+		require.NoError(t, store.Complete(ctx, "key", current, resp, now.Add(time.Hour)))
+		assert.ErrorIs(t, store.Complete(ctx, "key", current, resp, now.Add(time.Hour)), idempotency.ErrClaimLost, "already completed")
+	})
+}
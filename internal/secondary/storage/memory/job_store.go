@@ -0,0 +1,112 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+)
+
+type inMemoryJobStore struct {
+	jobs map[uuid.UUID]*job.Job
+	mu   sync.Mutex
+}
+
+func NewJobStore() job.Store {
+	return &inMemoryJobStore{
+		jobs: make(map[uuid.UUID]*job.Job),
+		mu:   sync.Mutex{},
+	}
+}
+
+func (s *inMemoryJobStore) Enqueue(ctx context.Context, j *job.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *j
+	s.jobs[j.ID] = &stored
+	return nil
+}
+
+func (s *inMemoryJobStore) Claim(ctx context.Context, now time.Time, lease time.Duration) (*job.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *job.Job
+	for _, j := range s.jobs {
+		due := j.State == job.StatePending && !j.RunAt.After(now)
+		abandoned := j.State == job.StateRunning && !j.LockedUntil.After(now)
+		if (due || abandoned) && (next == nil || j.RunAt.Before(next.RunAt)) {
+			next = j
+		}
+	}
+	if next == nil {
+		return nil, job.ErrNoJobAvailable
+	}
+
+	next.State = job.StateRunning
+	next.Attempts++
+	next.LockedUntil = now.Add(lease)
+	next.UpdatedAt = now
+
+	claimed := *next
+	return &claimed, nil
+}
+
+func (s *inMemoryJobStore) Complete(ctx context.Context, id uuid.UUID, attempt int) error {
+	return s.update(id, attempt, func(j *job.Job) {
+		j.State = job.StateDone
+	})
+}
+
+func (s *inMemoryJobStore) Retry(ctx context.Context, id uuid.UUID, attempt int, runAt time.Time, lastErr string) error {
+	return s.update(id, attempt, func(j *job.Job) {
+		j.State = job.StatePending
+		j.RunAt = runAt
+		j.LastError = lastErr
+	})
+}
+
+func (s *inMemoryJobStore) Bury(ctx context.Context, id uuid.UUID, attempt int, lastErr string) error {
+	return s.update(id, attempt, func(j *job.Job) {
+		j.State = job.StateDead
+		j.LastError = lastErr
+	})
+}
+
+func (s *inMemoryJobStore) DeadLetters(ctx context.Context) ([]*job.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var dead []*job.Job
+	for _, j := range s.jobs {
+		if j.State == job.StateDead {
+			copied := *j
+			dead = append(dead, &copied)
+		}
+	}
+
+	sort.Slice(dead, func(i, k int) bool {
+		return dead[i].CreatedAt.Before(dead[k].CreatedAt)
+	})
+	return dead, nil
+}
+
+func (s *inMemoryJobStore) update(id uuid.UUID, attempt int, apply func(j *job.Job)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j, exists := s.jobs[id]
+	if !exists {
+		return job.ErrJobNotFound
+	}
+	if j.State != job.StateRunning || j.Attempts != attempt {
+		return job.ErrLeaseLost
+	}
+	apply(j)
+	j.LockedUntil = time.Time{}
+	j.UpdatedAt = time.Now().UTC()
+	return nil
+}
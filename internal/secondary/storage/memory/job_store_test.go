@@ -0,0 +1,120 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	N int `json:"n"`
+}
+
+func (testPayload) JobType() string { return "test" }
+
+func TestJobStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+	lease := time.Minute
+
+	newJob := func(t *testing.T, runAt time.Time) *job.Job {
+		j, err := job.New(testPayload{N: 1}, 2, runAt)
+		require.NoError(t, err)
+		return j
+	}
+
+	t.Run("claims the earliest due job once", func(t *testing.T) {
+		store := NewJobStore()
+		later := newJob(t, now.Add(-time.Second))
+		earlier := newJob(t, now.Add(-time.Minute))
+		future := newJob(t, now.Add(time.Hour))
+		for _, j := range []*job.Job{later, earlier, future} {
+			require.NoError(t, store.Enqueue(ctx, j))
+		}
+
+		first, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+		assert.Equal(t, earlier.ID, first.ID)
+		assert.Equal(t, job.StateRunning, first.State)
+		assert.Equal(t, 1, first.Attempts)
+
+		second, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+		assert.Equal(t, later.ID, second.ID)
+
+		_, err = store.Claim(ctx, now, lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+	})
+
+	t.Run("expired lease is claimed again", func(t *testing.T) {
+		store := NewJobStore()
+		j := newJob(t, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		_, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		claimed, err := store.Claim(ctx, now.Add(lease), lease)
+		require.NoError(t, err)
+		assert.Equal(t, 2, claimed.Attempts)
+	})
+
+	t.Run("retry, complete and bury", func(t *testing.T) {
+		store := NewJobStore()
+		j := newJob(t, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		_, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Retry(ctx, j.ID, 1, now.Add(time.Second), "boom"))
+		_, err = store.Claim(ctx, now, lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+
+		claimed, err := store.Claim(ctx, now.Add(time.Second), lease)
+		require.NoError(t, err)
+		assert.Equal(t, "boom", claimed.LastError)
+
+		require.NoError(t, store.Bury(ctx, j.ID, claimed.Attempts, "gave up"))
+		dead, err := store.DeadLetters(ctx)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, job.StateDead, dead[0].State)
+
+		done := newJob(t, now)
+		require.NoError(t, store.Enqueue(ctx, done))
+		_, err = store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+		require.NoError(t, store.Complete(ctx, done.ID, 1))
+		_, err = store.Claim(ctx, now.Add(time.Hour), lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		store := NewJobStore()
+
+		assert.ErrorIs(t, store.Complete(ctx, uuid.New(), 1), job.ErrJobNotFound)
+		assert.ErrorIs(t, store.Retry(ctx, uuid.New(), 1, now, ""), job.ErrJobNotFound)
+		assert.ErrorIs(t, store.Bury(ctx, uuid.New(), 1, ""), job.ErrJobNotFound)
+	})
+
+	t.Run("worker whose lease expired cannot finish the job", func(t *testing.T) {
+		store := NewJobStore()
+		j := newJob(t, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		expired, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+		current, err := store.Claim(ctx, now.Add(lease), lease)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, store.Complete(ctx, j.ID, expired.Attempts), job.ErrLeaseLost)
+		assert.ErrorIs(t, store.Retry(ctx, j.ID, expired.Attempts, now, ""), job.ErrLeaseLost)
+		assert.ErrorIs(t, store.Bury(ctx, j.ID, expired.Attempts, ""), job.ErrLeaseLost)
+
+		require.NoError(t, store.Complete(ctx, j.ID, current.Attempts))
+		assert.ErrorIs(t, store.Complete(ctx, j.ID, current.Attempts), job.ErrLeaseLost, "job is no longer running")
+	})
+}
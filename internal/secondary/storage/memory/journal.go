@@ -0,0 +1,512 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// FsyncAlways syncs the change log before a change is acknowledged.
+	FsyncAlways = "always"
+	// FsyncInterval syncs it periodically, so that a crash of the machine
+	// loses at most the changes of the last interval.
+	FsyncInterval = "interval"
+	// FsyncNever leaves syncing to the operating system.
+	FsyncNever = "never"
+
+	maxRecord = 1 << 30
+)
+
+// ErrCorrupt is returned when persisted data fails its checksums or is
+// otherwise unreadable. Only a record cut short at the end of the newest
+// change log is accepted, as the trace of a crash mid-write, and dropped.
+var ErrCorrupt = errors.New("persisted data is corrupt")
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// change puts or deletes one item of a repository.
+type change struct {
+	Op    string          `json:"op"`
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// logRecord is a numbered batch of changes that are applied together.
+type logRecord struct {
+	Seq     uint64   `json:"seq"`
+	Changes []change `json:"changes"`
+}
+
+// snapshotHeader starts a snapshot, which then holds Count put changes
+// making up the state after change Seq.
+type snapshotHeader struct {
+	Seq   uint64 `json:"seq"`
+	Count int    `json:"count"`
+}
+
+// journal keeps one repository in files named after it: a snapshot and the
+// change logs since, each named by the sequence number it starts at. Every
+// record is framed by its length and CRC-32C checksum.
+type journal struct {
+	dir   string
+	name  string
+	fsync string
+
+	mu          sync.Mutex
+	buffered    bool
+	pending     []change
+	log         *os.File
+	start       uint64
+	seq         uint64
+	snapshotted uint64
+	dirty       bool
+	err         error
+}
+
+// openJournal replays the snapshot and change logs of the named repository
+// through apply and opens the newest log for appending.
+func openJournal(dir, name, fsync string, apply func(change) error) (*journal, error) {
+	j := &journal{dir: dir, name: name, fsync: fsync}
+	if err := j.replay(apply); err != nil {
+		return nil, fmt.Errorf("replaying %s: %w", name, err)
+	}
+
+	logs, err := j.logs()
+	if err != nil {
+		return nil, err
+	}
+	j.start = j.seq + 1
+	if len(logs) > 0 {
+		j.start = logs[len(logs)-1]
+	}
+	j.log, err = os.OpenFile(j.logPath(j.start), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	return j, syncDir(dir)
+}
+
+func (j *journal) snapshotPath() string {
+	return filepath.Join(j.dir, j.name+".snapshot")
+}
+
+func (j *journal) logPath(start uint64) string {
+	return filepath.Join(j.dir, fmt.Sprintf("%s.%020d.log", j.name, start))
+}
+
+// logs returns the start of every change log, oldest first.
+func (j *journal) logs() ([]uint64, error) {
+	paths, err := filepath.Glob(filepath.Join(j.dir, j.name+".*.log"))
+	if err != nil {
+		return nil, err
+	}
+	var starts []uint64
+	for _, path := range paths {
+		number := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), j.name+"."), ".log")
+		start, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			continue
+		}
+		starts = append(starts, start)
+	}
+	slices.Sort(starts)
+	return starts, nil
+}
+
+func (j *journal) replay(apply func(change) error) error {
+	if err := j.replaySnapshot(apply); err != nil {
+		return err
+	}
+
+	logs, err := j.logs()
+	if err != nil {
+		return err
+	}
+	for i, start := range logs {
+		data, err := os.ReadFile(j.logPath(start))
+		if err != nil {
+			return err
+		}
+		records, valid, err := parseLog(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", j.logPath(start), err)
+		}
+		if valid < len(data) {
+			if i < len(logs)-1 {
+				return fmt.Errorf("%s: truncated: %w", j.logPath(start), ErrCorrupt)
+			}
+			slog.Warn("dropping a change cut short by a crash", "log", j.logPath(start), "bytes", len(data)-valid)
+			if err := os.Truncate(j.logPath(start), int64(valid)); err != nil {
+				return err
+			}
+		}
+
+		for _, rec := range records {
+			if rec.Seq <= j.seq {
+				// Already in the snapshot.
+				continue
+			}
+			if rec.Seq != j.seq+1 {
+				return fmt.Errorf("%s: change %d follows %d: %w", j.logPath(start), rec.Seq, j.seq, ErrCorrupt)
+			}
+			for _, c := range rec.Changes {
+				if err := apply(c); err != nil {
+					return fmt.Errorf("applying change %d: %w", rec.Seq, err)
+				}
+			}
+			j.seq = rec.Seq
+		}
+	}
+	return nil
+}
+
+func (j *journal) replaySnapshot(apply func(change) error) error {
+	f, err := os.Open(j.snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var header snapshotHeader
+	if err := readJSONRecord(r, &header); err != nil {
+		return fmt.Errorf("%s: %w", j.snapshotPath(), err)
+	}
+	for range header.Count {
+		var c change
+		if err := readJSONRecord(r, &c); err != nil {
+			return fmt.Errorf("%s: %w", j.snapshotPath(), err)
+		}
+		if err := apply(c); err != nil {
+			return fmt.Errorf("applying snapshot: %w", err)
+		}
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		return fmt.Errorf("%s: trailing data: %w", j.snapshotPath(), ErrCorrupt)
+	}
+	j.seq, j.snapshotted = header.Seq, header.Seq
+	return nil
+}
+
+// put and delete log a single change. On a nil journal they do nothing, so
+// that repositories can call them whether or not they are persisted.
+func (j *journal) put(key string, value any) error {
+	if j == nil {
+		return nil
+	}
+	c, err := putChange(key, value)
+	if err != nil {
+		return err
+	}
+	return j.append(c)
+}
+
+func (j *journal) delete(key string) error {
+	if j == nil {
+		return nil
+	}
+	return j.append(change{Op: opDelete, Key: key})
+}
+
+func putChange(key string, value any) (change, error) {
+	data, err := json.Marshal(value)
+	return change{Op: opPut, Key: key, Value: data}, err
+}
+
+// append writes changes to the log as one record, so that they are
+// replayed all or not at all. The caller applies them only if it succeeds.
+// After a failed write the log may end in a partial record, so the journal
+// refuses further changes.
+func (j *journal) append(changes ...change) error {
+	if j == nil || len(changes) == 0 {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.buffered {
+		j.pending = append(j.pending, changes...)
+		return nil
+	}
+	if j.err != nil {
+		return j.err
+	}
+	payload, err := json.Marshal(logRecord{Seq: j.seq + 1, Changes: changes})
+	if err != nil {
+		return err
+	}
+
+	if _, err := j.log.Write(frame(payload)); err != nil {
+		j.err = fmt.Errorf("writing %s change log: %w", j.name, err)
+		return j.err
+	}
+	if j.fsync == FsyncAlways {
+		if err := j.log.Sync(); err != nil {
+			j.err = fmt.Errorf("syncing %s change log: %w", j.name, err)
+			return j.err
+		}
+	}
+	j.seq++
+	j.dirty = true
+	return nil
+}
+
+// buffer returns a journal that keeps changes for a transaction to append
+// to j when it commits, or nil if j is.
+func (j *journal) buffer() *journal {
+	if j == nil {
+		return nil
+	}
+	return &journal{name: j.name, buffered: true}
+}
+
+// commit appends the changes buffered in b as one record.
+func (j *journal) commit(b *journal) error {
+	if j == nil {
+		return nil
+	}
+	return j.append(b.pending...)
+}
+
+// sync flushes the log to stable storage if it changed since last time.
+func (j *journal) sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if !j.dirty || j.err != nil {
+		return j.err
+	}
+	if err := j.log.Sync(); err != nil {
+		j.err = fmt.Errorf("syncing %s change log: %w", j.name, err)
+		return j.err
+	}
+	j.dirty = false
+	return nil
+}
+
+// rotate starts a new change log and returns the number of the last change
+// in the old one, or false if the snapshot already covers it. The caller
+// must keep changes out until it has captured the state as of that change
+// for writeSnapshot.
+func (j *journal) rotate() (uint64, bool, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.err != nil {
+		return 0, false, j.err
+	}
+	if j.seq == j.snapshotted {
+		return j.seq, false, nil
+	}
+	if j.seq+1 == j.start {
+		// Nothing was logged since the last rotation.
+		return j.seq, true, nil
+	}
+	log, err := os.OpenFile(j.logPath(j.seq+1), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return 0, false, err
+	}
+	// Whatever the policy, the old log must be durable before a snapshot
+	// allows it to be removed.
+	err = j.log.Sync()
+	j.log.Close()
+	j.log, j.start, j.dirty = log, j.seq+1, false
+	if err != nil {
+		return 0, false, err
+	}
+	return j.seq, true, syncDir(j.dir)
+}
+
+// writeSnapshot atomically replaces the snapshot with the state after
+// change seq, given as count items, and then removes the change logs it
+// covers.
+func (j *journal) writeSnapshot(seq uint64, count int, items iter.Seq2[string, any]) error {
+	tmp, err := os.CreateTemp(j.dir, j.name+".snapshot.*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	w := bufio.NewWriter(tmp)
+	if err := writeJSONRecord(w, snapshotHeader{Seq: seq, Count: count}); err != nil {
+		return err
+	}
+	written := 0
+	for key, value := range items {
+		var c change
+		if c, err = putChange(key, value); err != nil {
+			break
+		}
+		if err = writeJSONRecord(w, c); err != nil {
+			break
+		}
+		written++
+	}
+	if err != nil {
+		return err
+	}
+	if written != count {
+		return fmt.Errorf("snapshot of %s has %d items, expected %d", j.name, written, count)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), j.snapshotPath()); err != nil {
+		return err
+	}
+	if err := syncDir(j.dir); err != nil {
+		return err
+	}
+	j.mu.Lock()
+	j.snapshotted = seq
+	j.mu.Unlock()
+
+	logs, err := j.logs()
+	if err != nil {
+		return err
+	}
+	for _, start := range logs {
+		if start <= seq {
+			if err := os.Remove(j.logPath(start)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (j *journal) close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	err := j.log.Sync()
+	return errors.Join(err, j.log.Close())
+}
+
+// frame prefixes payload with its length and checksum. The length lets a
+// reader tell a record cut short at the end of a log from one followed by
+// more; the checksum catches a record written only in part, which JSON
+// alone might still parse.
+func frame(payload []byte) []byte {
+	record := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint32(record[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.Checksum(payload, crcTable))
+	return append(record, payload...)
+}
+
+func writeJSONRecord(w io.Writer, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(frame(payload))
+	return err
+}
+
+func readJSONRecord(r io.Reader, v any) error {
+	var header [8]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return fmt.Errorf("reading record: %w", ErrCorrupt)
+	}
+	size := binary.BigEndian.Uint32(header[:4])
+	if size > maxRecord {
+		return fmt.Errorf("record of %d bytes: %w", size, ErrCorrupt)
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return fmt.Errorf("reading record: %w", ErrCorrupt)
+	}
+	if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:]) {
+		return fmt.Errorf("checksum mismatch: %w", ErrCorrupt)
+	}
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupt, err)
+	}
+	return nil
+}
+
+// parseLog returns the records in a change log and the length of the part
+// they take up. A last record that runs past the end of data or, having
+// been written only in part, fails its checksum is left out; a record
+// that fails its checksum anywhere else is corruption. So is one whose
+// length runs past the end of data although its whole payload is there:
+// then the length is what is damaged, and records after it would be lost.
+func parseLog(data []byte) ([]logRecord, int, error) {
+	var records []logRecord
+	offset := 0
+	for offset < len(data) {
+		rest := data[offset:]
+		if len(rest) < 8 {
+			break
+		}
+		size := int(binary.BigEndian.Uint32(rest[:4]))
+		if size > len(rest)-8 {
+			if size > maxRecord || startsWithJSON(rest[8:]) {
+				return nil, 0, fmt.Errorf("record length %d at offset %d: %w", size, offset, ErrCorrupt)
+			}
+			break
+		}
+		payload := rest[8 : 8+size]
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(rest[4:8]) {
+			if 8+size == len(rest) {
+				break
+			}
+			return nil, 0, fmt.Errorf("checksum mismatch at offset %d: %w", offset, ErrCorrupt)
+		}
+
+		var rec logRecord
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return nil, 0, fmt.Errorf("record at offset %d: %w: %w", offset, ErrCorrupt, err)
+		}
+		records = append(records, rec)
+		offset += 8 + size
+	}
+	return records, offset, nil
+}
+
+// startsWithJSON reports whether data begins with a complete JSON value.
+// A payload cut short by a crash never does, being a strict prefix of an
+// object.
+func startsWithJSON(data []byte) bool {
+	var v json.RawMessage
+	return json.NewDecoder(bytes.NewReader(data)).Decode(&v) == nil
+}
+
+// syncDir makes renames and newly created files in dir durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
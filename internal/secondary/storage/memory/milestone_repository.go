@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"maps"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+)
+
+type inMemoryMilestoneRepository struct {
+	txBase
+	milestones map[uuid.UUID]*milestone.Milestone
+	mu         sync.RWMutex
+
+	// base, in a fork, is the repository milestones overlays, less the
+	// milestones in deleted.
+	base    *inMemoryMilestoneRepository
+	deleted map[uuid.UUID]struct{}
+
+	// journal, if set, logs every change before it is made.
+	journal *journal
+}
+
+func NewMilestoneRepository() milestone.MilestoneRepository {
+	return &inMemoryMilestoneRepository{
+		txBase:     newTxBase(),
+		milestones: make(map[uuid.UUID]*milestone.Milestone),
+		mu:         sync.RWMutex{},
+	}
+}
+
+func (r *inMemoryMilestoneRepository) Save(ctx context.Context, m *milestone.Milestone) error {
+	return write(ctx, r, func(r *inMemoryMilestoneRepository) error {
+		if err := r.journal.put(m.ID().String(), newMilestoneRecord(m)); err != nil {
+			return err
+		}
+		r.store(m)
+		return nil
+	})
+}
+
+func (r *inMemoryMilestoneRepository) SaveAll(ctx context.Context, milestones []*milestone.Milestone) error {
+	return write(ctx, r, func(r *inMemoryMilestoneRepository) error {
+		return r.saveAll(milestones)
+	})
+}
+
+func (r *inMemoryMilestoneRepository) saveAll(milestones []*milestone.Milestone) error {
+	if r.journal != nil {
+		changes := make([]change, 0, len(milestones))
+		for _, m := range milestones {
+			c, err := putChange(m.ID().String(), newMilestoneRecord(m))
+			if err != nil {
+				return err
+			}
+			changes = append(changes, c)
+		}
+		if err := r.journal.append(changes...); err != nil {
+			return err
+		}
+	}
+	for _, m := range milestones {
+		r.store(m)
+	}
+	return nil
+}
+
+func (r *inMemoryMilestoneRepository) FindByUserID(ctx context.Context, userID uuid.UUID) ([]*milestone.Milestone, error) {
+	r, unlock := read(ctx, r)
+	defer unlock()
+
+	var found []*milestone.Milestone
+	for _, m := range r.all() {
+		if m.UserID() == userID {
+			found = append(found, m)
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].Date().Before(found[j].Date())
+	})
+	return found, nil
+}
+
+func (r *inMemoryMilestoneRepository) DeleteByImportID(ctx context.Context, userID, importID uuid.UUID) error {
+	return write(ctx, r, func(r *inMemoryMilestoneRepository) error {
+		return r.deleteByImportID(userID, importID)
+	})
+}
+
+func (r *inMemoryMilestoneRepository) deleteByImportID(userID, importID uuid.UUID) error {
+	if importID == uuid.Nil {
+		return milestone.ErrImportNotFound
+	}
+
+	var deleted []change
+	for id, m := range r.all() {
+		if m.UserID() == userID && m.ImportID() == importID {
+			deleted = append(deleted, change{Op: opDelete, Key: id.String()})
+		}
+	}
+
+	if len(deleted) == 0 {
+		return milestone.ErrImportNotFound
+	}
+	if err := r.journal.append(deleted...); err != nil {
+		return err
+	}
+	for _, c := range deleted {
+		r.delete(uuid.MustParse(c.Key))
+	}
+	return nil
+}
+
+func (r *inMemoryMilestoneRepository) store(m *milestone.Milestone) {
+	r.milestones[m.ID()] = m
+	delete(r.deleted, m.ID())
+}
+
+func (r *inMemoryMilestoneRepository) delete(id uuid.UUID) {
+	delete(r.milestones, id)
+	if r.base != nil {
+		r.deleted[id] = struct{}{}
+	}
+}
+
+// all yields the milestones stored, looking through a fork to its base.
+func (r *inMemoryMilestoneRepository) all() iter.Seq2[uuid.UUID, *milestone.Milestone] {
+	return func(yield func(uuid.UUID, *milestone.Milestone) bool) {
+		for id, m := range r.milestones {
+			if !yield(id, m) {
+				return
+			}
+		}
+		if r.base == nil {
+			return
+		}
+		for id, m := range r.base.milestones {
+			_, changed := r.milestones[id]
+			_, deleted := r.deleted[id]
+			if !changed && !deleted && !yield(id, m) {
+				return
+			}
+		}
+	}
+}
+
+func (r *inMemoryMilestoneRepository) lock() {
+	if r.base != nil {
+		r.base.rlock()
+	}
+	r.mu.Lock()
+}
+
+func (r *inMemoryMilestoneRepository) unlock() {
+	r.mu.Unlock()
+	if r.base != nil {
+		r.base.runlock()
+	}
+}
+
+func (r *inMemoryMilestoneRepository) rlock() {
+	if r.base != nil {
+		r.base.rlock()
+	}
+	r.mu.RLock()
+}
+
+func (r *inMemoryMilestoneRepository) runlock() {
+	r.mu.RUnlock()
+	if r.base != nil {
+		r.base.runlock()
+	}
+}
+
+// fork shares the milestones, which never change.
+func (r *inMemoryMilestoneRepository) fork() txRepository {
+	return &inMemoryMilestoneRepository{
+		txBase:     r.txBase,
+		milestones: make(map[uuid.UUID]*milestone.Milestone),
+		base:       r,
+		deleted:    make(map[uuid.UUID]struct{}),
+		journal:    r.journal.buffer(),
+	}
+}
+
+func (r *inMemoryMilestoneRepository) replace(fork txRepository) error {
+	f := fork.(*inMemoryMilestoneRepository)
+	if err := r.journal.commit(f.journal); err != nil {
+		return err
+	}
+	for id := range f.deleted {
+		delete(r.milestones, id)
+	}
+	maps.Copy(r.milestones, f.milestones)
+	return nil
+}
+
+// milestoneRecord is how a persisted repository writes a milestone to disk.
+type milestoneRecord struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Date        time.Time `json:"date"`
+	ImportID    uuid.UUID `json:"import_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func newMilestoneRecord(m *milestone.Milestone) milestoneRecord {
+	return milestoneRecord{
+		ID:          m.ID(),
+		UserID:      m.UserID(),
+		Title:       m.Title(),
+		Description: m.Description(),
+		Date:        m.Date(),
+		ImportID:    m.ImportID(),
+		CreatedAt:   m.CreatedAt(),
+	}
+}
+
+// apply replays a logged change.
+func (r *inMemoryMilestoneRepository) apply(c change) error {
+	id, err := uuid.Parse(c.Key)
+	if err != nil {
+		return fmt.Errorf("milestone key %q: %w", c.Key, ErrCorrupt)
+	}
+
+	switch c.Op {
+	case opPut:
+		var rec milestoneRecord
+		if err := json.Unmarshal(c.Value, &rec); err != nil {
+			return fmt.Errorf("milestone %s: %w: %w", id, ErrCorrupt, err)
+		}
+		r.milestones[id] = milestone.Restore(milestone.RestoreParams(rec))
+	case opDelete:
+		delete(r.milestones, id)
+	default:
+		return fmt.Errorf("milestone %s: unknown operation %q: %w", id, c.Op, ErrCorrupt)
+	}
+	return nil
+}
+
+func (r *inMemoryMilestoneRepository) snapshot() error {
+	r.mu.RLock()
+	seq, stale, err := r.journal.rotate()
+	if err != nil || !stale {
+		r.mu.RUnlock()
+		return err
+	}
+	milestones := make([]*milestone.Milestone, 0, len(r.milestones))
+	for _, m := range r.milestones {
+		milestones = append(milestones, m)
+	}
+	r.mu.RUnlock()
+
+	return r.journal.writeSnapshot(seq, len(milestones), func(yield func(string, any) bool) {
+		for _, m := range milestones {
+			if !yield(m.ID().String(), newMilestoneRecord(m)) {
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMilestoneRepository(t *testing.T) {
+	userID := uuid.New()
+
+	newMilestone := func(t *testing.T, userID uuid.UUID, title string, date time.Time) *milestone.Milestone {
+		m, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+			UserID: userID,
+			Title:  title,
+			Date:   date,
+		})
+		require.NoError(t, err, "failed to create test milestone")
+		return m
+	}
+
+	t.Run("find milestones by user ordered by date", func(t *testing.T) {
+		repo := NewMilestoneRepository()
+
+		later := newMilestone(t, userID, "Moved abroad", time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC))
+		earlier := newMilestone(t, userID, "Graduated", time.Date(2014, time.June, 12, 0, 0, 0, 0, time.UTC))
+		other := newMilestone(t, uuid.New(), "Someone else", time.Date(2015, time.May, 5, 0, 0, 0, 0, time.UTC))
+
+		for _, m := range []*milestone.Milestone{later, earlier, other} {
+			require.NoError(t, repo.Save(context.Background(), m))
+		}
+
+		found, err := repo.FindByUserID(context.Background(), userID)
+		require.NoError(t, err)
+
+		assert.Equal(t, []*milestone.Milestone{earlier, later}, found)
+	})
+
+	t.Run("delete milestones by import", func(t *testing.T) {
+		repo := NewMilestoneRepository()
+		importID := uuid.New()
+
+		imported, err := milestone.NewMilestone(milestone.NewMilestoneParams{
+			UserID:   userID,
+			Title:    "Imported",
+			Date:     time.Date(2016, time.April, 2, 0, 0, 0, 0, time.UTC),
+			ImportID: importID,
+		})
+		require.NoError(t, err)
+		manual := newMilestone(t, userID, "Manual", time.Date(2017, time.April, 2, 0, 0, 0, 0, time.UTC))
+
+		require.NoError(t, repo.SaveAll(context.Background(), []*milestone.Milestone{imported, manual}))
+
+		err = repo.DeleteByImportID(context.Background(), uuid.New(), importID)
+		assert.ErrorIs(t, err, milestone.ErrImportNotFound, "another user's import should not be deleted")
+
+		err = repo.DeleteByImportID(context.Background(), userID, importID)
+		require.NoError(t, err)
+
+		found, err := repo.FindByUserID(context.Background(), userID)
+		require.NoError(t, err)
+		assert.Equal(t, []*milestone.Milestone{manual}, found)
+
+		err = repo.DeleteByImportID(context.Background(), userID, importID)
+		assert.ErrorIs(t, err, milestone.ErrImportNotFound)
+	})
+
+	t.Run("no milestones for user", func(t *testing.T) {
+		repo := NewMilestoneRepository()
+
+		found, err := repo.FindByUserID(context.Background(), userID)
+
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+}
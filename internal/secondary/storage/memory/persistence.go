@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+)
+
+// PersistenceConfig keeps memory repositories in Dir across restarts. Each
+// change is appended to a log before it is applied, and every
+// SnapshotInterval the state is written out afresh so that the log can be
+// dropped. Fsync is one of FsyncAlways, FsyncInterval and FsyncNever.
+type PersistenceConfig struct {
+	Dir              string
+	SnapshotInterval time.Duration
+	Fsync            string
+	FsyncInterval    time.Duration
+}
+
+var DefaultPersistenceConfig = PersistenceConfig{
+	SnapshotInterval: 5 * time.Minute,
+	Fsync:            FsyncInterval,
+	FsyncInterval:    time.Second,
+}
+
+// persisted is a repository whose journal Persistence looks after.
+type persisted interface {
+	snapshot() error
+	log() *journal
+}
+
+func (r *inMemoryUserRepository) log() *journal      { return r.journal }
+func (r *inMemoryMilestoneRepository) log() *journal { return r.journal }
+func (r *inMemoryDeliveryRepository) log() *journal  { return r.journal }
+
+// Persistence opens memory repositories from disk and keeps them there.
+type Persistence struct {
+	cfg PersistenceConfig
+
+	mu    sync.Mutex
+	repos []persisted
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func OpenPersistence(cfg PersistenceConfig) (*Persistence, error) {
+	if err := os.MkdirAll(cfg.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating storage directory: %w", err)
+	}
+	return &Persistence{cfg: cfg}, nil
+}
+
+// UserRepository, MilestoneRepository and DeliveryRepository replay what
+// was stored before returning. Each may be opened only once.
+func (p *Persistence) UserRepository() (user.UserRepository, error) {
+	r := NewUserRepository().(*inMemoryUserRepository)
+	j, err := p.open(r, "users", r.apply)
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+func (p *Persistence) MilestoneRepository() (milestone.MilestoneRepository, error) {
+	r := NewMilestoneRepository().(*inMemoryMilestoneRepository)
+	j, err := p.open(r, "milestones", r.apply)
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+func (p *Persistence) DeliveryRepository() (digest.DeliveryRepository, error) {
+	r := NewDeliveryRepository().(*inMemoryDeliveryRepository)
+	j, err := p.open(r, "deliveries", r.apply)
+	if err != nil {
+		return nil, err
+	}
+	r.journal = j
+	return r, nil
+}
+
+func (p *Persistence) open(r persisted, name string, apply func(change) error) (*journal, error) {
+	j, err := openJournal(p.cfg.Dir, name, p.cfg.Fsync, apply)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	p.repos = append(p.repos, r)
+	p.mu.Unlock()
+	return j, nil
+}
+
+// Start snapshots the repositories, and syncs their logs if the policy is
+// FsyncInterval, in the background until Close is called.
+func (p *Persistence) Start(ctx context.Context) {
+	ctx, p.cancel = context.WithCancel(ctx)
+	p.done = make(chan struct{})
+
+	go func() {
+		defer close(p.done)
+		p.run(ctx)
+	}()
+}
+
+func (p *Persistence) run(ctx context.Context) {
+	snapshots := time.NewTicker(p.cfg.SnapshotInterval)
+	defer snapshots.Stop()
+
+	var syncs <-chan time.Time
+	if p.cfg.Fsync == FsyncInterval {
+		ticker := time.NewTicker(p.cfg.FsyncInterval)
+		defer ticker.Stop()
+		syncs = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-snapshots.C:
+			if err := p.snapshot(); err != nil {
+				slog.ErrorContext(ctx, "snapshotting memory storage failed", "error", err)
+			}
+		case <-syncs:
+			for _, r := range p.persisted() {
+				if err := r.log().sync(); err != nil {
+					slog.ErrorContext(ctx, "syncing memory storage failed", "error", err)
+				}
+			}
+		}
+	}
+}
+
+func (p *Persistence) persisted() []persisted {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.repos
+}
+
+func (p *Persistence) snapshot() error {
+	var errs []error
+	for _, r := range p.persisted() {
+		errs = append(errs, r.snapshot())
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background work, takes a final snapshot so that the
+// next start has no log to replay, and closes the logs. The repositories
+// must no longer be used.
+func (p *Persistence) Close(ctx context.Context) error {
+	if p.cancel != nil {
+		p.cancel()
+		select {
+		case <-p.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	errs := []error{p.snapshot()}
+	for _, r := range p.persisted() {
+		errs = append(errs, r.log().close())
+	}
+	return errors.Join(errs...)
+}
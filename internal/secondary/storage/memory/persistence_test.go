@@ -0,0 +1,272 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type persistedRepos struct {
+	p          *Persistence
+	users      user.UserRepository
+	milestones milestone.MilestoneRepository
+	deliveries digest.DeliveryRepository
+}
+
+func openRepos(t *testing.T, dir, fsync string) (persistedRepos, error) {
+	t.Helper()
+	cfg := DefaultPersistenceConfig
+	cfg.Dir, cfg.Fsync = dir, fsync
+	p, err := OpenPersistence(cfg)
+	require.NoError(t, err)
+
+	r := persistedRepos{p: p}
+	if r.users, err = p.UserRepository(); err != nil {
+		return r, err
+	}
+	if r.milestones, err = p.MilestoneRepository(); err != nil {
+		return r, err
+	}
+	r.deliveries, err = p.DeliveryRepository()
+	return r, err
+}
+
+func mustOpenRepos(t *testing.T, dir, fsync string) persistedRepos {
+	t.Helper()
+	r, err := openRepos(t, dir, fsync)
+	require.NoError(t, err)
+	return r
+}
+
+// crash closes the logs as a killed process would leave them, without the
+// final snapshot.
+func (r persistedRepos) crash(t *testing.T) {
+	t.Helper()
+	for _, repo := range r.p.persisted() {
+		require.NoError(t, repo.log().close())
+	}
+}
+
+func logFiles(t *testing.T, dir, name string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, name+".*.log"))
+	require.NoError(t, err)
+	return paths
+}
+
+func TestPersistence_Reopen(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	userID := uuid.New()
+	importID := uuid.New()
+	date := time.Date(2019, time.March, 1, 0, 0, 0, 0, time.UTC)
+	delivery := digest.Delivery{UserID: userID, Week: 1706, SentAt: time.Date(2024, time.June, 3, 9, 0, 0, 0, time.UTC)}
+
+	r := mustOpenRepos(t, dir, FsyncInterval)
+	u := restoreUser(t, userID, "john@example.com", "johndoe")
+	require.NoError(t, r.users.Create(ctx, u))
+	require.NoError(t, u.ChangeTimeZone("Europe/London"))
+	require.NoError(t, r.users.Save(ctx, u))
+
+	kept := milestone.Restore(milestone.RestoreParams{ID: uuid.New(), UserID: userID, Title: "Moved abroad", Date: date, CreatedAt: date})
+	imported := milestone.Restore(milestone.RestoreParams{ID: uuid.New(), UserID: userID, Title: "Imported", Date: date, ImportID: importID, CreatedAt: date})
+	require.NoError(t, r.milestones.SaveAll(ctx, []*milestone.Milestone{kept, imported}))
+	require.NoError(t, r.milestones.DeleteByImportID(ctx, userID, importID))
+
+	require.NoError(t, r.deliveries.Record(ctx, delivery))
+	require.NoError(t, r.deliveries.Record(ctx, digest.Delivery{UserID: userID, Week: 1707}))
+	require.NoError(t, r.deliveries.Delete(ctx, userID, 1707))
+	require.NoError(t, r.p.Close(ctx))
+
+	r = mustOpenRepos(t, dir, FsyncInterval)
+	defer r.p.Close(ctx)
+
+	found, err := r.users.FindByEmail(ctx, "John@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, userID, found.ID())
+	assert.Equal(t, "johndoe", found.Username())
+	assert.Equal(t, "hashed-password", found.PasswordHash())
+	assert.Equal(t, "Europe/London", found.Location().String())
+	assert.Equal(t, u.Version(), found.Version())
+	assert.True(t, u.CreatedAt().Equal(found.CreatedAt()))
+
+	milestones, err := r.milestones.FindByUserID(ctx, userID)
+	require.NoError(t, err)
+	require.Len(t, milestones, 1)
+	assert.Equal(t, kept.ID(), milestones[0].ID())
+	assert.Equal(t, "Moved abroad", milestones[0].Title())
+	assert.True(t, date.Equal(milestones[0].Date()))
+
+	assert.ErrorIs(t, r.deliveries.Record(ctx, delivery), digest.ErrAlreadyDelivered)
+	assert.NoError(t, r.deliveries.Record(ctx, digest.Delivery{UserID: userID, Week: 1707}))
+
+	assert.Len(t, logFiles(t, dir, "users"), 1, "the final snapshot replaces the logs")
+}
+
+func TestPersistence_ReplayAfterCrash(t *testing.T) {
+	for _, fsync := range []string{FsyncAlways, FsyncInterval, FsyncNever} {
+		t.Run(fsync, func(t *testing.T) {
+			ctx := context.Background()
+			dir := t.TempDir()
+
+			r := mustOpenRepos(t, dir, fsync)
+			u := restoreUser(t, uuid.New(), "john@example.com", "johndoe")
+			require.NoError(t, r.users.Create(ctx, u))
+			require.NoError(t, r.users.Save(ctx, u))
+			r.crash(t)
+
+			r = mustOpenRepos(t, dir, fsync)
+			defer r.p.Close(ctx)
+
+			found, err := r.users.FindByID(ctx, u.ID())
+			require.NoError(t, err)
+			assert.Equal(t, u.Version(), found.Version())
+
+			err = r.users.Save(ctx, u)
+			assert.NoError(t, err, "the replayed version accepts the next save")
+		})
+	}
+}
+
+func TestPersistence_SnapshotCompactsLog(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r := mustOpenRepos(t, dir, FsyncInterval)
+	first := restoreUser(t, uuid.New(), "first@example.com", "first")
+	require.NoError(t, r.users.Create(ctx, first))
+	require.NoError(t, r.p.snapshot())
+
+	second := restoreUser(t, uuid.New(), "second@example.com", "second")
+	require.NoError(t, r.users.Create(ctx, second))
+	require.NoError(t, r.users.Save(ctx, first))
+	assert.Len(t, logFiles(t, dir, "users"), 1, "logs covered by the snapshot are removed")
+	r.crash(t)
+
+	r = mustOpenRepos(t, dir, FsyncInterval)
+	defer r.p.Close(ctx)
+
+	users, err := r.users.FindAll(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	found, err := r.users.FindByID(ctx, first.ID())
+	require.NoError(t, err)
+	assert.Equal(t, first.Version(), found.Version())
+}
+
+func TestPersistence_TornWrite(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r := mustOpenRepos(t, dir, FsyncAlways)
+	u := restoreUser(t, uuid.New(), "john@example.com", "johndoe")
+	require.NoError(t, r.users.Create(ctx, u))
+	r.crash(t)
+
+	logs := logFiles(t, dir, "users")
+	require.Len(t, logs, 1)
+	intact, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	torn := frame([]byte(`{"seq":2,"changes":[]}`))
+	require.NoError(t, os.WriteFile(logs[0], append(intact, torn[:len(torn)-3]...), 0o600))
+
+	r = mustOpenRepos(t, dir, FsyncAlways)
+	_, err = r.users.FindByID(ctx, u.ID())
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(logs[0])
+	require.NoError(t, err)
+	assert.Equal(t, intact, data, "the partial record is dropped")
+
+	other := restoreUser(t, uuid.New(), "jane@example.com", "janedoe")
+	require.NoError(t, r.users.Create(ctx, other))
+	r.crash(t)
+
+	r = mustOpenRepos(t, dir, FsyncAlways)
+	defer r.p.Close(ctx)
+	users, err := r.users.FindAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+}
+
+func TestPersistence_CorruptLength(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	r := mustOpenRepos(t, dir, FsyncAlways)
+	require.NoError(t, r.users.Create(ctx, restoreUser(t, uuid.New(), "john@example.com", "johndoe")))
+	require.NoError(t, r.users.Create(ctx, restoreUser(t, uuid.New(), "jane@example.com", "janedoe")))
+	r.crash(t)
+
+	path := logFiles(t, dir, "users")[0]
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	// Make the first record's length run past the end of the log.
+	data[1] ^= 0x01
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = openRepos(t, dir, FsyncAlways)
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	after, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, data, after, "the records after the damaged length are kept")
+}
+
+func TestPersistence_Corruption(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		prepare func(t *testing.T, r persistedRepos)
+		file    func(t *testing.T, dir string) string
+	}{
+		{
+			name: "change log",
+			prepare: func(t *testing.T, r persistedRepos) {
+				require.NoError(t, r.users.Create(ctx, restoreUser(t, uuid.New(), "john@example.com", "johndoe")))
+				require.NoError(t, r.users.Create(ctx, restoreUser(t, uuid.New(), "jane@example.com", "janedoe")))
+				r.crash(t)
+			},
+			file: func(t *testing.T, dir string) string {
+				return logFiles(t, dir, "users")[0]
+			},
+		},
+		{
+			name: "snapshot",
+			prepare: func(t *testing.T, r persistedRepos) {
+				require.NoError(t, r.users.Create(ctx, restoreUser(t, uuid.New(), "john@example.com", "johndoe")))
+				require.NoError(t, r.p.Close(ctx))
+			},
+			file: func(t *testing.T, dir string) string {
+				return filepath.Join(dir, "users.snapshot")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.prepare(t, mustOpenRepos(t, dir, FsyncAlways))
+
+			path := tt.file(t, dir)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			// Flip a byte inside the first record's payload.
+			data[12] ^= 0xff
+			require.NoError(t, os.WriteFile(path, data, 0o600))
+
+			_, err = openRepos(t, dir, FsyncAlways)
+			assert.ErrorIs(t, err, ErrCorrupt)
+		})
+	}
+}
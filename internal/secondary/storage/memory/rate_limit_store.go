@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+)
+
+// sweepEvery is how many takes pass between sweeps for buckets that have
+// refilled and can be forgotten.
+const sweepEvery = 1024
+
+type bucketEntry struct {
+	bucket ratelimit.Bucket
+	limit  ratelimit.Limit
+}
+
+type inMemoryRateLimitStore struct {
+	buckets map[string]*bucketEntry
+	takes   int
+	mu      sync.Mutex
+}
+
+func NewRateLimitStore() ratelimit.Store {
+	return &inMemoryRateLimitStore{
+		buckets: make(map[string]*bucketEntry),
+		mu:      sync.Mutex{},
+	}
+}
+
+func (s *inMemoryRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.takes++
+	if s.takes%sweepEvery == 0 {
+		for k, e := range s.buckets {
+			if e.bucket.Full(e.limit, now) {
+				delete(s.buckets, k)
+			}
+		}
+	}
+
+	e, ok := s.buckets[key]
+	if !ok {
+		e = &bucketEntry{bucket: ratelimit.NewBucket(limit, now)}
+		s.buckets[key] = e
+	}
+	e.limit = limit
+	return e.bucket.Take(limit, now), nil
+}
+
+type inMemoryFailureStore struct {
+	failures map[string]ratelimit.Failures
+	mu       sync.Mutex
+}
+
+func NewFailureStore() ratelimit.FailureStore {
+	return &inMemoryFailureStore{
+		failures: make(map[string]ratelimit.Failures),
+		mu:       sync.Mutex{},
+	}
+}
+
+func (s *inMemoryFailureStore) Failures(ctx context.Context, key string) (ratelimit.Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.failures[key], nil
+}
+
+func (s *inMemoryFailureStore) AddFailure(ctx context.Context, key string, now, since time.Time) (ratelimit.Failures, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f := s.failures[key]
+	if f.Last.Before(since) {
+		f.Count = 0
+	}
+	f.Count++
+	f.Last = now
+	s.failures[key] = f
+	return f, nil
+}
+
+func (s *inMemoryFailureStore) ResetFailures(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.failures, key)
+	return nil
+}
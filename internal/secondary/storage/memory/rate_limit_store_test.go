@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	limit := ratelimit.Limit{Requests: 10, Per: time.Minute}
+	now := time.Now()
+
+	t.Run("concurrent takes never overspend", func(t *testing.T) {
+		store := NewRateLimitStore()
+
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for range 100 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d, err := store.Take(ctx, "key", limit, now)
+				assert.NoError(t, err)
+				if d.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(10), allowed.Load())
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		store := NewRateLimitStore()
+		one := ratelimit.Limit{Requests: 1, Per: time.Minute}
+
+		d, err := store.Take(ctx, "a", one, now)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		d, err = store.Take(ctx, "a", one, now)
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		d, err = store.Take(ctx, "b", one, now)
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+	})
+}
+
+func TestFailureStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewFailureStore()
+
+	f, err := store.Failures(ctx, "key")
+	require.NoError(t, err)
+	assert.Zero(t, f)
+
+	store.AddFailure(ctx, "key", now, now.Add(-time.Hour))
+	f, err = store.AddFailure(ctx, "key", now.Add(time.Minute), now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Failures{Count: 2, Last: now.Add(time.Minute)}, f)
+
+	f, err = store.AddFailure(ctx, "key", now.Add(3*time.Hour), now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, f.Count, "old failures are forgotten")
+
+	require.NoError(t, store.ResetFailures(ctx, "key"))
+	f, err = store.Failures(ctx, "key")
+	require.NoError(t, err)
+	assert.Zero(t, f)
+}
@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/transaction"
+)
+
+// txRepository is a memory repository that can take part in transactions.
+type txRepository interface {
+	// order is unique to the repository, so that commits lock
+	// repositories in the same order and cannot deadlock.
+	order() uint64
+	lock()
+	unlock()
+	rlock()
+	runlock()
+	// fork returns an empty overlay on the repository: it keeps the
+	// changes made to it, reads anything else through to the repository,
+	// and buffers changes to its journal instead of writing them. Locking
+	// the fork read-locks the repository too.
+	fork() txRepository
+	// replace applies the changes kept in a fork, logging the changes it
+	// buffered. The caller holds the write lock.
+	replace(fork txRepository) error
+}
+
+var repositories atomic.Uint64
+
+// txBase is embedded in repositories that take part in transactions.
+type txBase struct {
+	id uint64
+}
+
+func newTxBase() txBase {
+	return txBase{id: repositories.Add(1)}
+}
+
+func (b txBase) order() uint64 { return b.id }
+
+type txKey struct{}
+
+// memoryTx overlays its writes: a repository is forked the first time the
+// transaction writes to it, and the transaction reads and writes the fork
+// from then on, so it costs only the keys it changes. Writes are kept to
+// replay at commit onto a fresh fork of whatever was committed meanwhile,
+// so that they are checked against it, before the forks' changes are
+// applied to the repositories.
+type memoryTx struct {
+	mu        sync.Mutex
+	forks     map[txRepository]*txFork
+	rollbacks []func()
+}
+
+type txFork struct {
+	repo   txRepository
+	writes []func(txRepository) error
+}
+
+type memoryTransactionManager struct{}
+
+// NewTransactionManager runs use cases in transactions across memory
+// repositories. Transactions see what they wrote over what others have
+// committed; at commit every write must still succeed against the latest
+// state or none is kept. Persisted repositories log a transaction's
+// changes as one record each, so a crash mid-commit may keep them in some
+// repositories but not others.
+func NewTransactionManager() transaction.Manager {
+	return memoryTransactionManager{}
+}
+
+func (memoryTransactionManager) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx := &memoryTx{forks: make(map[txRepository]*txFork)}
+	err := fn(context.WithValue(ctx, txKey{}, tx))
+	if err == nil {
+		err = tx.commit()
+	}
+	if err != nil {
+		tx.rollback()
+	}
+	return err
+}
+
+func txFrom(ctx context.Context) *memoryTx {
+	tx, _ := ctx.Value(txKey{}).(*memoryTx)
+	return tx
+}
+
+// setVersion sets the version of an aggregate written under ctx, and puts
+// the old one back if the transaction ctx carries is rolled back.
+func setVersion(ctx context.Context, a aggregate.Versioned, v aggregate.Version) {
+	if tx := txFrom(ctx); tx != nil {
+		old := a.Version()
+		tx.mu.Lock()
+		tx.rollbacks = append(tx.rollbacks, func() { a.SetVersion(old) })
+		tx.mu.Unlock()
+	}
+	a.SetVersion(v)
+}
+
+func (tx *memoryTx) rollback() {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	for _, undo := range slices.Backward(tx.rollbacks) {
+		undo()
+	}
+}
+
+// read returns the copy of r that ctx sees, read-locked.
+func read[R txRepository](ctx context.Context, r R) (R, func()) {
+	if tx := txFrom(ctx); tx != nil {
+		tx.mu.Lock()
+		if f, ok := tx.forks[r]; ok {
+			r = f.repo.(R)
+		}
+		tx.mu.Unlock()
+	}
+	r.rlock()
+	return r, r.runlock
+}
+
+// write runs op on the copy of r that ctx sees, write-locked.
+func write[R txRepository](ctx context.Context, r R, op func(R) error) error {
+	tx := txFrom(ctx)
+	if tx == nil {
+		r.lock()
+		defer r.unlock()
+		return op(r)
+	}
+
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	f, ok := tx.forks[r]
+	if !ok {
+		f = &txFork{repo: r.fork()}
+	}
+	w := func(repo txRepository) error { return op(repo.(R)) }
+	f.repo.lock()
+	err := w(f.repo)
+	f.repo.unlock()
+	if err != nil {
+		return err
+	}
+	tx.forks[r] = f
+	f.writes = append(f.writes, w)
+	return nil
+}
+
+func (tx *memoryTx) commit() error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+
+	repos := make([]txRepository, 0, len(tx.forks))
+	for r := range tx.forks {
+		repos = append(repos, r)
+	}
+	slices.SortFunc(repos, func(a, b txRepository) int {
+		return cmp.Compare(a.order(), b.order())
+	})
+	for _, r := range repos {
+		r.lock()
+		defer r.unlock()
+	}
+
+	forks := make([]txRepository, len(repos))
+	for i, r := range repos {
+		forks[i] = r.fork()
+		for _, w := range tx.forks[r].writes {
+			if err := w(forks[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	var errs []error
+	for i, r := range repos {
+		errs = append(errs, r.replace(forks[i]))
+	}
+	return errors.Join(errs...)
+}
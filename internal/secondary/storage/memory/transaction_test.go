@@ -0,0 +1,223 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/mgwinsor/weekbyweek/internal/domain/milestone"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionManager(t *testing.T) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+
+	t.Run("commits writes to every repository", func(t *testing.T) {
+		users, deliveries := NewUserRepository(), NewDeliveryRepository()
+		u := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		delivery := digest.Delivery{UserID: u.ID(), Week: 1}
+
+		err := NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			if err := users.Create(ctx, u); err != nil {
+				return err
+			}
+			found, err := users.FindByEmail(ctx, "ada@example.com")
+			require.NoError(t, err, "the transaction sees its own writes")
+			assert.Equal(t, u.ID(), found.ID())
+
+			_, err = users.FindByID(context.Background(), u.ID())
+			assert.ErrorIs(t, err, user.ErrUserNotFound, "others do not until it commits")
+			return deliveries.Record(ctx, delivery)
+		})
+		require.NoError(t, err)
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.NoError(t, err)
+		assert.ErrorIs(t, deliveries.Record(ctx, delivery), digest.ErrAlreadyDelivered)
+	})
+
+	t.Run("discards writes when the use case fails", func(t *testing.T) {
+		users, deliveries := NewUserRepository(), NewDeliveryRepository()
+		u := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		delivery := digest.Delivery{UserID: u.ID(), Week: 1}
+
+		err := NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Create(ctx, u))
+			require.NoError(t, deliveries.Record(ctx, delivery))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		assert.Zero(t, u.Version(), "the user is not left at a version that was never stored")
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		assert.NoError(t, deliveries.Record(ctx, delivery))
+	})
+
+	t.Run("nested runs join the transaction", func(t *testing.T) {
+		users := NewUserRepository()
+		transactions := NewTransactionManager()
+		u := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+
+		err := transactions.Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, transactions.Run(ctx, func(ctx context.Context) error {
+				return users.Create(ctx, u)
+			}))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound, "the inner run did not commit on its own")
+	})
+
+	t.Run("writes are checked again at commit", func(t *testing.T) {
+		users := NewUserRepository()
+		mine := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		theirs := restoreUser(t, uuid.New(), "ada@example.com", "lovelace")
+		other := restoreUser(t, uuid.New(), "grace@example.com", "grace")
+
+		err := NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Create(ctx, other))
+			require.NoError(t, users.Create(ctx, mine))
+			return users.Create(context.Background(), theirs)
+		})
+		assert.ErrorIs(t, err, user.ErrEmailTaken)
+		assert.Zero(t, mine.Version(), "versions are put back when the commit fails")
+
+		all, err := users.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, all, 1, "none of the transaction's writes are kept")
+		assert.Equal(t, theirs.ID(), all[0].ID())
+	})
+
+	t.Run("a save committed meanwhile conflicts", func(t *testing.T) {
+		users := NewUserRepository()
+		u := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		require.NoError(t, users.Create(ctx, u))
+		concurrent, err := users.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+
+		err = NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, u.ChangeTimeZone("Asia/Tokyo"))
+			require.NoError(t, users.Save(ctx, u))
+			return users.Save(context.Background(), concurrent)
+		})
+		assert.ErrorIs(t, err, aggregate.ErrConflict)
+		assert.Equal(t, aggregate.Version(1), u.Version(), "the failed save does not advance u")
+
+		found, err := users.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "UTC", found.Location().String())
+		assert.Equal(t, aggregate.Version(2), found.Version())
+	})
+
+	t.Run("an email given up is free to take in the same transaction", func(t *testing.T) {
+		users := NewUserRepository()
+		ada := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		require.NoError(t, users.Create(ctx, ada))
+		newcomer := restoreUser(t, uuid.New(), "ada@example.com", "newcomer")
+
+		renamed := restoreUser(t, ada.ID(), "lovelace@example.com", "ada")
+		renamed.SetVersion(ada.Version())
+
+		require.NoError(t, NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Save(ctx, renamed))
+			return users.Create(ctx, newcomer)
+		}))
+
+		found, err := users.FindByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, newcomer.ID(), found.ID())
+		found, err = users.FindByEmail(ctx, "lovelace@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, ada.ID(), found.ID())
+	})
+
+	t.Run("reads look through to what is committed", func(t *testing.T) {
+		users, milestones := NewUserRepository(), NewMilestoneRepository()
+		ada := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		importID := uuid.New()
+		kept := milestone.Restore(milestone.RestoreParams{ID: uuid.New(), UserID: ada.ID(), Title: "kept", Date: time.Now()})
+		imported := milestone.Restore(milestone.RestoreParams{ID: uuid.New(), UserID: ada.ID(), Title: "imported", Date: time.Now(), ImportID: importID})
+		require.NoError(t, milestones.SaveAll(ctx, []*milestone.Milestone{kept, imported}))
+
+		require.NoError(t, NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, milestones.DeleteByImportID(ctx, ada.ID(), importID))
+			found, err := milestones.FindByUserID(ctx, ada.ID())
+			require.NoError(t, err)
+			require.Len(t, found, 1, "deleted milestones are hidden")
+			assert.Equal(t, kept.ID(), found[0].ID())
+
+			require.NoError(t, users.Create(context.Background(), ada))
+			byName, err := users.FindByUsername(ctx, "ada")
+			require.NoError(t, err)
+			assert.Len(t, byName, 1, "a user committed after the first write is seen")
+			return nil
+		}))
+
+		found, err := milestones.FindByUserID(ctx, ada.ID())
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("persisted repositories log the commit", func(t *testing.T) {
+		dir := t.TempDir()
+		r := mustOpenRepos(t, dir, FsyncAlways)
+		u := restoreUser(t, uuid.New(), "ada@example.com", "ada")
+		delivery := digest.Delivery{UserID: u.ID(), Week: 1, SentAt: time.Now().UTC()}
+
+		require.NoError(t, NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, r.users.Create(ctx, u))
+			require.NoError(t, r.users.Save(ctx, u))
+			return r.deliveries.Record(ctx, delivery)
+		}))
+		err := NewTransactionManager().Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, r.deliveries.Delete(ctx, u.ID(), 1))
+			return errAbort
+		})
+		require.ErrorIs(t, err, errAbort)
+		r.crash(t)
+
+		r = mustOpenRepos(t, dir, FsyncAlways)
+		defer r.p.Close(ctx)
+		found, err := r.users.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, aggregate.Version(2), found.Version())
+		assert.ErrorIs(t, r.deliveries.Record(ctx, delivery), digest.ErrAlreadyDelivered)
+	})
+}
+
+// BenchmarkTransactionManager_CreateUser shows a sign-up in a transaction
+// taking the same time however many users are stored.
+func BenchmarkTransactionManager_CreateUser(b *testing.B) {
+	ctx := context.Background()
+	transactions := NewTransactionManager()
+	for _, size := range []int{1_000, 1_000_000} {
+		users := NewUserRepository()
+		for i := range size {
+			u := restoreUser(b, uuid.New(), fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("user%d", i))
+			require.NoError(b, users.Create(ctx, u))
+		}
+
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				u := restoreUser(b, uuid.New(), fmt.Sprintf("new%d@example.com", i), fmt.Sprintf("new%d", i))
+				err := transactions.Run(ctx, func(ctx context.Context) error {
+					return users.Create(ctx, u)
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
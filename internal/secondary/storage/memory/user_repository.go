@@ -2,50 +2,392 @@ package memory
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
 	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 )
 
+// inMemoryUserRepository indexes users by normalised email, which is
+// unique, and by normalised username, which is not, so that every lookup
+// but FindAll takes constant time.
 type inMemoryUserRepository struct {
-	users map[uuid.UUID]*user.User
-	mu    sync.RWMutex
+	txBase
+	users      map[uuid.UUID]*user.User
+	byEmail    map[string]uuid.UUID
+	byUsername map[string]map[uuid.UUID]struct{}
+	mu         sync.RWMutex
+
+	// base, in a fork, is the repository the maps above overlay. Its
+	// indexes may name users the fork has changed since, so lookups check
+	// what they find.
+	base *inMemoryUserRepository
+
+	// journal, if set, logs every change before it is made.
+	journal *journal
 }
 
 func NewUserRepository() user.UserRepository {
 	return &inMemoryUserRepository{
-		users: make(map[uuid.UUID]*user.User),
-		mu:    sync.RWMutex{},
+		txBase:     newTxBase(),
+		users:      make(map[uuid.UUID]*user.User),
+		byEmail:    make(map[string]uuid.UUID),
+		byUsername: make(map[string]map[uuid.UUID]struct{}),
+		mu:         sync.RWMutex{},
 	}
 }
 
-func (r *inMemoryUserRepository) Save(ctx context.Context, user *user.User) error {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.users[user.ID()] = user
+// Create and Save store a copy, so that later changes to user need saving
+// again, and finders hand out copies for the same reason.
+func (r *inMemoryUserRepository) Create(ctx context.Context, u *user.User) error {
+	created := u.Clone()
+	created.SetVersion(1)
+	if err := write(ctx, r, func(r *inMemoryUserRepository) error { return r.create(created) }); err != nil {
+		return err
+	}
+
+	setVersion(ctx, u, 1)
+	slog.DebugContext(ctx, "user created", "user_id", u.ID())
+	return nil
+}
+
+func (r *inMemoryUserRepository) create(u *user.User) error {
+	if _, ok := r.user(u.ID()); ok {
+		return fmt.Errorf("user %s already exists: %w", u.ID(), aggregate.ErrConflict)
+	}
+	if _, ok := r.idByEmail(user.NormaliseEmail(u.Email())); ok {
+		return user.ErrEmailTaken
+	}
+
+	if err := r.journal.put(u.ID().String(), newUserRecord(u)); err != nil {
+		return err
+	}
+	r.store(u)
+	return nil
+}
+
+func (r *inMemoryUserRepository) Save(ctx context.Context, u *user.User) error {
+	loaded := u.Clone()
+	var version aggregate.Version
+	err := write(ctx, r, func(r *inMemoryUserRepository) error {
+		saved := loaded.Clone()
+		if err := r.save(saved); err != nil {
+			return err
+		}
+		version = saved.Version()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	setVersion(ctx, u, version)
+	slog.DebugContext(ctx, "user saved", "user_id", u.ID())
+	return nil
+}
+
+func (r *inMemoryUserRepository) save(u *user.User) error {
+	stored, ok := r.user(u.ID())
+	if !ok {
+		return user.ErrUserNotFound
+	}
+	if stored.Version() != u.Version() {
+		return fmt.Errorf("user %s at version %d, saving version %d: %w", u.ID(), stored.Version(), u.Version(), aggregate.ErrConflict)
+	}
+	if id, ok := r.idByEmail(user.NormaliseEmail(u.Email())); ok && id != u.ID() {
+		return user.ErrEmailTaken
+	}
+
+	u.SetVersion(stored.Version() + 1)
+	if err := r.journal.put(u.ID().String(), newUserRecord(u)); err != nil {
+		return err
+	}
+	r.unindex(stored)
+	r.store(u)
 	return nil
 }
 
 func (r *inMemoryUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r, unlock := read(ctx, r)
+	defer unlock()
 
-	u, exists := r.users[id]
+	u, exists := r.user(id)
 	if !exists {
 		return nil, user.ErrUserNotFound
 	}
-	return u, nil
+	return u.Clone(), nil
 }
 
 func (r *inMemoryUserRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
-	r.mu.RLock()
-	defer r.mu.RUnlock()
+	r, unlock := read(ctx, r)
+	defer unlock()
+
+	id, ok := r.idByEmail(user.NormaliseEmail(email))
+	if !ok {
+		return nil, user.ErrUserNotFound
+	}
+	u, _ := r.user(id)
+	return u.Clone(), nil
+}
+
+func (r *inMemoryUserRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	r, unlock := read(ctx, r)
+	defer unlock()
+
+	username = user.NormaliseUsername(username)
+	ids := r.byUsername[username]
+	if r.base != nil {
+		ids = maps.Clone(ids)
+		if ids == nil {
+			ids = make(map[uuid.UUID]struct{})
+		}
+		maps.Copy(ids, r.base.byUsername[username])
+	}
+
+	users := make([]*user.User, 0, len(ids))
+	for id := range ids {
+		if u, _ := r.user(id); user.NormaliseUsername(u.Username()) == username {
+			users = append(users, u.Clone())
+		}
+	}
+	sortByCreation(users)
+	return users, nil
+}
+
+func (r *inMemoryUserRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	r, unlock := read(ctx, r)
+	defer unlock()
 
+	users := make([]*user.User, 0, len(r.users))
 	for _, u := range r.users {
-		if u.Email() == email {
-			return u, nil
+		users = append(users, u.Clone())
+	}
+	if r.base != nil {
+		for id, u := range r.base.users {
+			if _, ok := r.users[id]; !ok {
+				users = append(users, u.Clone())
+			}
 		}
 	}
-	return nil, user.ErrUserNotFound
+	sortByCreation(users)
+	return users, nil
+}
+
+// user returns the user stored under id, looking through a fork to its
+// base.
+func (r *inMemoryUserRepository) user(id uuid.UUID) (*user.User, bool) {
+	u, ok := r.users[id]
+	if !ok && r.base != nil {
+		u, ok = r.base.users[id]
+	}
+	return u, ok
+}
+
+// idByEmail returns the ID of the user with the normalised email.
+func (r *inMemoryUserRepository) idByEmail(email string) (uuid.UUID, bool) {
+	id, ok := r.byEmail[email]
+	if ok || r.base == nil {
+		return id, ok
+	}
+	if id, ok = r.base.byEmail[email]; !ok {
+		return uuid.Nil, false
+	}
+	if u, _ := r.user(id); user.NormaliseEmail(u.Email()) != email {
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (r *inMemoryUserRepository) lock() {
+	if r.base != nil {
+		r.base.rlock()
+	}
+	r.mu.Lock()
+}
+
+func (r *inMemoryUserRepository) unlock() {
+	r.mu.Unlock()
+	if r.base != nil {
+		r.base.runlock()
+	}
+}
+
+func (r *inMemoryUserRepository) rlock() {
+	if r.base != nil {
+		r.base.rlock()
+	}
+	r.mu.RLock()
+}
+
+func (r *inMemoryUserRepository) runlock() {
+	r.mu.RUnlock()
+	if r.base != nil {
+		r.base.runlock()
+	}
+}
+
+func (r *inMemoryUserRepository) fork() txRepository {
+	return &inMemoryUserRepository{
+		txBase:     r.txBase,
+		users:      make(map[uuid.UUID]*user.User),
+		byEmail:    make(map[string]uuid.UUID),
+		byUsername: make(map[string]map[uuid.UUID]struct{}),
+		base:       r,
+		journal:    r.journal.buffer(),
+	}
+}
+
+// replace unindexes every user the fork changed before indexing any of
+// them, so that a user taking over an email another gave up in the same
+// transaction keeps it.
+func (r *inMemoryUserRepository) replace(fork txRepository) error {
+	f := fork.(*inMemoryUserRepository)
+	if err := r.journal.commit(f.journal); err != nil {
+		return err
+	}
+	for id := range f.users {
+		if stored, ok := r.users[id]; ok {
+			r.unindex(stored)
+		}
+	}
+	for _, u := range f.users {
+		r.store(u)
+	}
+	return nil
+}
+
+// store adds u, which must not be shared with callers, and indexes it.
+func (r *inMemoryUserRepository) store(u *user.User) {
+	r.users[u.ID()] = u
+	r.byEmail[user.NormaliseEmail(u.Email())] = u.ID()
+
+	username := user.NormaliseUsername(u.Username())
+	ids, ok := r.byUsername[username]
+	if !ok {
+		ids = make(map[uuid.UUID]struct{})
+		r.byUsername[username] = ids
+	}
+	ids[u.ID()] = struct{}{}
+}
+
+// unindex removes u from the indexes, leaving it to the caller to replace
+// it in users.
+func (r *inMemoryUserRepository) unindex(u *user.User) {
+	delete(r.byEmail, user.NormaliseEmail(u.Email()))
+
+	username := user.NormaliseUsername(u.Username())
+	delete(r.byUsername[username], u.ID())
+	if len(r.byUsername[username]) == 0 {
+		delete(r.byUsername, username)
+	}
+}
+
+func sortByCreation(users []*user.User) {
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].CreatedAt().Before(users[j].CreatedAt())
+	})
+}
+
+// userRecord is how a persisted repository writes a user to disk.
+type userRecord struct {
+	ID            uuid.UUID         `json:"id"`
+	Email         string            `json:"email"`
+	Username      string            `json:"username"`
+	PasswordHash  string            `json:"password_hash"`
+	DateOfBirth   time.Time         `json:"date_of_birth"`
+	AnchorMode    string            `json:"anchor_mode"`
+	FirstWeekday  int               `json:"first_weekday"`
+	TimeZone      string            `json:"time_zone"`
+	CalendarToken string            `json:"calendar_token"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	Version       aggregate.Version `json:"version"`
+}
+
+func newUserRecord(u *user.User) userRecord {
+	return userRecord{
+		ID:            u.ID(),
+		Email:         u.Email(),
+		Username:      u.Username(),
+		PasswordHash:  u.PasswordHash(),
+		DateOfBirth:   u.DateOfBirth(),
+		AnchorMode:    string(u.WeekAnchor().Mode),
+		FirstWeekday:  int(u.WeekAnchor().FirstWeekday),
+		TimeZone:      u.Location().String(),
+		CalendarToken: u.CalendarToken(),
+		CreatedAt:     u.CreatedAt(),
+		UpdatedAt:     u.UpdatedAt(),
+		Version:       u.Version(),
+	}
+}
+
+// apply replays a logged change.
+func (r *inMemoryUserRepository) apply(c change) error {
+	id, err := uuid.Parse(c.Key)
+	if err != nil {
+		return fmt.Errorf("user key %q: %w", c.Key, ErrCorrupt)
+	}
+	if stored, ok := r.users[id]; ok {
+		r.unindex(stored)
+		delete(r.users, id)
+	}
+
+	switch c.Op {
+	case opPut:
+		var rec userRecord
+		if err := json.Unmarshal(c.Value, &rec); err != nil {
+			return fmt.Errorf("user %s: %w: %w", id, ErrCorrupt, err)
+		}
+		u, err := user.Restore(user.RestoreParams{
+			ID:            rec.ID,
+			Email:         rec.Email,
+			Username:      rec.Username,
+			PasswordHash:  rec.PasswordHash,
+			DateOfBirth:   rec.DateOfBirth,
+			WeekAnchor:    week.Anchor{Mode: week.Mode(rec.AnchorMode), FirstWeekday: time.Weekday(rec.FirstWeekday)},
+			TimeZone:      rec.TimeZone,
+			CalendarToken: rec.CalendarToken,
+			CreatedAt:     rec.CreatedAt,
+			UpdatedAt:     rec.UpdatedAt,
+			Version:       rec.Version,
+		})
+		if err != nil {
+			return fmt.Errorf("user %s: %w", id, err)
+		}
+		r.store(u)
+	case opDelete:
+	default:
+		return fmt.Errorf("user %s: unknown operation %q: %w", id, c.Op, ErrCorrupt)
+	}
+	return nil
+}
+
+func (r *inMemoryUserRepository) snapshot() error {
+	r.mu.RLock()
+	seq, stale, err := r.journal.rotate()
+	if err != nil || !stale {
+		r.mu.RUnlock()
+		return err
+	}
+	users := make([]*user.User, 0, len(r.users))
+	for _, u := range r.users {
+		users = append(users, u)
+	}
+	r.mu.RUnlock()
+
+	return r.journal.writeSnapshot(seq, len(users), func(yield func(string, any) bool) {
+		for _, u := range users {
+			if !yield(u.ID().String(), newUserRecord(u)) {
+				return
+			}
+		}
+	})
 }
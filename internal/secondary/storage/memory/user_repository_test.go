@@ -2,22 +2,31 @@ package memory
 
 import (
 	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
 	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
 type fakeHasher struct{}
 
-func (f *fakeHasher) Hash(password string) (string, error)          { return "hashed-" + password, nil }
-func (f *fakeHasher) Compare(hashedPassword, password string) error { return nil }
+func (f *fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+func (f *fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error { return nil }
 
-func TestUserRepository(t *testing.T) {
-	validUser, err := user.NewUser(
+func newTestUser(t *testing.T) *user.User {
+	t.Helper()
+	u, err := user.NewUser(
+		context.Background(),
 		user.NewUserParams{
 			Email:       "john@example.com",
 			Username:    "johndoe",
@@ -27,11 +36,34 @@ func TestUserRepository(t *testing.T) {
 		&fakeHasher{},
 	)
 	require.NoError(t, err, "failed to create test user")
+	return u
+}
+
+// restoreUser builds users directly, skipping validation and hashing.
+func restoreUser(t testing.TB, id uuid.UUID, email, username string) *user.User {
+	t.Helper()
+	u, err := user.Restore(user.RestoreParams{
+		ID:            id,
+		Email:         email,
+		Username:      username,
+		PasswordHash:  "hashed-password",
+		DateOfBirth:   time.Date(1992, time.November, 21, 0, 0, 0, 0, time.UTC),
+		WeekAnchor:    week.DefaultAnchor,
+		TimeZone:      "UTC",
+		CalendarToken: "token",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	})
+	require.NoError(t, err)
+	return u
+}
 
-	t.Run("save and find user by ID and email", func(t *testing.T) {
+func TestUserRepository(t *testing.T) {
+	t.Run("create and find user by ID and email", func(t *testing.T) {
 		repo := NewUserRepository()
+		validUser := newTestUser(t)
 
-		err := repo.Save(context.Background(), validUser)
+		err := repo.Create(context.Background(), validUser)
 		require.NoError(t, err)
 
 		foundByID, err := repo.FindByID(context.Background(), validUser.ID())
@@ -44,6 +76,89 @@ func TestUserRepository(t *testing.T) {
 		assert.Equal(t, validUser, foundByEmail, "user found by email should match the saved user")
 	})
 
+	t.Run("find all users", func(t *testing.T) {
+		repo := NewUserRepository()
+		validUser := newTestUser(t)
+
+		found, err := repo.FindAll(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, found)
+
+		err = repo.Create(context.Background(), validUser)
+		require.NoError(t, err)
+
+		found, err = repo.FindAll(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []*user.User{validUser}, found)
+	})
+
+	t.Run("saves advance the version", func(t *testing.T) {
+		repo := NewUserRepository()
+		u := newTestUser(t)
+		assert.Equal(t, aggregate.Version(0), u.Version())
+
+		require.NoError(t, repo.Create(context.Background(), u))
+		assert.Equal(t, aggregate.Version(1), u.Version())
+
+		require.NoError(t, u.ChangeTimeZone("Europe/Berlin"))
+		require.NoError(t, repo.Save(context.Background(), u))
+		assert.Equal(t, aggregate.Version(2), u.Version())
+
+		found, err := repo.FindByID(context.Background(), u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, aggregate.Version(2), found.Version())
+		assert.Equal(t, "Europe/Berlin", found.Location().String())
+	})
+
+	t.Run("rejects saving a stale copy", func(t *testing.T) {
+		repo := NewUserRepository()
+		u := newTestUser(t)
+		require.NoError(t, repo.Create(context.Background(), u))
+
+		first, err := repo.FindByID(context.Background(), u.ID())
+		require.NoError(t, err)
+		second, err := repo.FindByID(context.Background(), u.ID())
+		require.NoError(t, err)
+
+		require.NoError(t, first.ChangeTimeZone("Europe/Berlin"))
+		require.NoError(t, repo.Save(context.Background(), first))
+
+		require.NoError(t, second.ChangeTimeZone("Asia/Tokyo"))
+		err = repo.Save(context.Background(), second)
+		assert.ErrorIs(t, err, aggregate.ErrConflict)
+
+		found, err := repo.FindByID(context.Background(), u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "Europe/Berlin", found.Location().String(), "the first edit is kept")
+	})
+
+	t.Run("rejects a registered email", func(t *testing.T) {
+		repo := NewUserRepository()
+		require.NoError(t, repo.Create(context.Background(), newTestUser(t)))
+
+		err := repo.Create(context.Background(), newTestUser(t))
+		assert.ErrorIs(t, err, user.ErrEmailTaken)
+
+		found, err := repo.FindAll(context.Background())
+		require.NoError(t, err)
+		assert.Len(t, found, 1)
+	})
+
+	t.Run("rejects creating a user twice", func(t *testing.T) {
+		repo := NewUserRepository()
+		u := newTestUser(t)
+		require.NoError(t, repo.Create(context.Background(), u))
+
+		assert.ErrorIs(t, repo.Create(context.Background(), u), aggregate.ErrConflict)
+	})
+
+	t.Run("saving requires creating first", func(t *testing.T) {
+		repo := NewUserRepository()
+
+		err := repo.Save(context.Background(), newTestUser(t))
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+	})
+
 	t.Run("return error for non-existent ID", func(t *testing.T) {
 		repo := NewUserRepository()
 
@@ -64,3 +179,154 @@ func TestUserRepository(t *testing.T) {
 		assert.Nil(t, foundUser, "found user should be nil on error")
 	})
 }
+
+func TestUserRepository_Indexes(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository()
+	ada := newTestUser(t)
+	require.NoError(t, repo.Create(ctx, ada))
+
+	t.Run("emails ignore case", func(t *testing.T) {
+		found, err := repo.FindByEmail(ctx, "John@Example.COM")
+		require.NoError(t, err)
+		assert.Equal(t, ada.ID(), found.ID())
+
+		shouting := restoreUser(t, uuid.New(), "JOHN@EXAMPLE.COM", "shouty")
+		assert.ErrorIs(t, repo.Create(ctx, shouting), user.ErrEmailTaken)
+	})
+
+	t.Run("usernames are not unique", func(t *testing.T) {
+		namesake := restoreUser(t, uuid.New(), "other@example.com", "JohnDoe")
+		require.NoError(t, repo.Create(ctx, namesake))
+
+		found, err := repo.FindByUsername(ctx, "johndoe")
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+		assert.Equal(t, ada.ID(), found[0].ID(), "oldest first")
+		assert.Equal(t, namesake.ID(), found[1].ID())
+
+		found, err = repo.FindByUsername(ctx, "nobody")
+		require.NoError(t, err)
+		assert.Empty(t, found)
+	})
+
+	t.Run("saving reindexes", func(t *testing.T) {
+		moved := restoreUser(t, ada.ID(), "john@example.org", "jdoe")
+		moved.SetVersion(ada.Version())
+		require.NoError(t, repo.Save(ctx, moved))
+
+		_, err := repo.FindByEmail(ctx, "john@example.com")
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		found, err := repo.FindByEmail(ctx, "john@example.org")
+		require.NoError(t, err)
+		assert.Equal(t, ada.ID(), found.ID())
+
+		byName, err := repo.FindByUsername(ctx, "johndoe")
+		require.NoError(t, err)
+		assert.Len(t, byName, 1)
+		byName, err = repo.FindByUsername(ctx, "jdoe")
+		require.NoError(t, err)
+		assert.Len(t, byName, 1)
+
+		taken := restoreUser(t, ada.ID(), "other@example.com", "jdoe")
+		taken.SetVersion(moved.Version())
+		assert.ErrorIs(t, repo.Save(ctx, taken), user.ErrEmailTaken)
+	})
+}
+
+func TestUserRepository_Isolation(t *testing.T) {
+	ctx := context.Background()
+	repo := NewUserRepository()
+	u := newTestUser(t)
+	require.NoError(t, repo.Create(ctx, u))
+
+	require.NoError(t, u.ChangeTimeZone("Europe/Berlin"))
+	found, err := repo.FindByID(ctx, u.ID())
+	require.NoError(t, err)
+	assert.Equal(t, "UTC", found.Location().String(), "changing a created user does not change the stored one")
+
+	require.NoError(t, found.ChangeTimeZone("Asia/Tokyo"))
+	for _, find := range []func() (*user.User, error){
+		func() (*user.User, error) { return repo.FindByID(ctx, u.ID()) },
+		func() (*user.User, error) { return repo.FindByEmail(ctx, u.Email()) },
+		func() (*user.User, error) {
+			users, err := repo.FindByUsername(ctx, u.Username())
+			return users[0], err
+		},
+		func() (*user.User, error) {
+			users, err := repo.FindAll(ctx)
+			return users[0], err
+		},
+	} {
+		again, err := find()
+		require.NoError(t, err)
+		assert.Equal(t, "UTC", again.Location().String(), "changing a found user does not change the stored one")
+		assert.NotSame(t, found, again)
+	}
+}
+
+func TestUserRepository_ConcurrentCreate(t *testing.T) {
+	repo := NewUserRepository()
+	users := make([]*user.User, 300)
+	for i := range users {
+		users[i] = newTestUser(t)
+	}
+
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for _, u := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(context.Background(), u)
+			if err == nil {
+				created.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, user.ErrEmailTaken)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created.Load())
+	found, err := repo.FindAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, found, 1)
+}
+
+// BenchmarkUserRepository_Find shows lookups taking the same time however
+// many users are stored.
+func BenchmarkUserRepository_Find(b *testing.B) {
+	ctx := context.Background()
+	for _, size := range []int{1_000, 1_000_000} {
+		repo := NewUserRepository()
+		ids := make([]uuid.UUID, size)
+		for i := range ids {
+			ids[i] = uuid.New()
+			u := restoreUser(b, ids[i], fmt.Sprintf("user%d@example.com", i), fmt.Sprintf("user%d", i))
+			require.NoError(b, repo.Create(ctx, u))
+		}
+
+		b.Run(fmt.Sprintf("by_id/%d", size), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				if _, err := repo.FindByID(ctx, ids[i%size]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("by_email/%d", size), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				if _, err := repo.FindByEmail(ctx, fmt.Sprintf("USER%d@example.com", i%size)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("by_username/%d", size), func(b *testing.B) {
+			for i := 0; b.Loop(); i++ {
+				if _, err := repo.FindByUsername(ctx, fmt.Sprintf("user%d", i%size)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+)
+
+type sqlDeliveryRepository struct {
+	db *sql.DB
+}
+
+func NewDeliveryRepository(db *sql.DB) digest.DeliveryRepository {
+	return &sqlDeliveryRepository{db: db}
+}
+
+// Record leaves the one delivery per user and week to the primary key, so
+// that instances sharing the database do not both send a digest.
+func (r *sqlDeliveryRepository) Record(ctx context.Context, d digest.Delivery) error {
+	ok, err := applied(conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO digest_deliveries (user_id, week, sent_at) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`,
+		d.UserID.String(), d.Week, millis(d.SentAt),
+	))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return digest.ErrAlreadyDelivered
+	}
+	return nil
+}
+
+func (r *sqlDeliveryRepository) Delete(ctx context.Context, userID uuid.UUID, week int) error {
+	_, err := conn(ctx, r.db).ExecContext(ctx,
+		`DELETE FROM digest_deliveries WHERE user_id = $1 AND week = $2`,
+		userID.String(), week,
+	)
+	return err
+}
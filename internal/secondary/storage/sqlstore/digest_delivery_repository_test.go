@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeliveryRepository(t *testing.T) {
+	ctx := context.Background()
+	userID := uuid.New()
+	delivery := digest.Delivery{UserID: userID, Week: 1706, SentAt: time.Now().UTC()}
+
+	t.Run("record once per user and week", func(t *testing.T) {
+		repo := NewDeliveryRepository(newTestDB(t))
+
+		require.NoError(t, repo.Record(ctx, delivery))
+
+		err := repo.Record(ctx, delivery)
+		assert.ErrorIs(t, err, digest.ErrAlreadyDelivered)
+
+		require.NoError(t, repo.Record(ctx, digest.Delivery{UserID: userID, Week: 1707}))
+		require.NoError(t, repo.Record(ctx, digest.Delivery{UserID: uuid.New(), Week: 1706}))
+	})
+
+	t.Run("deleted delivery can be recorded again", func(t *testing.T) {
+		repo := NewDeliveryRepository(newTestDB(t))
+
+		require.NoError(t, repo.Record(ctx, delivery))
+		require.NoError(t, repo.Delete(ctx, userID, delivery.Week))
+		require.NoError(t, repo.Delete(ctx, userID, delivery.Week))
+
+		assert.NoError(t, repo.Record(ctx, delivery))
+	})
+
+	t.Run("recorded by another instance", func(t *testing.T) {
+		db := newTestDB(t)
+
+		require.NoError(t, NewDeliveryRepository(db).Record(ctx, delivery))
+
+		err := NewDeliveryRepository(db).Record(ctx, delivery)
+		assert.ErrorIs(t, err, digest.ErrAlreadyDelivered)
+	})
+
+	t.Run("rolled back with the transaction", func(t *testing.T) {
+		db := newTestDB(t)
+		repo := NewDeliveryRepository(db)
+
+		err := NewTransactionManager(db).Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, repo.Record(ctx, delivery))
+			return errors.New("boom")
+		})
+		require.Error(t, err)
+
+		assert.NoError(t, repo.Record(ctx, delivery))
+	})
+}
@@ -0,0 +1,99 @@
+package sqlstore
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/idempotency"
+)
+
+type sqlIdempotencyStore struct {
+	db     *sql.DB
+	claims atomic.Int64
+}
+
+// NewIdempotencyStore keeps idempotency records in db. Responses are stored
+// as JSON, NULL while the request is in flight.
+func NewIdempotencyStore(db *sql.DB) idempotency.Store {
+	return &sqlIdempotencyStore{db: db}
+}
+
+// Claim inserts the record, or takes over an expired one, in a single
+// statement; only when that changes nothing is the existing record read.
+func (s *sqlIdempotencyStore) Claim(ctx context.Context, key, fingerprint string, now, until time.Time) (idempotency.Record, string, error) {
+	if s.claims.Add(1)%sweepEvery == 0 {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, millis(now)); err != nil {
+			return idempotency.Record{}, "", err
+		}
+	}
+
+	claim := rand.Text()
+	for {
+		res, err := s.db.ExecContext(ctx,
+			`INSERT INTO idempotency_keys (key, fingerprint, claim, response, expires_at) VALUES ($1, $2, $3, NULL, $4)
+			ON CONFLICT (key) DO UPDATE SET
+				fingerprint = excluded.fingerprint, claim = excluded.claim, response = NULL, expires_at = excluded.expires_at
+			WHERE idempotency_keys.expires_at <= $5`,
+			key, fingerprint, claim, millis(until), millis(now),
+		)
+		ok, err := applied(res, err)
+		if err != nil {
+			return idempotency.Record{}, "", err
+		}
+		if ok {
+			return idempotency.Record{}, claim, nil
+		}
+
+		var (
+			rec       idempotency.Record
+			response  sql.NullString
+			expiresAt int64
+		)
+		err = s.db.QueryRowContext(ctx,
+			`SELECT fingerprint, response, expires_at FROM idempotency_keys WHERE key = $1`, key,
+		).Scan(&rec.Fingerprint, &response, &expiresAt)
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			// Released in the meantime; try to claim it again.
+			continue
+		case err != nil:
+			return idempotency.Record{}, "", err
+		}
+		rec.ExpiresAt = fromMillis(expiresAt)
+		if response.Valid {
+			rec.Response = new(idempotency.Response)
+			if err := json.Unmarshal([]byte(response.String), rec.Response); err != nil {
+				return idempotency.Record{}, "", err
+			}
+		}
+		return rec, "", nil
+	}
+}
+
+func (s *sqlIdempotencyStore) Complete(ctx context.Context, key, claim string, resp idempotency.Response, expires time.Time) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET response = $1, expires_at = $2 WHERE key = $3 AND claim = $4 AND response IS NULL`,
+		string(data), millis(expires), key, claim,
+	)
+	ok, err := applied(res, err)
+	if err == nil && !ok {
+		err = idempotency.ErrClaimLost
+	}
+	return err
+}
+
+func (s *sqlIdempotencyStore) Release(ctx context.Context, key, claim string) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE key = $1 AND claim = $2 AND response IS NULL`, key, claim,
+	)
+	return err
+}
@@ -0,0 +1,167 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+)
+
+const jobColumns = `id, type, payload, state, attempts, max_attempts, run_at, locked_until, last_error, created_at, updated_at`
+
+type sqlJobStore struct {
+	db *sql.DB
+}
+
+func NewJobStore(db *sql.DB) job.Store {
+	return &sqlJobStore{db: db}
+}
+
+func (s *sqlJobStore) Enqueue(ctx context.Context, j *job.Job) error {
+	_, err := conn(ctx, s.db).ExecContext(ctx,
+		`INSERT INTO jobs (`+jobColumns+`) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`,
+		j.ID.String(), j.Type, string(j.Payload), string(j.State), j.Attempts, j.MaxAttempts,
+		millis(j.RunAt), millis(j.LockedUntil), j.LastError, millis(j.CreatedAt), millis(j.UpdatedAt),
+	)
+	return err
+}
+
+// Claim picks the oldest due job and locks it with a single UPDATE. The
+// claimable condition is repeated on the outer statement so that a worker
+// racing for the same row updates nothing instead of claiming it twice.
+func (s *sqlJobStore) Claim(ctx context.Context, now time.Time, lease time.Duration) (*job.Job, error) {
+	const claimable = `((state = 'pending' AND run_at <= $2) OR (state = 'running' AND locked_until <= $2))`
+
+	row := conn(ctx, s.db).QueryRowContext(ctx,
+		`UPDATE jobs
+		SET state = 'running', attempts = attempts + 1, locked_until = $1, updated_at = $2
+		WHERE id = (SELECT id FROM jobs WHERE `+claimable+` ORDER BY run_at LIMIT 1)
+		AND `+claimable+`
+		RETURNING `+jobColumns,
+		millis(now.Add(lease)), millis(now),
+	)
+
+	j, err := scanJob(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, job.ErrNoJobAvailable
+	}
+	return j, err
+}
+
+func (s *sqlJobStore) Complete(ctx context.Context, id uuid.UUID, attempt int) error {
+	return s.finish(ctx, id, attempt,
+		`UPDATE jobs SET state = 'done', locked_until = 0, updated_at = $3`,
+		millis(time.Now()),
+	)
+}
+
+func (s *sqlJobStore) Retry(ctx context.Context, id uuid.UUID, attempt int, runAt time.Time, lastErr string) error {
+	return s.finish(ctx, id, attempt,
+		`UPDATE jobs SET state = 'pending', run_at = $3, last_error = $4, locked_until = 0, updated_at = $5`,
+		millis(runAt), lastErr, millis(time.Now()),
+	)
+}
+
+func (s *sqlJobStore) Bury(ctx context.Context, id uuid.UUID, attempt int, lastErr string) error {
+	return s.finish(ctx, id, attempt,
+		`UPDATE jobs SET state = 'dead', last_error = $3, locked_until = 0, updated_at = $4`,
+		lastErr, millis(time.Now()),
+	)
+}
+
+func (s *sqlJobStore) DeadLetters(ctx context.Context) ([]*job.Job, error) {
+	rows, err := conn(ctx, s.db).QueryContext(ctx,
+		`SELECT `+jobColumns+` FROM jobs WHERE state = 'dead' ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dead []*job.Job
+	for rows.Next() {
+		j, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		dead = append(dead, j)
+	}
+	return dead, rows.Err()
+}
+
+// finish runs update, which sets columns from $3 on, against the job only
+// while it is still running under attempt.
+func (s *sqlJobStore) finish(ctx context.Context, id uuid.UUID, attempt int, update string, args ...any) error {
+	res, err := conn(ctx, s.db).ExecContext(ctx,
+		update+` WHERE id = $1 AND state = 'running' AND attempts = $2`,
+		append([]any{id.String(), attempt}, args...)...,
+	)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	var exists int
+	err = conn(ctx, s.db).QueryRowContext(ctx, `SELECT 1 FROM jobs WHERE id = $1`, id.String()).Scan(&exists)
+	if errors.Is(err, sql.ErrNoRows) {
+		return job.ErrJobNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return job.ErrLeaseLost
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanJob(row scanner) (*job.Job, error) {
+	var (
+		j                                        job.Job
+		id, payload, state                       string
+		runAt, lockedUntil, createdAt, updatedAt int64
+	)
+
+	err := row.Scan(&id, &j.Type, &payload, &state, &j.Attempts, &j.MaxAttempts,
+		&runAt, &lockedUntil, &j.LastError, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	j.ID, err = uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	j.Payload = []byte(payload)
+	j.State = job.State(state)
+	j.RunAt = fromMillis(runAt)
+	j.LockedUntil = fromMillis(lockedUntil)
+	j.CreatedAt = fromMillis(createdAt)
+	j.UpdatedAt = fromMillis(updatedAt)
+	return &j, nil
+}
+
+func millis(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
+
+func fromMillis(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(ms).UTC()
+}
@@ -0,0 +1,200 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPayload struct {
+	N int `json:"n"`
+}
+
+func (testPayload) JobType() string { return "test" }
+
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := Open(context.Background(), "file:"+filepath.Join(t.TempDir(), "weekbyweek.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestJob(t *testing.T, n int, runAt time.Time) *job.Job {
+	t.Helper()
+	j, err := job.New(testPayload{N: n}, 3, runAt)
+	require.NoError(t, err)
+	return j
+}
+
+func TestJobStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	lease := time.Minute
+
+	t.Run("claim due job", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		j := newTestJob(t, 1, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+
+		claimed, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		assert.Equal(t, j.ID, claimed.ID)
+		assert.Equal(t, "test", claimed.Type)
+		assert.JSONEq(t, `{"n":1}`, string(claimed.Payload))
+		assert.Equal(t, job.StateRunning, claimed.State)
+		assert.Equal(t, 1, claimed.Attempts)
+		assert.Equal(t, 3, claimed.MaxAttempts)
+		assert.Equal(t, now.Add(lease), claimed.LockedUntil)
+
+		_, err = store.Claim(ctx, now, lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable, "running job should not be claimed twice")
+	})
+
+	t.Run("jobs are not claimed before they are due", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		require.NoError(t, store.Enqueue(ctx, newTestJob(t, 1, now.Add(time.Hour))))
+
+		_, err := store.Claim(ctx, now, lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+	})
+
+	t.Run("retry reschedules the job", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		j := newTestJob(t, 1, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		_, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Retry(ctx, j.ID, 1, now.Add(time.Second), "boom"))
+
+		_, err = store.Claim(ctx, now, lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+
+		claimed, err := store.Claim(ctx, now.Add(time.Second), lease)
+		require.NoError(t, err)
+		assert.Equal(t, 2, claimed.Attempts)
+		assert.Equal(t, "boom", claimed.LastError)
+	})
+
+	t.Run("expired lease is claimed again", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		j := newTestJob(t, 1, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		_, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		claimed, err := store.Claim(ctx, now.Add(lease), lease)
+		require.NoError(t, err)
+		assert.Equal(t, j.ID, claimed.ID)
+		assert.Equal(t, 2, claimed.Attempts)
+	})
+
+	t.Run("completed job is not claimed", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		j := newTestJob(t, 1, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		_, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Complete(ctx, j.ID, 1))
+
+		_, err = store.Claim(ctx, now.Add(2*lease), lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+	})
+
+	t.Run("buried job is dead lettered", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		j := newTestJob(t, 1, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		_, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+
+		require.NoError(t, store.Bury(ctx, j.ID, 1, "gave up"))
+
+		dead, err := store.DeadLetters(ctx)
+		require.NoError(t, err)
+		require.Len(t, dead, 1)
+		assert.Equal(t, j.ID, dead[0].ID)
+		assert.Equal(t, job.StateDead, dead[0].State)
+		assert.Equal(t, "gave up", dead[0].LastError)
+
+		_, err = store.Claim(ctx, now.Add(2*lease), lease)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+
+		assert.ErrorIs(t, store.Complete(ctx, uuid.New(), 1), job.ErrJobNotFound)
+		assert.ErrorIs(t, store.Retry(ctx, uuid.New(), 1, now, ""), job.ErrJobNotFound)
+		assert.ErrorIs(t, store.Bury(ctx, uuid.New(), 1, ""), job.ErrJobNotFound)
+	})
+
+	t.Run("worker whose lease expired cannot finish the job", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		j := newTestJob(t, 1, now)
+		require.NoError(t, store.Enqueue(ctx, j))
+		expired, err := store.Claim(ctx, now, lease)
+		require.NoError(t, err)
+		current, err := store.Claim(ctx, now.Add(lease), lease)
+		require.NoError(t, err)
+
+		assert.ErrorIs(t, store.Complete(ctx, j.ID, expired.Attempts), job.ErrLeaseLost)
+		assert.ErrorIs(t, store.Retry(ctx, j.ID, expired.Attempts, now, ""), job.ErrLeaseLost)
+		assert.ErrorIs(t, store.Bury(ctx, j.ID, expired.Attempts, ""), job.ErrLeaseLost)
+
+		require.NoError(t, store.Complete(ctx, j.ID, current.Attempts))
+		assert.ErrorIs(t, store.Complete(ctx, j.ID, current.Attempts), job.ErrLeaseLost, "job is no longer running")
+	})
+
+	t.Run("concurrent claims hand out each job once", func(t *testing.T) {
+		store := NewJobStore(newTestDB(t))
+		const jobs = 30
+		for i := range jobs {
+			require.NoError(t, store.Enqueue(ctx, newTestJob(t, i, now)))
+		}
+
+		var (
+			mu      sync.Mutex
+			claimed = map[uuid.UUID]int{}
+			wg      sync.WaitGroup
+		)
+		for range 6 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for misses := 0; misses < 20; {
+					j, err := store.Claim(ctx, now, lease)
+					if errors.Is(err, job.ErrNoJobAvailable) {
+						misses++
+						continue
+					}
+					if !assert.NoError(t, err) {
+						return
+					}
+					mu.Lock()
+					claimed[j.ID]++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Len(t, claimed, jobs)
+		for id, n := range claimed {
+			assert.Equal(t, 1, n, "job %s claimed %d times", id, n)
+		}
+	})
+}
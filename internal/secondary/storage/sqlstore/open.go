@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"slices"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqlitePragmas are applied to every connection unless the DSN sets them.
+// Writers on other connections are waited for rather than failing with
+// SQLITE_BUSY, readers do not block the writer, and transactions take the
+// write lock when they begin: one that upgrades a read lock later fails
+// at once instead of waiting.
+var sqlitePragmas = []struct{ key, name, value string }{
+	{"_pragma", "busy_timeout", "busy_timeout(5000)"},
+	{"_pragma", "journal_mode", "journal_mode(WAL)"},
+	{"_txlock", "", "immediate"},
+}
+
+// Open opens the SQLite database at dsn, configured for concurrent use
+// through a pool of connections, and creates any tables that do not exist
+// yet.
+func Open(ctx context.Context, dsn string) (*sql.DB, error) {
+	db, err := sql.Open("sqlite", withPragmas(dsn))
+	if err != nil {
+		return nil, err
+	}
+	if err := Migrate(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+func withPragmas(dsn string) string {
+	name, query, _ := strings.Cut(dsn, "?")
+	q, err := url.ParseQuery(query)
+	if err != nil {
+		// Leave it to the driver to report.
+		return dsn
+	}
+	for _, p := range sqlitePragmas {
+		if !hasSetting(q, p.key, p.name) {
+			q.Add(p.key, p.value)
+		}
+	}
+	return name + "?" + q.Encode()
+}
+
+// hasSetting reports whether q sets key or, if name is not empty, the
+// pragma of that name.
+func hasSetting(q url.Values, key, name string) bool {
+	if name == "" {
+		return q.Has(key)
+	}
+	return slices.ContainsFunc(q[key], func(v string) bool {
+		return strings.HasPrefix(strings.ToLower(strings.TrimSpace(v)), name)
+	})
+}
@@ -0,0 +1,35 @@
+package sqlstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+
+	pragma := func(t *testing.T, dsn, name string) string {
+		t.Helper()
+		db, err := Open(ctx, dsn)
+		require.NoError(t, err)
+		defer db.Close()
+		var value string
+		require.NoError(t, db.QueryRowContext(ctx, "PRAGMA "+name).Scan(&value))
+		return value
+	}
+
+	t.Run("configures connections for concurrent use", func(t *testing.T) {
+		dsn := "file:" + filepath.Join(t.TempDir(), "weekbyweek.db")
+		assert.Equal(t, "wal", pragma(t, dsn, "journal_mode"))
+		assert.Equal(t, "5000", pragma(t, dsn, "busy_timeout"))
+	})
+
+	t.Run("keeps settings from the DSN", func(t *testing.T) {
+		dsn := "file:" + filepath.Join(t.TempDir(), "weekbyweek.db") + "?_pragma=busy_timeout(100)"
+		assert.Equal(t, "100", pragma(t, dsn, "busy_timeout"))
+	})
+}
@@ -0,0 +1,134 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+)
+
+// sweepEvery is how many takes pass between deletions of buckets that have
+// refilled completely.
+const sweepEvery = 1024
+
+type sqlRateLimitStore struct {
+	db    *sql.DB
+	takes atomic.Int64
+}
+
+func NewRateLimitStore(db *sql.DB) ratelimit.Store {
+	return &sqlRateLimitStore{db: db}
+}
+
+// Take reads the bucket, spends a token in Go and writes it back only if
+// the row is unchanged, retrying when another request or instance got there
+// first. This keeps the bucket arithmetic in one place and the SQL portable.
+func (s *sqlRateLimitStore) Take(ctx context.Context, key string, limit ratelimit.Limit, now time.Time) (ratelimit.Decision, error) {
+	now = fromMillis(millis(now))
+	if s.takes.Add(1)%sweepEvery == 0 {
+		if _, err := s.db.ExecContext(ctx, `DELETE FROM rate_limits WHERE full_at < $1`, millis(now)); err != nil {
+			return ratelimit.Decision{}, err
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return ratelimit.Decision{}, err
+		}
+
+		var (
+			tokens    float64
+			updatedAt int64
+		)
+		err := s.db.QueryRowContext(ctx,
+			`SELECT tokens, updated_at FROM rate_limits WHERE key = $1`, key,
+		).Scan(&tokens, &updatedAt)
+
+		var res sql.Result
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			b := ratelimit.NewBucket(limit, now)
+			d := b.Take(limit, now)
+			res, err = s.db.ExecContext(ctx,
+				`INSERT INTO rate_limits (key, tokens, updated_at, full_at) VALUES ($1, $2, $3, $4)
+				ON CONFLICT (key) DO NOTHING`,
+				key, b.Tokens, millis(b.Updated), millis(now.Add(d.Reset)),
+			)
+			if ok, err := applied(res, err); ok || err != nil {
+				return d, err
+			}
+		case err != nil:
+			return ratelimit.Decision{}, err
+		default:
+			b := ratelimit.Bucket{Tokens: tokens, Updated: fromMillis(updatedAt)}
+			d := b.Take(limit, now)
+			if !d.Allowed {
+				return d, nil
+			}
+			res, err = s.db.ExecContext(ctx,
+				`UPDATE rate_limits SET tokens = $1, updated_at = $2, full_at = $3
+				WHERE key = $4 AND tokens = $5 AND updated_at = $6`,
+				b.Tokens, millis(b.Updated), millis(now.Add(d.Reset)), key, tokens, updatedAt,
+			)
+			if ok, err := applied(res, err); ok || err != nil {
+				return d, err
+			}
+		}
+	}
+}
+
+func applied(res sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+type sqlFailureStore struct {
+	db *sql.DB
+}
+
+func NewFailureStore(db *sql.DB) ratelimit.FailureStore {
+	return &sqlFailureStore{db: db}
+}
+
+func (s *sqlFailureStore) Failures(ctx context.Context, key string) (ratelimit.Failures, error) {
+	var (
+		f      ratelimit.Failures
+		lastAt int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`SELECT count, last_at FROM password_failures WHERE key = $1`, key,
+	).Scan(&f.Count, &lastAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ratelimit.Failures{}, nil
+	}
+	f.Last = fromMillis(lastAt)
+	return f, err
+}
+
+func (s *sqlFailureStore) AddFailure(ctx context.Context, key string, now, since time.Time) (ratelimit.Failures, error) {
+	var (
+		f      ratelimit.Failures
+		lastAt int64
+	)
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO password_failures (key, count, last_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			count = CASE WHEN password_failures.last_at < $3 THEN 1 ELSE password_failures.count + 1 END,
+			last_at = $2
+		RETURNING count, last_at`,
+		key, millis(now), millis(since),
+	).Scan(&f.Count, &lastAt)
+	f.Last = fromMillis(lastAt)
+	return f, err
+}
+
+func (s *sqlFailureStore) ResetFailures(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM password_failures WHERE key = $1`, key)
+	return err
+}
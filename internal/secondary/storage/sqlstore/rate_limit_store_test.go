@@ -0,0 +1,90 @@
+package sqlstore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/ratelimit"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimitStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	t.Run("instances sharing a database never overspend", func(t *testing.T) {
+		db := newTestDB(t)
+		stores := []ratelimit.Store{NewRateLimitStore(db), NewRateLimitStore(db)}
+		limit := ratelimit.Limit{Requests: 10, Per: time.Minute}
+
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for i := range 50 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				d, err := stores[i%2].Take(ctx, "key", limit, now)
+				assert.NoError(t, err)
+				if d.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int32(10), allowed.Load())
+	})
+
+	t.Run("refills over time", func(t *testing.T) {
+		store := NewRateLimitStore(newTestDB(t))
+		limit := ratelimit.Limit{Requests: 2, Per: 2 * time.Second}
+
+		for range 2 {
+			d, err := store.Take(ctx, "key", limit, now)
+			require.NoError(t, err)
+			assert.True(t, d.Allowed)
+		}
+		d, err := store.Take(ctx, "key", limit, now)
+		require.NoError(t, err)
+		assert.False(t, d.Allowed)
+		assert.Equal(t, time.Second, d.RetryAfter)
+
+		d, err = store.Take(ctx, "key", limit, now.Add(time.Second))
+		require.NoError(t, err)
+		assert.True(t, d.Allowed)
+		assert.Equal(t, 0, d.Remaining)
+	})
+}
+
+func TestFailureStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	store := NewFailureStore(newTestDB(t))
+
+	f, err := store.Failures(ctx, "key")
+	require.NoError(t, err)
+	assert.Zero(t, f)
+
+	_, err = store.AddFailure(ctx, "key", now, now.Add(-time.Hour))
+	require.NoError(t, err)
+	f, err = store.AddFailure(ctx, "key", now.Add(time.Minute), now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Failures{Count: 2, Last: now.Add(time.Minute)}, f)
+
+	f, err = store.Failures(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.Count)
+
+	f, err = store.AddFailure(ctx, "key", now.Add(3*time.Hour), now.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 1, f.Count, "old failures are forgotten")
+
+	require.NoError(t, store.ResetFailures(ctx, "key"))
+	f, err = store.Failures(ctx, "key")
+	require.NoError(t, err)
+	assert.Zero(t, f)
+}
@@ -0,0 +1,81 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+)
+
+// The schema sticks to SQL understood by both PostgreSQL and SQLite:
+// timestamps are Unix milliseconds and identifiers are text.
+var schema = []string{
+	`CREATE TABLE IF NOT EXISTS jobs (
+		id           TEXT PRIMARY KEY,
+		type         TEXT NOT NULL,
+		payload      TEXT NOT NULL,
+		state        TEXT NOT NULL,
+		attempts     INTEGER NOT NULL,
+		max_attempts INTEGER NOT NULL,
+		run_at       BIGINT NOT NULL,
+		locked_until BIGINT NOT NULL,
+		last_error   TEXT NOT NULL,
+		created_at   BIGINT NOT NULL,
+		updated_at   BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS jobs_state_run_at ON jobs (state, run_at)`,
+	`CREATE TABLE IF NOT EXISTS rate_limits (
+		key        TEXT PRIMARY KEY,
+		tokens     DOUBLE PRECISION NOT NULL,
+		updated_at BIGINT NOT NULL,
+		full_at    BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS rate_limits_full_at ON rate_limits (full_at)`,
+	`CREATE TABLE IF NOT EXISTS password_failures (
+		key     TEXT PRIMARY KEY,
+		count   INTEGER NOT NULL,
+		last_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key         TEXT PRIMARY KEY,
+		fingerprint TEXT NOT NULL,
+		claim       TEXT NOT NULL,
+		response    TEXT,
+		expires_at  BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS idempotency_keys_expires_at ON idempotency_keys (expires_at)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id                  TEXT PRIMARY KEY,
+		email               TEXT NOT NULL,
+		email_normalised    TEXT NOT NULL,
+		username            TEXT NOT NULL,
+		username_normalised TEXT NOT NULL,
+		password_hash       TEXT NOT NULL,
+		date_of_birth       BIGINT NOT NULL,
+		week_mode           TEXT NOT NULL,
+		first_weekday       INTEGER NOT NULL,
+		time_zone           TEXT NOT NULL,
+		calendar_token      TEXT NOT NULL,
+		version             BIGINT NOT NULL,
+		created_at          BIGINT NOT NULL,
+		updated_at          BIGINT NOT NULL
+	)`,
+	// Emails and usernames are normalised in Go rather than with lower,
+	// which folds only ASCII in SQLite.
+	`CREATE UNIQUE INDEX IF NOT EXISTS users_email_key ON users (email_normalised)`,
+	`CREATE INDEX IF NOT EXISTS users_username_key ON users (username_normalised)`,
+	`CREATE TABLE IF NOT EXISTS digest_deliveries (
+		user_id TEXT NOT NULL,
+		week    INTEGER NOT NULL,
+		sent_at BIGINT NOT NULL,
+		PRIMARY KEY (user_id, week)
+	)`,
+}
+
+// Migrate creates any tables and indexes that do not exist yet.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
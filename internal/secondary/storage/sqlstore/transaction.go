@@ -0,0 +1,89 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/transaction"
+)
+
+// querier is what *sql.DB and *sql.Tx have in common.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+type txKey struct{}
+
+type sqlTx struct {
+	db        *sql.DB
+	tx        *sql.Tx
+	mu        sync.Mutex
+	rollbacks []func()
+}
+
+// conn returns the transaction ctx carries for db, if any, and otherwise
+// db, so that repositories join transactions without being told.
+func conn(ctx context.Context, db *sql.DB) querier {
+	if t, ok := ctx.Value(txKey{}).(*sqlTx); ok && t.db == db {
+		return t.tx
+	}
+	return db
+}
+
+// setVersion sets the version of an aggregate written to db under ctx, and
+// puts the old one back if the transaction ctx carries is rolled back.
+func setVersion(ctx context.Context, db *sql.DB, a aggregate.Versioned, v aggregate.Version) {
+	if t, ok := ctx.Value(txKey{}).(*sqlTx); ok && t.db == db {
+		old := a.Version()
+		t.mu.Lock()
+		t.rollbacks = append(t.rollbacks, func() { a.SetVersion(old) })
+		t.mu.Unlock()
+	}
+	a.SetVersion(v)
+}
+
+func (t *sqlTx) rollback() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, undo := range slices.Backward(t.rollbacks) {
+		undo()
+	}
+}
+
+type sqlTransactionManager struct {
+	db *sql.DB
+}
+
+// NewTransactionManager runs use cases in database transactions. Only
+// repositories on the same db take part in them.
+func NewTransactionManager(db *sql.DB) transaction.Manager {
+	return &sqlTransactionManager{db: db}
+}
+
+func (m *sqlTransactionManager) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := conn(ctx, m.db).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// Rolling back after a commit does nothing.
+	defer tx.Rollback()
+
+	t := &sqlTx{db: m.db, tx: tx}
+	err = fn(context.WithValue(ctx, txKey{}, t))
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		t.rollback()
+	}
+	return err
+}
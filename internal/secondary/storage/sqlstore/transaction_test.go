@@ -0,0 +1,78 @@
+package sqlstore
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mgwinsor/weekbyweek/internal/domain/job"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransactionManager(t *testing.T) {
+	ctx := context.Background()
+	errAbort := errors.New("abort")
+	now := time.Now().UTC().Truncate(time.Millisecond)
+
+	t.Run("commits writes to every repository", func(t *testing.T) {
+		db := newTestDB(t)
+		users, jobs := NewUserRepository(db), NewJobStore(db)
+		u := newTestUser(t, "ada@example.com")
+
+		err := NewTransactionManager(db).Run(ctx, func(ctx context.Context) error {
+			if err := users.Create(ctx, u); err != nil {
+				return err
+			}
+			found, err := users.FindByEmail(ctx, "ada@example.com")
+			require.NoError(t, err, "the transaction sees its own writes")
+			assert.Equal(t, u.ID(), found.ID())
+			return jobs.Enqueue(ctx, newTestJob(t, 1, now))
+		})
+		require.NoError(t, err)
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.NoError(t, err)
+		_, err = jobs.Claim(ctx, now, time.Minute)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rolls back writes when the use case fails", func(t *testing.T) {
+		db := newTestDB(t)
+		users, jobs := NewUserRepository(db), NewJobStore(db)
+		u := newTestUser(t, "ada@example.com")
+
+		err := NewTransactionManager(db).Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, users.Create(ctx, u))
+			require.NoError(t, jobs.Enqueue(ctx, newTestJob(t, 1, now)))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+		assert.Zero(t, u.Version(), "the user is not left at a version that was never stored")
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		_, err = jobs.Claim(ctx, now, time.Minute)
+		assert.ErrorIs(t, err, job.ErrNoJobAvailable)
+	})
+
+	t.Run("nested runs join the transaction", func(t *testing.T) {
+		db := newTestDB(t)
+		users := NewUserRepository(db)
+		transactions := NewTransactionManager(db)
+		u := newTestUser(t, "ada@example.com")
+
+		err := transactions.Run(ctx, func(ctx context.Context) error {
+			require.NoError(t, transactions.Run(ctx, func(ctx context.Context) error {
+				return users.Create(ctx, u)
+			}))
+			return errAbort
+		})
+		assert.ErrorIs(t, err, errAbort)
+
+		_, err = users.FindByID(ctx, u.ID())
+		assert.ErrorIs(t, err, user.ErrUserNotFound, "the inner run did not commit on its own")
+	})
+}
@@ -0,0 +1,148 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+)
+
+const userColumns = `id, email, username, password_hash, date_of_birth, week_mode, first_weekday, time_zone, calendar_token, version, created_at, updated_at`
+
+type sqlUserRepository struct {
+	db *sql.DB
+}
+
+func NewUserRepository(db *sql.DB) user.UserRepository {
+	return &sqlUserRepository{db: db}
+}
+
+// Create leaves the uniqueness of emails to the database, so that it holds
+// across instances sharing it.
+func (r *sqlUserRepository) Create(ctx context.Context, u *user.User) error {
+	version := aggregate.Version(1)
+	ok, err := applied(conn(ctx, r.db).ExecContext(ctx,
+		`INSERT INTO users (`+userColumns+`, email_normalised, username_normalised)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT DO NOTHING`,
+		u.ID().String(), u.Email(), u.Username(), u.PasswordHash(), u.DateOfBirth().UnixMilli(),
+		string(u.WeekAnchor().Mode), int(u.WeekAnchor().FirstWeekday), u.Location().String(),
+		u.CalendarToken(), int64(version), millis(u.CreatedAt()), millis(u.UpdatedAt()),
+		user.NormaliseEmail(u.Email()), user.NormaliseUsername(u.Username()),
+	))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if _, err := r.FindByID(ctx, u.ID()); err == nil {
+			return fmt.Errorf("user %s already exists: %w", u.ID(), aggregate.ErrConflict)
+		}
+		return user.ErrEmailTaken
+	}
+
+	setVersion(ctx, r.db, u, version)
+	return nil
+}
+
+func (r *sqlUserRepository) Save(ctx context.Context, u *user.User) error {
+	version := u.Version() + 1
+	ok, err := applied(conn(ctx, r.db).ExecContext(ctx,
+		`UPDATE users SET email = $2, username = $3, password_hash = $4, date_of_birth = $5,
+			week_mode = $6, first_weekday = $7, time_zone = $8, calendar_token = $9,
+			version = $10, updated_at = $11, email_normalised = $13, username_normalised = $14
+		WHERE id = $1 AND version = $12`,
+		u.ID().String(), u.Email(), u.Username(), u.PasswordHash(), u.DateOfBirth().UnixMilli(),
+		string(u.WeekAnchor().Mode), int(u.WeekAnchor().FirstWeekday), u.Location().String(),
+		u.CalendarToken(), int64(version), millis(u.UpdatedAt()), int64(u.Version()),
+		user.NormaliseEmail(u.Email()), user.NormaliseUsername(u.Username()),
+	))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		stored, err := r.FindByID(ctx, u.ID())
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("user %s at version %d, saving version %d: %w", u.ID(), stored.Version(), u.Version(), aggregate.ErrConflict)
+	}
+
+	setVersion(ctx, r.db, u, version)
+	return nil
+}
+
+func (r *sqlUserRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	return r.findOne(ctx, `SELECT `+userColumns+` FROM users WHERE id = $1`, id.String())
+}
+
+// FindByEmail and FindByUsername look up the normalised columns the users
+// indexes are on.
+func (r *sqlUserRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	return r.findOne(ctx, `SELECT `+userColumns+` FROM users WHERE email_normalised = $1`, user.NormaliseEmail(email))
+}
+
+func (r *sqlUserRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	return r.findAll(ctx, `SELECT `+userColumns+` FROM users WHERE username_normalised = $1 ORDER BY created_at`, user.NormaliseUsername(username))
+}
+
+func (r *sqlUserRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	return r.findAll(ctx, `SELECT `+userColumns+` FROM users ORDER BY created_at`)
+}
+
+func (r *sqlUserRepository) findAll(ctx context.Context, query string, args ...any) ([]*user.User, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*user.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *sqlUserRepository) findOne(ctx context.Context, query string, args ...any) (*user.User, error) {
+	u, err := scanUser(conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, user.ErrUserNotFound
+	}
+	return u, err
+}
+
+func scanUser(row scanner) (*user.User, error) {
+	var (
+		p                                          user.RestoreParams
+		id, mode                                   string
+		firstWeekday                               int
+		dateOfBirth, createdAt, updatedAt, version int64
+	)
+
+	err := row.Scan(&id, &p.Email, &p.Username, &p.PasswordHash, &dateOfBirth,
+		&mode, &firstWeekday, &p.TimeZone, &p.CalendarToken, &version, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	p.ID, err = uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+	p.DateOfBirth = time.UnixMilli(dateOfBirth).UTC()
+	p.WeekAnchor = week.Anchor{Mode: week.Mode(mode), FirstWeekday: time.Weekday(firstWeekday)}
+	p.CreatedAt = fromMillis(createdAt)
+	p.UpdatedAt = fromMillis(updatedAt)
+	p.Version = aggregate.Version(version)
+	return user.Restore(p)
+}
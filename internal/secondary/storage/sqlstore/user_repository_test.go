@@ -0,0 +1,189 @@
+package sqlstore
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mgwinsor/weekbyweek/internal/domain/aggregate"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/week"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+
+func (fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error { return nil }
+
+func newTestUser(t *testing.T, email string) *user.User {
+	t.Helper()
+	u, err := user.NewUser(context.Background(), user.NewUserParams{
+		Email:       email,
+		Username:    "ada",
+		Password:    "correct horse",
+		DateOfBirth: time.Date(1960, time.December, 10, 0, 0, 0, 0, time.UTC),
+		WeekAnchor:  week.Anchor{Mode: week.ModeCalendar, FirstWeekday: time.Sunday},
+		TimeZone:    "Europe/London",
+	}, fakeHasher{})
+	require.NoError(t, err)
+	return u
+}
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("create and find", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+
+		require.NoError(t, repo.Create(ctx, u))
+		assert.Equal(t, aggregate.Version(1), u.Version())
+
+		byID, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		byEmail, err := repo.FindByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		all, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+
+		for _, found := range []*user.User{byID, byEmail, all[0]} {
+			assert.Equal(t, u.ID(), found.ID())
+			assert.Equal(t, u.Email(), found.Email())
+			assert.Equal(t, u.Username(), found.Username())
+			assert.Equal(t, u.PasswordHash(), found.PasswordHash())
+			assert.True(t, u.DateOfBirth().Equal(found.DateOfBirth()))
+			assert.Equal(t, u.WeekAnchor(), found.WeekAnchor())
+			assert.Equal(t, "Europe/London", found.Location().String())
+			assert.Equal(t, u.CalendarToken(), found.CalendarToken())
+			assert.WithinDuration(t, u.CreatedAt(), found.CreatedAt(), time.Millisecond)
+			assert.Equal(t, aggregate.Version(1), found.Version())
+		}
+	})
+
+	t.Run("rejects a registered email", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		require.NoError(t, repo.Create(ctx, newTestUser(t, "ada@example.com")))
+
+		assert.ErrorIs(t, repo.Create(ctx, newTestUser(t, "ada@example.com")), user.ErrEmailTaken)
+		assert.NoError(t, repo.Create(ctx, newTestUser(t, "grace@example.com")))
+	})
+
+	t.Run("emails and usernames ignore case", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		first := newTestUser(t, "ada@example.com")
+		second := newTestUser(t, "lovelace@example.com")
+		require.NoError(t, repo.Create(ctx, first))
+		require.NoError(t, repo.Create(ctx, second))
+
+		assert.ErrorIs(t, repo.Create(ctx, newTestUser(t, "ADA@example.com")), user.ErrEmailTaken)
+		found, err := repo.FindByEmail(ctx, "Ada@Example.com")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID(), found.ID())
+
+		byName, err := repo.FindByUsername(ctx, "ADA")
+		require.NoError(t, err)
+		require.Len(t, byName, 2)
+		assert.ElementsMatch(t, []uuid.UUID{first.ID(), second.ID()}, []uuid.UUID{byName[0].ID(), byName[1].ID()})
+	})
+
+	t.Run("case is folded beyond ASCII", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "élodie@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		assert.ErrorIs(t, repo.Create(ctx, newTestUser(t, "ÉLODIE@example.com")), user.ErrEmailTaken)
+		found, err := repo.FindByEmail(ctx, "Élodie@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, u.ID(), found.ID())
+	})
+
+	t.Run("rejects creating a user twice", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		assert.ErrorIs(t, repo.Create(ctx, u), aggregate.ErrConflict)
+	})
+
+	t.Run("saves advance the version", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+
+		require.NoError(t, u.ChangeTimeZone("Asia/Tokyo"))
+		require.NoError(t, repo.Save(ctx, u))
+		assert.Equal(t, aggregate.Version(2), u.Version())
+
+		found, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+		assert.Equal(t, "Asia/Tokyo", found.Location().String())
+		assert.Equal(t, aggregate.Version(2), found.Version())
+	})
+
+	t.Run("rejects saving a stale copy", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+		u := newTestUser(t, "ada@example.com")
+		require.NoError(t, repo.Create(ctx, u))
+		stale, err := repo.FindByID(ctx, u.ID())
+		require.NoError(t, err)
+
+		require.NoError(t, repo.Save(ctx, u))
+		require.NoError(t, stale.ChangeTimeZone("Asia/Tokyo"))
+		assert.ErrorIs(t, repo.Save(ctx, stale), aggregate.ErrConflict)
+	})
+
+	t.Run("saving requires creating first", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+
+		assert.ErrorIs(t, repo.Save(ctx, newTestUser(t, "ada@example.com")), user.ErrUserNotFound)
+	})
+
+	t.Run("unknown users are not found", func(t *testing.T) {
+		repo := NewUserRepository(newTestDB(t))
+
+		_, err := repo.FindByID(ctx, uuid.New())
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		_, err = repo.FindByEmail(ctx, "nobody@example.com")
+		assert.ErrorIs(t, err, user.ErrUserNotFound)
+		all, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, all)
+	})
+}
+
+func TestUserRepository_ConcurrentCreate(t *testing.T) {
+	repo := NewUserRepository(newTestDB(t))
+	users := make([]*user.User, 200)
+	for i := range users {
+		users[i] = newTestUser(t, "ada@example.com")
+	}
+
+	var created atomic.Int32
+	var wg sync.WaitGroup
+	for _, u := range users {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := repo.Create(context.Background(), u)
+			if err == nil {
+				created.Add(1)
+				return
+			}
+			assert.ErrorIs(t, err, user.ErrEmailTaken)
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int32(1), created.Load())
+	all, err := repo.FindAll(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, all, 1)
+}
@@ -0,0 +1,136 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	appuser "github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/mgwinsor/weekbyweek/internal/domain/user"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var userIDKey = attribute.Key("user.id")
+
+func passwordMatch(match bool) attribute.KeyValue {
+	return attribute.Bool("password.match", match)
+}
+
+type userService struct {
+	next    appuser.Service
+	tracing *Tracing
+}
+
+func (t *Tracing) UserService(next appuser.Service) appuser.Service {
+	return &userService{next: next, tracing: t}
+}
+
+func (s *userService) CreateUser(ctx context.Context, req appuser.CreateUserRequest) (*appuser.CreateUserResponse, error) {
+	ctx, span := s.tracing.start(ctx, "userService.CreateUser")
+	resp, err := s.next.CreateUser(ctx, req)
+	if err == nil {
+		span.SetAttributes(userIDKey.String(resp.ID.String()))
+	}
+	end(span, err, appuser.ErrEmailExists)
+	return resp, err
+}
+
+func (s *userService) Authenticate(ctx context.Context, email, password string) (uuid.UUID, error) {
+	ctx, span := s.tracing.start(ctx, "userService.Authenticate")
+	id, err := s.next.Authenticate(ctx, email, password)
+	if err == nil {
+		span.SetAttributes(userIDKey.String(id.String()))
+	}
+	end(span, err, appuser.ErrInvalidCredentials, appuser.ErrAccountLocked)
+	return id, err
+}
+
+func (s *userService) GetProfile(ctx context.Context, id uuid.UUID) (*appuser.ProfileResponse, error) {
+	ctx, span := s.tracing.start(ctx, "userService.GetProfile", userIDKey.String(id.String()))
+	resp, err := s.next.GetProfile(ctx, id)
+	end(span, err, appuser.ErrUserNotFound)
+	return resp, err
+}
+
+func (s *userService) UpdateProfile(ctx context.Context, id uuid.UUID, req appuser.UpdateProfileRequest) (*appuser.ProfileResponse, error) {
+	ctx, span := s.tracing.start(ctx, "userService.UpdateProfile", userIDKey.String(id.String()))
+	resp, err := s.next.UpdateProfile(ctx, id, req)
+	end(span, err, appuser.ErrUserNotFound, appuser.ErrInvalidProfile)
+	return resp, err
+}
+
+type userRepository struct {
+	next    user.UserRepository
+	tracing *Tracing
+}
+
+func (t *Tracing) UserRepository(next user.UserRepository) user.UserRepository {
+	return &userRepository{next: next, tracing: t}
+}
+
+func (r *userRepository) Create(ctx context.Context, u *user.User) error {
+	ctx, span := r.tracing.start(ctx, "UserRepository.Create", userIDKey.String(u.ID().String()))
+	err := r.next.Create(ctx, u)
+	end(span, err, user.ErrEmailTaken)
+	return err
+}
+
+func (r *userRepository) Save(ctx context.Context, u *user.User) error {
+	ctx, span := r.tracing.start(ctx, "UserRepository.Save", userIDKey.String(u.ID().String()))
+	err := r.next.Save(ctx, u)
+	end(span, err)
+	return err
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User, error) {
+	ctx, span := r.tracing.start(ctx, "UserRepository.FindByID", userIDKey.String(id.String()))
+	u, err := r.next.FindByID(ctx, id)
+	end(span, err, user.ErrUserNotFound)
+	return u, err
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*user.User, error) {
+	ctx, span := r.tracing.start(ctx, "UserRepository.FindByEmail")
+	u, err := r.next.FindByEmail(ctx, email)
+	end(span, err, user.ErrUserNotFound)
+	return u, err
+}
+
+func (r *userRepository) FindByUsername(ctx context.Context, username string) ([]*user.User, error) {
+	ctx, span := r.tracing.start(ctx, "UserRepository.FindByUsername")
+	users, err := r.next.FindByUsername(ctx, username)
+	end(span, err)
+	return users, err
+}
+
+func (r *userRepository) FindAll(ctx context.Context) ([]*user.User, error) {
+	ctx, span := r.tracing.start(ctx, "UserRepository.FindAll")
+	users, err := r.next.FindAll(ctx)
+	end(span, err)
+	return users, err
+}
+
+type passwordHasher struct {
+	next    user.PasswordHasher
+	tracing *Tracing
+}
+
+func (t *Tracing) PasswordHasher(next user.PasswordHasher) user.PasswordHasher {
+	return &passwordHasher{next: next, tracing: t}
+}
+
+func (h *passwordHasher) Hash(ctx context.Context, password string) (string, error) {
+	ctx, span := h.tracing.start(ctx, "PasswordHasher.Hash")
+	hash, err := h.next.Hash(ctx, password)
+	end(span, err)
+	return hash, err
+}
+
+// Compare does not mark a mismatch as a span error; a wrong password is an
+// expected outcome, recorded as an attribute instead.
+func (h *passwordHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	ctx, span := h.tracing.start(ctx, "PasswordHasher.Compare")
+	err := h.next.Compare(ctx, hashedPassword, password)
+	span.SetAttributes(passwordMatch(err == nil))
+	span.End()
+	return err
+}
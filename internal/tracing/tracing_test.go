@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	appuser "github.com/mgwinsor/weekbyweek/internal/app/user"
+	"github.com/mgwinsor/weekbyweek/internal/secondary/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeHasher struct{}
+
+func (fakeHasher) Hash(ctx context.Context, password string) (string, error) {
+	return "hashed-" + password, nil
+}
+
+func (fakeHasher) Compare(ctx context.Context, hashedPassword, password string) error {
+	if hashedPassword != "hashed-"+password {
+		return errors.New("mismatch")
+	}
+	return nil
+}
+
+func newTestTracing() (*Tracing, *tracetest.SpanRecorder) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return New(provider), recorder
+}
+
+func spansByName(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	return spans
+}
+
+func TestCreateUserTrace(t *testing.T) {
+	tr, recorder := newTestTracing()
+	service := tr.UserService(appuser.NewUserService(
+		tr.UserRepository(memory.NewUserRepository()),
+		tr.PasswordHasher(fakeHasher{}),
+		memory.NewTransactionManager(),
+	))
+
+	r := chi.NewRouter()
+	r.Use(tr.Middleware)
+	r.Post("/users", func(w http.ResponseWriter, r *http.Request) {
+		_, err := service.CreateUser(r.Context(), appuser.CreateUserRequest{
+			Email:       "ada@example.com",
+			Username:    "ada",
+			Password:    "correct horse",
+			DateOfBirth: time.Date(1990, time.December, 10, 0, 0, 0, 0, time.UTC),
+		})
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := spansByName(recorder)
+	require.Len(t, spans, 4)
+
+	server := spans["POST /users"]
+	require.NotNil(t, server)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", server.SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String())
+	assert.True(t, server.Parent().IsRemote())
+
+	createUser := spans["userService.CreateUser"]
+	require.NotNil(t, createUser)
+	assert.Equal(t, server.SpanContext().SpanID(), createUser.Parent().SpanID())
+
+	for _, name := range []string{"PasswordHasher.Hash", "UserRepository.Create"} {
+		span := spans[name]
+		require.NotNil(t, span, name)
+		assert.Equal(t, createUser.SpanContext().SpanID(), span.Parent().SpanID(), name)
+		assert.Equal(t, codes.Unset, span.Status().Code, name)
+	}
+}
+
+func TestDecorators_Errors(t *testing.T) {
+	ctx := context.Background()
+	tr, recorder := newTestTracing()
+	repo := memory.NewUserRepository()
+	service := tr.UserService(appuser.NewUserService(repo, fakeHasher{}, memory.NewTransactionManager()))
+	req := appuser.CreateUserRequest{
+		Email:       "ada@example.com",
+		Username:    "ada",
+		Password:    "correct horse",
+		DateOfBirth: time.Date(1990, time.December, 10, 0, 0, 0, 0, time.UTC),
+	}
+
+	_, err := service.CreateUser(ctx, req)
+	require.NoError(t, err)
+	_, err = service.CreateUser(ctx, req)
+	require.ErrorIs(t, err, appuser.ErrEmailExists)
+	_, err = service.CreateUser(ctx, appuser.CreateUserRequest{Email: "not an email"})
+	require.Error(t, err)
+
+	hasher := tr.PasswordHasher(fakeHasher{})
+	assert.Error(t, hasher.Compare(ctx, "hashed-secret", "guess"))
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 4)
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+	assert.Equal(t, codes.Unset, ended[1].Status().Code, "email conflicts are expected")
+	assert.Equal(t, codes.Error, ended[2].Status().Code)
+	assert.Equal(t, codes.Unset, ended[3].Status().Code, "a wrong password is expected")
+	assert.Contains(t, ended[3].Attributes(), passwordMatch(false))
+}